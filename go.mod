@@ -1,34 +1,36 @@
 module github.com/asolopovas/woo-helpers
 
-go 1.23
-
-toolchain go1.23.0
+go 1.23.0
 
 require github.com/spf13/cobra v1.8.1
 
 require (
+	github.com/JohannesKaufmann/html-to-markdown/v2 v2.3.3
+	github.com/chai2010/webp v1.4.0
 	github.com/davidbyttow/govips/v2 v2.14.0
 	github.com/go-resty/resty/v2 v2.13.1
 	github.com/h2non/bimg v1.1.9
 	github.com/inconshreveable/mousetrap v1.1.0
+	github.com/sashabaranov/go-openai v1.36.1
 	github.com/spf13/pflag v1.0.5
 	golang.org/x/image v0.16.0
-	golang.org/x/net v0.34.0
-	golang.org/x/text v0.21.0
+	golang.org/x/net v0.39.0
+	golang.org/x/sync v0.13.0
+	golang.org/x/term v0.31.0
+	golang.org/x/text v0.24.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/JohannesKaufmann/dom v0.2.0 // indirect
 	github.com/JohannesKaufmann/html-to-markdown v1.6.0 // indirect
-	github.com/JohannesKaufmann/html-to-markdown/v2 v2.2.2 // indirect
 	github.com/PuerkitoBio/goquery v1.10.1 // indirect
 	github.com/andybalholm/cascadia v1.3.3 // indirect
 	github.com/openai/openai-go v0.1.0-alpha.47 // indirect
-	github.com/sashabaranov/go-openai v1.36.1 // indirect
 	github.com/tidwall/gjson v1.18.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
 	github.com/tidwall/sjson v1.2.5 // indirect
-	github.com/yuin/goldmark v1.7.8 // indirect
+	github.com/yuin/goldmark v1.7.11 // indirect
+	golang.org/x/sys v0.32.0 // indirect
 )
@@ -1,9 +1,15 @@
 package main
 
 import (
+	_ "embed"
+
 	wooh "github.com/asolopovas/woo-helpers/src"
 )
 
+//go:embed version
+var version string
+
 func main() {
+	wooh.Version = version
 	wooh.Run()
 }
@@ -0,0 +1,284 @@
+package wooh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultApiNamespace = "wc/v3"
+	defaultOrderBy      = "id"
+	defaultOrder        = "asc"
+)
+
+var (
+	allowedApiNamespaces = []string{"wc/v1", "wc/v2", "wc/v3"}
+	allowedProxySchemes  = []string{"http", "https", "socks5"}
+	allowedOrderBy       = []string{"date", "modified", "id", "include", "title", "slug", "price", "popularity", "rating", "menu_order"}
+)
+
+func ValidateOrdering(conf *Config) error {
+	validOrderBy := false
+	for _, v := range allowedOrderBy {
+		if conf.OrderBy == v {
+			validOrderBy = true
+			break
+		}
+	}
+	if !validOrderBy {
+		return fmt.Errorf("unsupported order_by %q (allowed: %s)", conf.OrderBy, strings.Join(allowedOrderBy, ", "))
+	}
+	if conf.Order != "asc" && conf.Order != "desc" {
+		return fmt.Errorf("unsupported order %q (allowed: asc, desc)", conf.Order)
+	}
+	return nil
+}
+
+func ValidateApiNamespace(conf *Config) error {
+	ns := strings.Trim(conf.ApiNamespace, "/")
+	if ns == "" {
+		return fmt.Errorf("api_namespace must not be empty")
+	}
+	for _, allowed := range allowedApiNamespaces {
+		if ns == allowed {
+			return nil
+		}
+	}
+	if !conf.AllowCustomNamespace {
+		return fmt.Errorf("unsupported api_namespace %q (allowed: %s); set allow_custom_namespace to use it anyway",
+			conf.ApiNamespace, strings.Join(allowedApiNamespaces, ", "))
+	}
+	if strings.ContainsAny(ns, " ?#") {
+		return fmt.Errorf("invalid custom api_namespace %q", conf.ApiNamespace)
+	}
+	return nil
+}
+
+// siteURL returns the WordPress base URL for conf.Site. Site may carry a
+// scheme and a subpath (e.g. "http://example.com/shop/"); https is assumed
+// when no scheme is given and trailing slashes are dropped.
+func siteURL(conf *Config) string {
+	site := strings.TrimSpace(conf.Site)
+	if !strings.HasPrefix(site, "http://") && !strings.HasPrefix(site, "https://") {
+		site = "https://" + site
+	}
+	return strings.TrimRight(site, "/")
+}
+
+// wpEndpoint builds a WordPress REST URL, e.g. wpEndpoint(conf, "wp/v2/media").
+func wpEndpoint(conf *Config, path string) string {
+	return siteURL(conf) + "/wp-json/" + strings.TrimLeft(path, "/")
+}
+
+// wooEndpoint builds an authenticated WooCommerce REST URL for path under the
+// configured namespace, e.g. wooEndpoint(conf, "products/12").
+func wooEndpoint(conf *Config, path string) string {
+	return fmt.Sprintf(
+		"%s/%s?consumer_key=%s&consumer_secret=%s",
+		wpEndpoint(conf, strings.Trim(conf.ApiNamespace, "/")), strings.TrimLeft(path, "/"),
+		conf.WooConsumerKey, conf.WooConsumerSecret,
+	)
+}
+
+// httpTrace makes newClient log the timings of every request; set by --trace.
+var httpTrace bool
+
+// logTrace logs where the time of a traced request went. The query string is
+// left out since it carries the API credentials.
+func logTrace(_ *resty.Client, resp *resty.Response) error {
+	ti := resp.Request.TraceInfo()
+	u, err := url.Parse(resp.Request.URL)
+	target := resp.Request.URL
+	if err == nil {
+		target = u.Scheme + "://" + u.Host + u.Path
+	}
+	slog.Debug("Request timings",
+		"method", resp.Request.Method, "url", target, "status", resp.StatusCode(),
+		"dns", ti.DNSLookup, "connect", ti.ConnTime, "tls", ti.TLSHandshake,
+		"server", ti.ServerTime, "total", ti.TotalTime, "conn_reused", ti.IsConnReused)
+	return nil
+}
+
+// newClient returns a resty client carrying the settings shared by every
+// WordPress and WooCommerce request.
+func newClient(conf *Config) *resty.Client {
+	userAgent := conf.UserAgent
+	if userAgent == "" {
+		userAgent = "wooh/" + version()
+	}
+	client := resty.New().SetHeader("User-Agent", userAgent)
+	// Product pages carry full HTML descriptions and shrink several times
+	// under gzip. The header is set explicitly so Go's transport leaves the
+	// body compressed and resty decompresses it.
+	if conf.DisableCompression {
+		client.SetHeader("Accept-Encoding", "identity")
+	} else {
+		client.SetHeader("Accept-Encoding", "gzip")
+	}
+	if conf.ProxyURL != "" {
+		client.SetProxy(conf.ProxyURL)
+	}
+	if conf.OfflineProducts != "" {
+		client.SetTransport(offlineTransport{})
+	}
+	if httpTrace {
+		client.EnableTrace().OnAfterResponse(logTrace)
+	}
+	return client
+}
+
+// newOpenAIClient returns an OpenAI client routed through conf.ProxyURL when
+// set. Without it, both clients fall back to HTTP_PROXY/HTTPS_PROXY.
+func newOpenAIClient(conf *Config) *openai.Client {
+	cfg := openai.DefaultConfig(conf.OpenAIKey)
+	if conf.GeneratorBackend == GeneratorLocal {
+		cfg.BaseURL = conf.GeneratorBaseURL
+	}
+	if conf.ProxyURL != "" {
+		cfg.HTTPClient = generatorHTTPClient(conf.ProxyURL)
+	}
+	return openai.NewClientWithConfig(cfg)
+}
+
+var proxyClients sync.Map // proxy_url -> *http.Client
+
+// generatorHTTPClient returns the HTTP client for generation requests sent
+// through proxyURL, or through HTTP_PROXY/HTTPS_PROXY when it is empty. One
+// client is kept per proxy so a long run reuses its connections instead of
+// leaving a transport behind with every request.
+func generatorHTTPClient(proxyURL string) *http.Client {
+	if c, ok := proxyClients.Load(proxyURL); ok {
+		return c.(*http.Client)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		if proxy, err := url.Parse(proxyURL); err == nil {
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+	}
+	c, _ := proxyClients.LoadOrStore(proxyURL, &http.Client{Transport: transport})
+	return c.(*http.Client)
+}
+
+func ValidateProxyURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid proxy_url %q: %w", raw, err)
+	}
+	for _, scheme := range allowedProxySchemes {
+		if u.Scheme == scheme && u.Host != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported proxy_url %q (want scheme://host:port with scheme one of %s)", raw, strings.Join(allowedProxySchemes, ", "))
+}
+
+// WooAPIError is the error body WordPress and WooCommerce return on failure.
+type WooAPIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *WooAPIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// ErrHTMLResponse means the server answered with an HTML page, typically a
+// security plugin or WAF block page, where JSON was expected.
+var ErrHTMLResponse = errors.New("received HTML instead of JSON")
+
+var htmlTitleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// htmlError returns an ErrHTMLResponse naming the page title when resp is
+// an HTML page, and nil otherwise.
+func htmlError(resp *resty.Response) error {
+	body := strings.TrimSpace(resp.String())
+	isHTML := strings.Contains(resp.Header().Get("Content-Type"), "text/html") ||
+		strings.HasPrefix(strings.ToLower(body), "<!doctype html") ||
+		strings.HasPrefix(strings.ToLower(body), "<html")
+	if !isHTML {
+		return nil
+	}
+	err := fmt.Errorf("%w (status %d); possibly blocked by security plugin", ErrHTMLResponse, resp.StatusCode())
+	if m := htmlTitleRegex.FindStringSubmatch(body); m != nil {
+		if title := strings.Join(strings.Fields(m[1]), " "); title != "" {
+			err = fmt.Errorf("%w: %q", err, title)
+		}
+	}
+	return err
+}
+
+// decodeJSON unmarshals the body of resp into v, reporting HTML pages
+// clearly rather than as a JSON syntax error.
+func decodeJSON(resp *resty.Response, v interface{}) error {
+	if err := htmlError(resp); err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.Body(), v)
+}
+
+// ErrHostRateLimited means a managed WordPress host throttled the request
+// itself, before it reached WordPress. These blocks tend to last minutes.
+var ErrHostRateLimited = errors.New("rate limited by the host")
+
+// hostRateLimitSignature identifies a managed host's rate-limit response by
+// a header it sets or a marker in its error page.
+type hostRateLimitSignature struct {
+	host   string
+	header string
+	marker string // matched case-insensitively against the body
+}
+
+var hostRateLimitSignatures = []hostRateLimitSignature{
+	{host: "WP Engine", header: "Wpe-Backend", marker: "wpengine"},
+	{host: "Kinsta", header: "X-Kinsta-Cache", marker: "kinsta"},
+	{host: "Pantheon", header: "X-Pantheon-Styx-Hostname", marker: "pantheon"},
+	{host: "SiteGround", marker: "siteground"},
+	{host: "Cloudflare", marker: "error code: 1015"},
+}
+
+// hostRateLimitError returns an ErrHostRateLimited naming the host when resp
+// is a 429 or 503 carrying one of the known host signatures, and nil
+// otherwise.
+func hostRateLimitError(resp *resty.Response) error {
+	if resp.StatusCode() != 429 && resp.StatusCode() != 503 {
+		return nil
+	}
+	body := strings.ToLower(resp.String())
+	for _, sig := range hostRateLimitSignatures {
+		if (sig.header != "" && resp.Header().Get(sig.header) != "") || strings.Contains(body, sig.marker) {
+			return fmt.Errorf("%w (%s, status %d)", ErrHostRateLimited, sig.host, resp.StatusCode())
+		}
+	}
+	return nil
+}
+
+// apiError converts an error response into a *WooAPIError, falling back to
+// the raw body when it isn't a WordPress error object.
+func apiError(resp *resty.Response) error {
+	if err := hostRateLimitError(resp); err != nil {
+		return err
+	}
+	if err := htmlError(resp); err != nil {
+		return err
+	}
+	apiErr := &WooAPIError{Status: resp.StatusCode()}
+	if err := json.Unmarshal(resp.Body(), apiErr); err != nil || apiErr.Code == "" {
+		return fmt.Errorf("%s, %s", resp.Status(), resp.String())
+	}
+	return apiErr
+}
@@ -0,0 +1,404 @@
+package wooh
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"default", "", "wooh/" + version()},
+		{"configured", "acme-sync/2.0", "acme-sync/2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("User-Agent")
+				w.Write([]byte(`{"id": 1}`))
+			})
+			conf.UserAgent = tt.userAgent
+			if _, err := GetProduct(conf, 1); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("User-Agent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionNeverEmpty(t *testing.T) {
+	if v := version(); v == "" {
+		t.Error("version() is empty")
+	}
+}
+
+func TestGeneratorHTTPClientThroughProxy(t *testing.T) {
+	// An HTTP proxy is sent the absolute URL of every request it forwards.
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`))
+	}))
+	defer proxy.Close()
+
+	conf := &Config{
+		OpenAIKey:        "sk-test",
+		GeneratorBackend: GeneratorLocal,
+		GeneratorBaseURL: "http://llm.invalid/v1",
+		ProxyURL:         proxy.URL,
+	}
+	for i := 0; i < 3; i++ {
+		_, err := newOpenAIClient(conf).CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+			Model:    "test",
+			Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(proxied) != 3 || proxied[0] != "http://llm.invalid/v1/chat/completions" {
+		t.Errorf("proxy saw %v", proxied)
+	}
+
+	if generatorHTTPClient(proxy.URL) != generatorHTTPClient(proxy.URL) {
+		t.Error("each call built a new client for the same proxy")
+	}
+	if generatorHTTPClient(proxy.URL) == generatorHTTPClient("") {
+		t.Error("proxied and direct requests share a client")
+	}
+}
+
+func TestValidateApiNamespace(t *testing.T) {
+	tests := []struct {
+		namespace string
+		custom    bool
+		wantErr   bool
+	}{
+		{"wc/v3", false, false},
+		{"/wc/v2/", false, false},
+		{"wc/v1", false, false},
+		{"", false, true},
+		{"acme/v1", false, true},
+		{"acme/v1", true, false},
+		{"acme/v1?x=1", true, true},
+	}
+	for _, tt := range tests {
+		err := ValidateApiNamespace(&Config{ApiNamespace: tt.namespace, AllowCustomNamespace: tt.custom})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateApiNamespace(%q, custom %v) = %v, want error %v", tt.namespace, tt.custom, err, tt.wantErr)
+		}
+	}
+}
+
+func TestApiNamespaceEndpoint(t *testing.T) {
+	tests := []struct {
+		namespace string
+		wantPath  string
+	}{
+		{"", "/wp-json/wc/v3/products/12"},
+		{"wc/v2", "/wp-json/wc/v2/products/12"},
+		{"/acme/v1/", "/wp-json/acme/v1/products/12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantPath, func(t *testing.T) {
+			var got string
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Path
+				w.Write([]byte(`{"id": 12}`))
+			})
+			conf.ApiNamespace = tt.namespace
+			applyDefaults(conf)
+			if _, err := GetProduct(conf, 12); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.wantPath {
+				t.Errorf("requested %s, want %s", got, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestSiteURL(t *testing.T) {
+	tests := []struct {
+		site string
+		want string
+	}{
+		{"example.com", "https://example.com"},
+		{" example.com/ ", "https://example.com"},
+		{"http://localhost:8080", "http://localhost:8080"},
+		{"example.com/shop", "https://example.com/shop"},
+		{"https://example.com/shop/store//", "https://example.com/shop/store"},
+	}
+	for _, tt := range tests {
+		if got := siteURL(&Config{Site: tt.site}); got != tt.want {
+			t.Errorf("siteURL(%q) = %q, want %q", tt.site, got, tt.want)
+		}
+	}
+}
+
+func TestSubpathSite(t *testing.T) {
+	var paths []string
+	conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/shop/wp-json/wc/v3/products" {
+			w.Write([]byte(`[{"id": 3}]`))
+			return
+		}
+		w.Write([]byte(`{"id": 3}`))
+	})
+	conf.Site += "/shop/"
+
+	if _, err := GetProduct(conf, 3); err != nil {
+		t.Fatal(err)
+	}
+	products, err := fetchProductPages(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 1 {
+		t.Errorf("listed %d products, want 1", len(products))
+	}
+	want := []string{"/shop/wp-json/wc/v3/products/3", "/shop/wp-json/wc/v3/products"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("requested %v, want %v", paths, want)
+	}
+}
+
+func TestValidateOrdering(t *testing.T) {
+	tests := []struct {
+		orderBy, order string
+		wantErr        bool
+	}{
+		{"id", "asc", false},
+		{"price", "desc", false},
+		{"menu_order", "asc", false},
+		{"random", "asc", true},
+		{"id", "up", true},
+	}
+	for _, tt := range tests {
+		err := ValidateOrdering(&Config{OrderBy: tt.orderBy, Order: tt.order})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateOrdering(%s, %s) = %v, want error %v", tt.orderBy, tt.order, err, tt.wantErr)
+		}
+	}
+}
+
+func TestProductOrdering(t *testing.T) {
+	tests := []struct {
+		orderBy, order string
+	}{
+		{"", ""}, // defaults: id asc
+		{"price", "desc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.orderBy+" "+tt.order, func(t *testing.T) {
+			var query url.Values
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query()
+				// The store's order, whatever it is, must survive.
+				w.Write([]byte(`[{"id": 3}, {"id": 1}, {"id": 2}]`))
+			})
+			conf.OrderBy, conf.Order = tt.orderBy, tt.order
+			applyDefaults(conf)
+
+			products, err := fetchProductPages(conf, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query.Get("orderby") != conf.OrderBy || query.Get("order") != conf.Order {
+				t.Errorf("sent orderby=%q order=%q, want %q %q", query.Get("orderby"), query.Get("order"), conf.OrderBy, conf.Order)
+			}
+			var ids []int64
+			for _, p := range products {
+				ids = append(ids, p.ID)
+			}
+			if !slices.Equal(ids, []int64{3, 1, 2}) {
+				t.Errorf("products in order %v, want the store's [3 1 2]", ids)
+			}
+		})
+	}
+}
+
+func TestHTMLErrorPages(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		wantHTML    bool
+		wantText    string
+	}{
+		{
+			name:        "security plugin block",
+			contentType: "text/html; charset=UTF-8",
+			status:      http.StatusForbidden,
+			body:        "<html><head><title>\n  Access Denied -\n Wordfence </title></head><body>Blocked</body></html>",
+			wantHTML:    true,
+			wantText:    `(status 403); possibly blocked by security plugin: "Access Denied - Wordfence"`,
+		},
+		{
+			name:        "doctype without a content type",
+			contentType: "application/octet-stream",
+			status:      http.StatusOK,
+			body:        "<!DOCTYPE html><html><body>Maintenance</body></html>",
+			wantHTML:    true,
+			wantText:    "(status 200); possibly blocked by security plugin",
+		},
+		{
+			name:        "WooCommerce JSON error",
+			contentType: "application/json",
+			status:      http.StatusNotFound,
+			body:        `{"code":"woocommerce_rest_product_invalid_id","message":"Invalid ID."}`,
+			wantText:    "404 woocommerce_rest_product_invalid_id: Invalid ID.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			resp, err := newClient(conf).R().Get(wooEndpoint(conf, "products/1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var product WooProduct
+			decodeErr := decodeJSON(resp, &product)
+			for _, err := range []error{apiError(resp), decodeErr} {
+				if tt.wantHTML != errors.Is(err, ErrHTMLResponse) {
+					t.Errorf("err = %v, want HTML error %v", err, tt.wantHTML)
+				}
+			}
+			if err := apiError(resp); !strings.Contains(err.Error(), tt.wantText) {
+				t.Errorf("apiError = %q, want it to contain %q", err, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestProductFetchCompression(t *testing.T) {
+	tests := []struct {
+		name         string
+		disable      bool
+		wantEncoding string
+	}{
+		{"gzip", false, "gzip"},
+		{"compression disabled", true, "identity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var products []map[string]interface{}
+			for id := 1; id <= 50; id++ {
+				p := testProduct(id, "Oak Board")
+				p["description"] = "<p>" + strings.Repeat("Solid oak, oiled and ready to fit in any room. ", 40) + "</p>"
+				products = append(products, p)
+			}
+			body, _ := json.Marshal(products)
+
+			var encoding string
+			var sent int
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				encoding = r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("X-WP-TotalPages", "1")
+				if !strings.Contains(encoding, "gzip") {
+					sent, _ = w.Write(body)
+					return
+				}
+				var buf bytes.Buffer
+				zw := gzip.NewWriter(&buf)
+				zw.Write(body)
+				zw.Close()
+				w.Header().Set("Content-Encoding", "gzip")
+				sent, _ = w.Write(buf.Bytes())
+			})
+			conf.DisableCompression = tt.disable
+
+			cache, err := NewCache(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := GetProducts(conf, cache, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 50 || got[49].Description != products[49]["description"] {
+				t.Fatalf("decoded %d products", len(got))
+			}
+			if encoding != tt.wantEncoding {
+				t.Errorf("Accept-Encoding = %q, want %q", encoding, tt.wantEncoding)
+			}
+			if !tt.disable && sent > len(body)/10 {
+				t.Errorf("sent %d bytes for %d bytes of JSON", sent, len(body))
+			}
+		})
+	}
+}
+
+func TestHTTPTraceLogsTimings(t *testing.T) {
+	restoreLogger(t)
+	trace := httpTrace
+	t.Cleanup(func() { httpTrace = trace })
+
+	conf, _ := newTestStore(t, newFakeStore(testProduct(1, "Oak Board")).handle)
+	for _, enabled := range []bool{false, true} {
+		var logs bytes.Buffer
+		slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		httpTrace = enabled
+		if _, err := GetProduct(conf, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		var entry map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var e map[string]interface{}
+			if json.Unmarshal([]byte(line), &e) == nil && e["msg"] == "Request timings" {
+				entry = e
+			}
+		}
+		if !enabled {
+			if entry != nil {
+				t.Errorf("timings logged without --trace: %v", entry)
+			}
+			continue
+		}
+		if entry == nil {
+			t.Fatalf("no timings logged with --trace:\n%s", logs.String())
+		}
+		if entry["level"] != "DEBUG" || entry["method"] != "GET" || entry["status"] != float64(200) {
+			t.Errorf("timings entry = %v", entry)
+		}
+		if total, _ := entry["total"].(float64); total <= 0 {
+			t.Errorf("total time = %v, want it measured", entry["total"])
+		}
+		for _, key := range []string{"dns", "connect", "tls", "server"} {
+			if _, ok := entry[key]; !ok {
+				t.Errorf("timings entry lacks %s", key)
+			}
+		}
+		if logged := entry["url"].(string); strings.Contains(logged, conf.WooConsumerSecret) || !strings.HasSuffix(logged, "/products/1") {
+			t.Errorf("logged url = %s", logged)
+		}
+	}
+}
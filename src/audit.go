@@ -0,0 +1,82 @@
+package wooh
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// DuplicateGroup is a set of products sharing the same normalized name or SKU.
+type DuplicateGroup struct {
+	Key      string
+	Products []WooProduct
+}
+
+type DuplicateReport struct {
+	ByName []DuplicateGroup
+	BySku  []DuplicateGroup
+}
+
+// FindDuplicates groups the cached products by normalized name and by SKU
+// and returns the groups with more than one member.
+func FindDuplicates(conf *Config) (DuplicateReport, error) {
+	cache, err := NewCache(conf)
+	if err != nil {
+		return DuplicateReport{}, err
+	}
+	products, err := GetProducts(conf, cache, 24*time.Hour)
+	if err != nil {
+		return DuplicateReport{}, err
+	}
+
+	return DuplicateReport{
+		ByName: groupDuplicates(products, func(p WooProduct) string {
+			return conf.NameRules.normalize(p.Name)
+		}),
+		BySku: groupDuplicates(products, func(p WooProduct) string {
+			return strings.TrimSpace(p.Sku)
+		}),
+	}, nil
+}
+
+// groupDuplicates buckets products by key, ignoring empty keys, and returns
+// the buckets with more than one product sorted by key.
+func groupDuplicates(products []WooProduct, key func(WooProduct) string) []DuplicateGroup {
+	buckets := make(map[string][]WooProduct)
+	for _, p := range products {
+		if k := key(p); k != "" {
+			buckets[k] = append(buckets[k], p)
+		}
+	}
+
+	var groups []DuplicateGroup
+	for k, members := range buckets {
+		if len(members) > 1 {
+			groups = append(groups, DuplicateGroup{Key: k, Products: members})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+// MetaKeyHistogram counts how often each meta_data key occurs across the
+// cached products, which shows the SEO plugin and meta conventions a store
+// uses.
+func MetaKeyHistogram(conf *Config) (map[string]int, error) {
+	cache, err := NewCache(conf)
+	if err != nil {
+		return nil, err
+	}
+	products, err := GetProducts(conf, cache, 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, p := range products {
+		for _, entry := range p.MetaData {
+			counts[entry.Key]++
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,81 @@
+package wooh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	named := func(id int, name, sku string) map[string]interface{} {
+		p := testProduct(id, name)
+		p["sku"] = sku
+		return p
+	}
+	store := newFakeStore(
+		named(1, "Oak Board", "OAK-1"),
+		named(2, "oak  board!", "OAK-2"),
+		named(3, "Walnut Board", "OAK-1"),
+		named(4, "Pine Board", ""),
+		named(5, "Ash Board", ""),
+		named(6, "Birch Board", " BIRCH "),
+		named(7, "Birch Panel", "BIRCH"),
+	)
+	conf, _ := newTestStore(t, store.handle)
+
+	report, err := FindDuplicates(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := func(groups []DuplicateGroup) map[string][]int64 {
+		out := map[string][]int64{}
+		for _, g := range groups {
+			for _, p := range g.Products {
+				out[g.Key] = append(out[g.Key], p.ID)
+			}
+		}
+		return out
+	}
+	// Products without a SKU are not duplicates of each other.
+	if got, want := ids(report.ByName), map[string][]int64{"oak board": {1, 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("by name = %v, want %v", got, want)
+	}
+	if got, want := ids(report.BySku), map[string][]int64{"BIRCH": {6, 7}, "OAK-1": {1, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("by SKU = %v, want %v", got, want)
+	}
+	if report.BySku[0].Key != "BIRCH" {
+		t.Errorf("SKU groups not sorted by key: %q first", report.BySku[0].Key)
+	}
+}
+
+func TestMetaKeyHistogram(t *testing.T) {
+	withMeta := func(id int, keys ...string) map[string]interface{} {
+		p := testProduct(id, "Oak Board")
+		var meta []interface{}
+		for i, key := range keys {
+			meta = append(meta, map[string]interface{}{"id": id*10 + i, "key": key, "value": "x"})
+		}
+		p["meta_data"] = meta
+		return p
+	}
+	store := newFakeStore(
+		withMeta(1, yoastTitleKey, yoastDescKey, "_rank_math_title"),
+		withMeta(2, yoastTitleKey, "_wc_gla_brand"),
+		withMeta(3, yoastTitleKey, yoastDescKey),
+		withMeta(4),
+	)
+	conf, _ := newTestStore(t, store.handle)
+
+	counts, err := MetaKeyHistogram(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		yoastTitleKey:      3,
+		yoastDescKey:       2,
+		"_rank_math_title": 1,
+		"_wc_gla_brand":    1,
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("histogram = %v, want %v", counts, want)
+	}
+}
@@ -0,0 +1,93 @@
+package wooh
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord is one line of the audit log: the outcome of writing one
+// product.
+type AuditRecord struct {
+	Timestamp time.Time         `json:"timestamp"`
+	ID        int64             `json:"id"`
+	Op        string            `json:"op"`
+	Old       map[string]string `json:"old,omitempty"`
+	New       map[string]string `json:"new,omitempty"`
+	Result    string            `json:"result"` // updated or failed
+	Error     string            `json:"error,omitempty"`
+}
+
+// auditLog appends AuditRecords as JSON lines to the file named by
+// audit_log. Unlike the tracker it is never rewritten, so it keeps the
+// history of every run across tracker resets. A nil auditLog records
+// nothing.
+type auditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &auditLog{file: f}, nil
+}
+
+// add appends rec. A failed write is logged rather than failing the run.
+func (a *auditLog) add(rec AuditRecord) {
+	if a == nil {
+		return
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		slog.Warn("Could not encode audit record", "err", err)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		slog.Warn("Could not write audit log", "err", err)
+	}
+}
+
+func (a *auditLog) close() error {
+	if a == nil {
+		return nil
+	}
+	return a.file.Close()
+}
+
+// auditRecord describes the write of u: the meta keys and descriptions it
+// sets, with the values they replace.
+func (u *ProductUpdate) auditRecord(err error) AuditRecord {
+	rec := AuditRecord{
+		Timestamp: time.Now().UTC(),
+		ID:        u.Product.ID,
+		Op:        "seo",
+		Old:       make(map[string]string),
+		New:       make(map[string]string),
+		Result:    "updated",
+	}
+	for _, entry := range u.MetaData {
+		rec.Old[entry.Key] = u.Product.MetaData.Get(entry.Key)
+		rec.New[entry.Key] = fmt.Sprint(entry.Value)
+	}
+	if u.Description != "" {
+		rec.Old["description"] = u.Product.Description
+		rec.New["description"] = u.Description
+	}
+	if u.ShortDescription != "" {
+		rec.Old["short_description"] = u.Product.ShortDescription
+		rec.New["short_description"] = u.ShortDescription
+	}
+	if err != nil {
+		rec.Result = "failed"
+		rec.Error = err.Error()
+	}
+	return rec
+}
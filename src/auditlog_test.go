@@ -0,0 +1,89 @@
+package wooh
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readAuditLog decodes every record in the audit log at path.
+func readAuditLog(t *testing.T, path string) []AuditRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []AuditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("malformed audit line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestAuditLogAppendsAcrossRuns(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Walnut Board"))
+	conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/products/2") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":"rest_invalid_param","message":"Invalid parameter(s): meta_data"}`))
+			return
+		}
+		store.handle(w, r)
+	})
+	conf.OpenAIStub = true
+	conf.AuditLog = filepath.Join(t.TempDir(), "audit.jsonl")
+
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	first := readAuditLog(t, conf.AuditLog)
+	if len(first) != 2 {
+		t.Fatalf("first run logged %d records, want 2: %+v", len(first), first)
+	}
+	byID := make(map[int64]AuditRecord)
+	for _, rec := range first {
+		byID[rec.ID] = rec
+	}
+	if rec := byID[1]; rec.Result != "updated" || rec.Op != "seo" || rec.New[yoastTitleKey] == "" || rec.Error != "" {
+		t.Errorf("record for product 1 = %+v", rec)
+	}
+	if rec := byID[2]; rec.Result != "failed" || !strings.Contains(rec.Error, "rest_invalid_param") {
+		t.Errorf("record for product 2 = %+v, want a failure", rec)
+	}
+
+	// Resetting the tracker starts the SEO pass over but keeps the history.
+	if _, err := UpdateSEO(conf, SEOOptions{RestartTracking: true, Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	all := readAuditLog(t, conf.AuditLog)
+	if len(all) != 4 {
+		t.Fatalf("after a tracker reset the log has %d records, want 4", len(all))
+	}
+	for i, rec := range first {
+		if all[i].ID != rec.ID || all[i].Result != rec.Result || !all[i].Timestamp.Equal(rec.Timestamp) {
+			t.Errorf("record %d rewritten: %+v, was %+v", i, all[i], rec)
+		}
+	}
+}
+
+func TestAuditLogWithoutConfig(t *testing.T) {
+	var audit *auditLog
+	audit.add(AuditRecord{ID: 1})
+	if err := audit.close(); err != nil {
+		t.Errorf("close of a nil audit log = %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package wooh
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+type SEOBackupEntry struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	FocusKW     string `json:"focuskw"`
+}
+
+// BackupSEO writes the current Yoast title, description and focus keyphrase
+// of every product to path as JSON. Products are fetched live.
+func BackupSEO(conf *Config, path string) error {
+	cache, err := NewCache(conf)
+	if err != nil {
+		return err
+	}
+	products, err := GetProducts(conf, cache, 0)
+	if err != nil {
+		return fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	entries := make([]SEOBackupEntry, 0, len(products))
+	for _, p := range products {
+		entries = append(entries, SEOBackupEntry{
+			ID:          p.ID,
+			Title:       p.MetaData.YoastTitle(),
+			Description: p.MetaData.YoastDesc(),
+			FocusKW:     p.MetaData.YoastFocusKW(),
+		})
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SEO backup: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write SEO backup: %w", err)
+	}
+
+	slog.Info("Backed up SEO meta", "products", len(entries), "path", path)
+	return nil
+}
+
+// RestoreSEO pushes the entries of a BackupSEO file back to the store.
+func RestoreSEO(conf *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read SEO backup: %w", err)
+	}
+
+	var entries []SEOBackupEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse SEO backup: %w", err)
+	}
+
+	updates := make([]map[string]interface{}, 0, len(entries))
+	for _, e := range entries {
+		updates = append(updates, map[string]interface{}{
+			"id": e.ID,
+			"meta_data": MetaData{
+				{Key: yoastTitleKey, Value: e.Title},
+				{Key: yoastDescKey, Value: e.Description},
+				{Key: yoastFocusKWKey, Value: e.FocusKW},
+			},
+		})
+	}
+
+	if !confirmDestructive(len(updates), "overwrite SEO meta from "+path) {
+		return ErrNotConfirmed
+	}
+	slog.Info("Restoring SEO meta", "products", len(updates), "path", path)
+	return BatchUpdateProducts(conf, updates)
+}
@@ -0,0 +1,77 @@
+package wooh
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBackupRestoreSEO(t *testing.T) {
+	seoProduct := func(id int, title, desc, kw string) map[string]interface{} {
+		p := testProduct(id, "Board")
+		p["meta_data"] = []interface{}{
+			map[string]interface{}{"key": yoastTitleKey, "value": title},
+			map[string]interface{}{"key": yoastDescKey, "value": desc},
+			map[string]interface{}{"key": yoastFocusKWKey, "value": kw},
+			map[string]interface{}{"key": "_other", "value": "kept"},
+		}
+		return p
+	}
+	store := newFakeStore(
+		seoProduct(1, "Oak Board", "Solid oak.", "oak board"),
+		seoProduct(2, "Walnut Board", "Solid walnut.", ""),
+	)
+	conf, _ := newTestStore(t, store.handle)
+	path := filepath.Join(t.TempDir(), "seo-backup.json")
+
+	if err := BackupSEO(conf, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []SEOBackupEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := []SEOBackupEntry{
+		{ID: 1, Title: "Oak Board", Description: "Solid oak.", FocusKW: "oak board"},
+		{ID: 2, Title: "Walnut Board", Description: "Solid walnut."},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("backup = %+v, want %+v", entries, want)
+	}
+
+	// Overwrite the meta, as an autofill run would.
+	store.products[1] = seoProduct(1, "Generated", "Generated.", "generated")
+	store.products[2] = seoProduct(2, "Generated", "Generated.", "generated")
+
+	restore := assumeYes
+	t.Cleanup(func() { assumeYes = restore })
+	assumeYes = false
+	if err := RestoreSEO(conf, path); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("unconfirmed restore = %v, want ErrNotConfirmed", err)
+	}
+	if len(store.written()) != 0 {
+		t.Fatal("unconfirmed restore wrote to the store")
+	}
+
+	assumeYes = true
+	if err := RestoreSEO(conf, path); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range want {
+		meta := store.product(e.ID).MetaData
+		got := SEOBackupEntry{ID: e.ID, Title: meta.YoastTitle(), Description: meta.YoastDesc(), FocusKW: meta.YoastFocusKW()}
+		if got != e {
+			t.Errorf("restored %+v, want %+v", got, e)
+		}
+		if meta.Get("_other") != "kept" {
+			t.Errorf("product %d lost meta the backup does not cover", e.ID)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package wooh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WooCommerce rejects batch requests with more than 100 objects.
+const batchLimit = 100
+
+type batchResponse struct {
+	Update []struct {
+		ID    int64 `json:"id"`
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error,omitempty"`
+	} `json:"update"`
+}
+
+// BatchUpdateProducts sends updates (each carrying an "id") through the
+// products/batch endpoint in chunks of batchLimit.
+func BatchUpdateProducts(conf *Config, updates []map[string]interface{}) error {
+	client := newClient(conf)
+
+	var failures []string
+	for start := 0; start < len(updates); start += batchLimit {
+		end := start + batchLimit
+		if end > len(updates) {
+			end = len(updates)
+		}
+
+		resp, err := client.R().
+			SetHeader("Content-Type", "application/json").
+			SetBody(map[string]interface{}{"update": updates[start:end]}).
+			Post(wooEndpoint(conf, "products/batch"))
+		if err != nil {
+			return fmt.Errorf("failed to send batch update: %w", err)
+		}
+		if resp.IsError() {
+			return fmt.Errorf("batch update failed: %w", apiError(resp))
+		}
+
+		var result batchResponse
+		if err := decodeJSON(resp, &result); err != nil {
+			return fmt.Errorf("failed to parse batch response: %w", err)
+		}
+		for _, item := range result.Update {
+			if item.Error != nil {
+				failures = append(failures, fmt.Sprintf("product %d: %s", item.ID, item.Error.Message))
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("batch update failed for %d products: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// GetProductsByID fetches the given products live from the API, bypassing the cache.
+func GetProductsByID(conf *Config, ids []int) ([]WooProduct, error) {
+	client := newClient(conf)
+
+	var products []WooProduct
+	for start := 0; start < len(ids); start += batchLimit {
+		end := start + batchLimit
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		include := make([]string, 0, end-start)
+		for _, id := range ids[start:end] {
+			include = append(include, fmt.Sprintf("%d", id))
+		}
+
+		resp, err := client.R().
+			SetHeader("Accept", "application/json").
+			SetQueryParams(productQueryParams(conf)).
+			SetQueryParams(map[string]string{
+				"include":  strings.Join(include, ","),
+				"per_page": fmt.Sprintf("%d", batchLimit),
+			}).
+			Get(wooEndpoint(conf, "products"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch products: %w", err)
+		}
+		if resp.IsError() {
+			return nil, fmt.Errorf("error fetching products: %w", apiError(resp))
+		}
+
+		var page []WooProduct
+		if err := decodeProducts(conf, resp, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse products: %w", err)
+		}
+		products = append(products, page...)
+	}
+
+	return products, nil
+}
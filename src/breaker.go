@@ -0,0 +1,176 @@
+package wooh
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCircuitBreakerThreshold = 5
+	defaultCircuitBreakerCooldown  = 30 * time.Second
+	defaultHostRateLimitCooldown   = 5 * time.Minute
+)
+
+// ErrCircuitOpen aborts a run after the store kept failing, including the
+// probe sent once the circuit breaker's cooldown had passed.
+var ErrCircuitOpen = errors.New("store keeps failing, circuit breaker open")
+
+type breakerState int
+
+const (
+	breakerClosed  breakerState = iota // requests flow
+	breakerOpen                        // waiting for the cooldown before a probe
+	breakerTripped                     // the probe failed too; the run is over
+)
+
+// circuitBreaker stops a run from hammering a store that is down. After
+// threshold consecutive failures it holds every write for the cooldown, then
+// lets a single probe through: success closes it again, failure trips it for
+// good. A managed host's rate-limit response opens it at once for the
+// longer host cooldown.
+type circuitBreaker struct {
+	threshold    int
+	cooldown     time.Duration
+	hostCooldown time.Duration
+
+	mu       sync.Mutex
+	changed  chan struct{} // closed and replaced when a probe finishes
+	state    breakerState
+	failures int
+	openedAt time.Time
+	openFor  time.Duration // cooldown of the current opening
+	probing  bool
+}
+
+// newCircuitBreaker returns a breaker for conf, or nil when it is disabled
+// by a negative circuit_breaker_threshold. A nil breaker allows everything.
+func newCircuitBreaker(conf *Config) *circuitBreaker {
+	if conf.CircuitBreakerThreshold < 0 {
+		return nil
+	}
+	return &circuitBreaker{
+		threshold:    conf.CircuitBreakerThreshold,
+		cooldown:     conf.CircuitBreakerCooldown,
+		hostCooldown: conf.HostRateLimitCooldown,
+		changed:      make(chan struct{}),
+	}
+}
+
+// allow blocks while the circuit is open and returns ErrCircuitOpen once it
+// has tripped, or ctx's error if ctx is done first.
+func (b *circuitBreaker) allow(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		switch b.state {
+		case breakerClosed:
+			b.mu.Unlock()
+			return nil
+		case breakerTripped:
+			b.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		wait := b.openFor - time.Since(b.openedAt)
+		if !b.probing && wait <= 0 {
+			b.probing = true
+			b.mu.Unlock()
+			slog.Warn("Circuit breaker half-open, probing the store")
+			return nil
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		// While a probe is out only its outcome can let us through.
+		var timeout <-chan time.Time
+		var timer *time.Timer
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// record reports the outcome of a request let through by allow. Errors the
+// store sent on purpose, such as a 404 or a validation error, say nothing
+// about its health and are not counted.
+func (b *circuitBreaker) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.wake()
+
+	if !isStoreFailure(err) {
+		b.failures = 0
+		b.state = breakerClosed
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	switch {
+	case b.probing:
+		b.probing = false
+		b.state = breakerTripped
+		slog.Error("Circuit breaker probe failed", "err", err)
+	case b.state == breakerClosed && errors.Is(err, ErrHostRateLimited):
+		slog.Warn("Circuit breaker open, pausing writes", "err", err, "cooldown", b.hostCooldown)
+		b.open(b.hostCooldown)
+	case b.state == breakerClosed && b.failures >= b.threshold:
+		slog.Warn("Circuit breaker open after consecutive store failures, pausing writes", "failures", b.failures, "cooldown", b.cooldown)
+		b.open(b.cooldown)
+	}
+}
+
+// open holds writes for cooldown. b.mu must be held.
+func (b *circuitBreaker) open(cooldown time.Duration) {
+	b.state = breakerOpen
+	b.openedAt = time.Now()
+	b.openFor = cooldown
+}
+
+// wake releases every allow waiting on the current state. b.mu must be held.
+func (b *circuitBreaker) wake() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// tripped reports whether the run should stop.
+func (b *circuitBreaker) tripped() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == breakerTripped
+}
+
+// isStoreFailure reports whether err suggests the store is unhealthy:
+// network errors, timeouts, HTML error pages, 429s and 5xx responses.
+func isStoreFailure(err error) bool {
+	if err == nil || errors.Is(err, ErrWriteNotPersisted) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *WooAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Status >= 500 || apiErr.Status == 429
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package wooh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testBreaker(threshold int, cooldown, hostCooldown time.Duration) *circuitBreaker {
+	return newCircuitBreaker(&Config{
+		CircuitBreakerThreshold: threshold,
+		CircuitBreakerCooldown:  cooldown,
+		HostRateLimitCooldown:   hostCooldown,
+	})
+}
+
+func TestCircuitBreakerRecord(t *testing.T) {
+	unavailable := &WooAPIError{Status: 503, Code: "unavailable"}
+	invalid := &WooAPIError{Status: 400, Code: "rest_invalid_param"}
+	rateLimited := fmt.Errorf("update product: %w", ErrHostRateLimited)
+
+	tests := []struct {
+		name string
+		errs []error
+		want breakerState
+	}{
+		{"successes", []error{nil, nil}, breakerClosed},
+		{"below threshold", []error{unavailable, unavailable}, breakerClosed},
+		{"threshold reached", []error{unavailable, unavailable, unavailable}, breakerOpen},
+		{"success resets the count", []error{unavailable, unavailable, nil, unavailable, unavailable}, breakerClosed},
+		{"client errors do not count", []error{invalid, invalid, invalid, invalid}, breakerClosed},
+		{"cancellations do not count", []error{context.Canceled, context.Canceled, context.Canceled}, breakerClosed},
+		{"network errors count", []error{errors.New("connection refused"), errors.New("EOF"), errors.New("timeout")}, breakerOpen},
+		{"host rate limit opens at once", []error{rateLimited}, breakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := testBreaker(3, time.Hour, time.Hour)
+			for _, err := range tt.errs {
+				b.record(err)
+			}
+			if b.state != tt.want {
+				t.Errorf("state = %v, want %v", b.state, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHostCooldown(t *testing.T) {
+	b := testBreaker(3, time.Second, time.Hour)
+	b.record(fmt.Errorf("%w", ErrHostRateLimited))
+	if b.openFor != time.Hour {
+		t.Errorf("opened for %s, want the host cooldown", b.openFor)
+	}
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	tests := []struct {
+		name        string
+		probe       error
+		wantState   breakerState
+		wantTripped bool
+	}{
+		{"probe succeeds", nil, breakerClosed, false},
+		{"probe fails", &WooAPIError{Status: 502}, breakerTripped, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := testBreaker(1, 20*time.Millisecond, time.Hour)
+			b.record(&WooAPIError{Status: 500})
+
+			start := time.Now()
+			if err := b.allow(context.Background()); err != nil {
+				t.Fatalf("probe not allowed: %v", err)
+			}
+			if waited := time.Since(start); waited < 20*time.Millisecond {
+				t.Errorf("probe let through after %s, before the cooldown", waited)
+			}
+
+			// A second writer waits for the probe's outcome.
+			second := make(chan error, 1)
+			go func() { second <- b.allow(context.Background()) }()
+			select {
+			case err := <-second:
+				t.Fatalf("second writer let through during the probe: %v", err)
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			b.record(tt.probe)
+			select {
+			case err := <-second:
+				if tt.wantTripped != errors.Is(err, ErrCircuitOpen) {
+					t.Errorf("second writer got %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("second writer still blocked after the probe finished")
+			}
+			if b.state != tt.wantState || b.tripped() != tt.wantTripped {
+				t.Errorf("state = %v, tripped = %v", b.state, b.tripped())
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerAllowCancelled(t *testing.T) {
+	b := testBreaker(1, time.Hour, time.Hour)
+	b.record(&WooAPIError{Status: 500})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.allow(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("allow = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("allow ignored the cancelled context")
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := testBreaker(-1, time.Hour, time.Hour)
+	if b != nil {
+		t.Fatal("negative threshold should disable the breaker")
+	}
+	b.record(&WooAPIError{Status: 500})
+	if err := b.allow(context.Background()); err != nil || b.tripped() {
+		t.Errorf("nil breaker blocked: %v", err)
+	}
+}
+
+func TestUpdateSEOStopsWhenCircuitOpens(t *testing.T) {
+	var products []map[string]interface{}
+	for i := 1; i <= 20; i++ {
+		products = append(products, testProduct(i, fmt.Sprintf("Board %d", i)))
+	}
+	store := newFakeStore(products...)
+	var writes atomic.Int32
+	conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			store.handle(w, r)
+			return
+		}
+		writes.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"code": "internal_error", "message": "down"}`))
+	})
+	conf.OpenAIStub = true
+	conf.WooConcurrency = 1
+	conf.CircuitBreakerThreshold = 3
+	conf.CircuitBreakerCooldown = 10 * time.Millisecond
+
+	result, err := UpdateSEO(conf, SEOOptions{Quiet: true})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("UpdateSEO = %v, want ErrCircuitOpen", err)
+	}
+	// Three failures open the circuit and the half-open probe fails too.
+	if n := writes.Load(); n != 4 {
+		t.Errorf("store received %d writes, want 4", n)
+	}
+	if result == nil || len(result.Updated) != 0 || len(result.Failed) == 0 || len(result.Failed) == 20 {
+		t.Errorf("result = %+v, want some products failed and the rest never attempted", result)
+	}
+}
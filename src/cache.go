@@ -0,0 +1,93 @@
+package wooh
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	CacheBackendFile   = "file"
+	CacheBackendMemory = "memory"
+)
+
+// Cache stores the product catalog between fetches. Backends other than
+// file and memory, such as a shared redis, only need these two methods and a
+// case in NewCache.
+type Cache interface {
+	// Fetch returns the cached products and true, or false when there are
+	// none or they are older than maxAge.
+	Fetch(maxAge time.Duration) ([]WooProduct, bool, error)
+	Save(products []WooProduct) error
+}
+
+func ValidateCacheBackend(backend string) error {
+	if backend != CacheBackendFile && backend != CacheBackendMemory {
+		return fmt.Errorf("unsupported cache_backend %q (allowed: %s, %s)", backend, CacheBackendFile, CacheBackendMemory)
+	}
+	return nil
+}
+
+var caches sync.Map // "memory:" and cache file path -> in-memory cache
+
+// NewCache returns the cache_backend cache for conf's cache_filename. Memory
+// caches live for the process, so every caller asking for the same file
+// shares one; they hold products only, never the config that created them.
+func NewCache(conf *Config) (Cache, error) {
+	cacheFilePath, err := CachePath(conf, conf.CacheFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.CacheBackend == CacheBackendMemory {
+		c, _ := caches.LoadOrStore(CacheBackendMemory+":"+cacheFilePath, &memoryCache{})
+		return c.(Cache), nil
+	}
+	return &fileCache{path: cacheFilePath, format: conf.CacheFormat}, nil
+}
+
+// fileCache keeps the catalog in a JSON or gob file, per cache_format.
+type fileCache struct {
+	path   string
+	format string
+	pc     ProductCache
+}
+
+func (c *fileCache) Fetch(maxAge time.Duration) ([]WooProduct, bool, error) {
+	products, err := loadCachedProducts(c.format, &c.pc, c.path, maxAge)
+	return products, products != nil, err
+}
+
+func (c *fileCache) Save(products []WooProduct) error {
+	if c.format == CacheFormatGob {
+		saveGobCache(c.path, products)
+	} else {
+		c.pc.SaveToCache(c.path, products)
+	}
+	return nil
+}
+
+// memoryCache keeps the catalog for the life of the process and never
+// touches the disk.
+type memoryCache struct {
+	mu         sync.Mutex
+	products   []WooProduct
+	lastUpdate time.Time
+}
+
+func (c *memoryCache) Fetch(maxAge time.Duration) ([]WooProduct, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.products == nil || time.Since(c.lastUpdate) > maxAge {
+		return nil, false, nil
+	}
+	return append([]WooProduct(nil), c.products...), true, nil
+}
+
+func (c *memoryCache) Save(products []WooProduct) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.products = append([]WooProduct{}, products...)
+	c.lastUpdate = time.Now()
+	return nil
+}
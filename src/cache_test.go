@@ -0,0 +1,270 @@
+package wooh
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheBackends(t *testing.T) {
+	products := []WooProduct{
+		{ID: 1, Name: "Oak Board", Sku: "OAK-1", RegularPrice: "10.00", MetaData: MetaData{{Key: "_yoast_wpseo_title", Value: "Oak"}}},
+		{ID: 2, Name: "Walnut Board", Categories: []WooCategory{{ID: 3, Name: "Boards", Slug: "boards"}}},
+	}
+	tests := []struct {
+		backend string
+		format  string
+	}{
+		{CacheBackendFile, CacheFormatJSON},
+		{CacheBackendFile, CacheFormatGob},
+		{CacheBackendMemory, CacheFormatJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend+"/"+tt.format, func(t *testing.T) {
+			conf := &Config{CacheDir: t.TempDir(), CacheBackend: tt.backend, CacheFormat: tt.format}
+			applyDefaults(conf)
+			cache, err := NewCache(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, ok, err := cache.Fetch(time.Hour); ok || err != nil || got != nil {
+				t.Fatalf("empty cache Fetch = %v, %v, %v", got, ok, err)
+			}
+			if err := cache.Save(products); err != nil {
+				t.Fatal(err)
+			}
+			got, ok, err := cache.Fetch(time.Hour)
+			if !ok || err != nil {
+				t.Fatalf("Fetch after Save = %v, %v", ok, err)
+			}
+			if len(got) != 2 || got[0].Name != "Oak Board" || got[0].MetaData.Get("_yoast_wpseo_title") != "Oak" || got[1].Categories[0].Slug != "boards" {
+				t.Errorf("Fetch returned %+v", got)
+			}
+			time.Sleep(time.Millisecond)
+			if _, ok, _ := cache.Fetch(time.Nanosecond); ok {
+				t.Error("Fetch returned products older than maxAge")
+			}
+
+			_, err = os.Stat(mustCachePath(t, conf, conf.CacheFilename))
+			if onDisk := err == nil; onDisk != (tt.backend == CacheBackendFile) {
+				t.Errorf("cache file on disk = %v for backend %s", onDisk, tt.backend)
+			}
+		})
+	}
+}
+
+func TestNewCacheFollowsEachConfig(t *testing.T) {
+	dir := t.TempDir()
+	jsonConf := &Config{CacheDir: dir, CacheFormat: CacheFormatJSON}
+	gobConf := &Config{CacheDir: dir, CacheFormat: CacheFormatGob}
+	applyDefaults(jsonConf)
+	applyDefaults(gobConf)
+
+	// The same file asked for with a different cache_format must not get the
+	// first caller's settings.
+	jsonCache, _ := NewCache(jsonConf)
+	if err := jsonCache.Save([]WooProduct{{ID: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	gobCache, _ := NewCache(gobConf)
+	if err := gobCache.Save([]WooProduct{{ID: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readGobCache(mustCachePath(t, gobConf, gobConf.CacheFilename)); err != nil {
+		t.Errorf("second config's cache not written as gob: %v", err)
+	}
+
+	memConf := *jsonConf
+	memConf.CacheBackend = CacheBackendMemory
+	a, _ := NewCache(&memConf)
+	b, _ := NewCache(&memConf)
+	if a != b {
+		t.Error("memory caches for the same file are not shared")
+	}
+}
+
+func TestGetProductsSharesOneFetch(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Walnut Board"))
+	conf, requests := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond) // keep the fetch in flight while the others arrive
+		store.handle(w, r)
+	})
+
+	const callers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each caller builds its own cache for the same file.
+			cache, err := NewCache(conf)
+			if err == nil {
+				var products []WooProduct
+				products, err = GetProducts(conf, cache, time.Hour)
+				if err == nil && len(products) != 2 {
+					t.Errorf("got %d products, want 2", len(products))
+				}
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("store fetched %d times, want 1", n)
+	}
+
+	cache, _ := NewCache(conf)
+	if _, err := GetProducts(conf, cache, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("fresh cache not used: store fetched %d times", n)
+	}
+}
+
+func TestGetProductsSingleflightScope(t *testing.T) {
+	t.Run("each cache file fetches once", func(t *testing.T) {
+		store := newFakeStore(testProduct(1, "Oak Board"))
+		conf, requests := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+			store.handle(w, r)
+		})
+		other := *conf
+		other.CacheFilename = "other-products.json"
+
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			c := conf
+			if i%2 == 1 {
+				c = &other
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cache, err := NewCache(c)
+				if err == nil {
+					_, err = GetProducts(c, cache, time.Hour)
+				}
+				if err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		if n := requests.Load(); n != 2 {
+			t.Errorf("store fetched %d times, want once per cache file", n)
+		}
+	})
+
+	t.Run("callers share the error", func(t *testing.T) {
+		conf, requests := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"code": "internal_error", "message": "down"}`))
+		})
+
+		var wg sync.WaitGroup
+		var failed atomic.Int32
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cache, _ := NewCache(conf)
+				if _, err := GetProducts(conf, cache, time.Hour); err != nil {
+					failed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if failed.Load() != 5 {
+			t.Errorf("%d of 5 callers saw the error", failed.Load())
+		}
+		if n := requests.Load(); n != 1 {
+			t.Errorf("store fetched %d times, want 1", n)
+		}
+	})
+}
+
+func TestMemoryCacheFreshness(t *testing.T) {
+	c := &memoryCache{}
+	if _, ok, _ := c.Fetch(time.Hour); ok {
+		t.Error("empty memory cache reported fresh")
+	}
+
+	c.Save([]WooProduct{})
+	if got, ok, _ := c.Fetch(time.Hour); !ok || len(got) != 0 {
+		t.Errorf("saved empty catalog = %v, %v; want a fresh empty result", got, ok)
+	}
+
+	c.Save([]WooProduct{{ID: 1, Name: "Oak Board"}})
+	c.lastUpdate = time.Now().Add(-30 * time.Minute)
+	if _, ok, _ := c.Fetch(time.Hour); !ok {
+		t.Error("30 minute old cache not fresh for a 1h max age")
+	}
+	if _, ok, _ := c.Fetch(10 * time.Minute); ok {
+		t.Error("30 minute old cache fresh for a 10m max age")
+	}
+
+	// Callers get their own copy.
+	got, _, _ := c.Fetch(time.Hour)
+	got[0].Name = "Changed"
+	if again, _, _ := c.Fetch(time.Hour); again[0].Name != "Oak Board" {
+		t.Errorf("Fetch result aliases the cache: %q", again[0].Name)
+	}
+}
+
+func TestMemoryCacheConcurrentUse(t *testing.T) {
+	c := &memoryCache{}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.Save([]WooProduct{{ID: int64(i)}})
+		}()
+		go func() {
+			defer wg.Done()
+			if got, ok, _ := c.Fetch(time.Hour); ok && len(got) != 1 {
+				t.Errorf("Fetch saw %d products mid-Save", len(got))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGetProductsMemoryBackendSkipsDisk(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"))
+	conf, requests := newTestStore(t, store.handle)
+	conf.CacheBackend = CacheBackendMemory
+	conf.CacheFilename = "memory-" + t.Name() + ".json"
+
+	for i := 0; i < 2; i++ {
+		cache, err := NewCache(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := GetProducts(conf, cache, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("store fetched %d times, want 1", n)
+	}
+	entries, _ := os.ReadDir(conf.CacheDir)
+	for _, e := range entries {
+		if !e.IsDir() {
+			t.Errorf("memory backend wrote %s", e.Name())
+		}
+	}
+}
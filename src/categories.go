@@ -0,0 +1,296 @@
+package wooh
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// AssignCategory adds categoryID to each product's existing categories.
+// Products that already carry the category are left untouched.
+func AssignCategory(conf *Config, productIDs []int, categoryID int) error {
+	products, err := GetProductsByID(conf, productIDs)
+	if err != nil {
+		return err
+	}
+
+	found := make(map[int]bool, len(products))
+	var updates []map[string]interface{}
+	for _, product := range products {
+		found[int(product.ID)] = true
+
+		categories := make([]map[string]interface{}, 0, len(product.Categories)+1)
+		hasCategory := false
+		for _, c := range product.Categories {
+			if int(c.ID) == categoryID {
+				hasCategory = true
+			}
+			categories = append(categories, map[string]interface{}{"id": c.ID})
+		}
+		if hasCategory {
+			slog.Info("Product already in category, skipping", "product_id", product.ID, "category", categoryID)
+			continue
+		}
+		categories = append(categories, map[string]interface{}{"id": categoryID})
+
+		updates = append(updates, map[string]interface{}{
+			"id":         product.ID,
+			"categories": categories,
+		})
+	}
+
+	for _, id := range productIDs {
+		if !found[id] {
+			return fmt.Errorf("product ID %d not found", id)
+		}
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+	slog.Info("Assigning category", "category", categoryID, "products", len(updates))
+	return BatchUpdateProducts(conf, updates)
+}
+
+// GetCategories returns every product category, cached for
+// category_cache_age.
+func GetCategories(conf *Config) ([]WooCategory, error) {
+	return cachedList(conf, categoriesCacheFilename, conf.CategoryCacheAge, func() ([]WooCategory, error) {
+		return fetchCategories(conf)
+	})
+}
+
+func fetchCategories(conf *Config) ([]WooCategory, error) {
+	return paginate[WooCategory](context.Background(), newClient(conf), conf, "products/categories", nil)
+}
+
+// WooAttribute is a global product attribute, such as "Colour".
+type WooAttribute struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Type        string `json:"type"`
+	OrderBy     string `json:"order_by"`
+	HasArchives bool   `json:"has_archives"`
+}
+
+// GetAttributes returns every global product attribute, cached for
+// attribute_cache_age.
+func GetAttributes(conf *Config) ([]WooAttribute, error) {
+	return cachedList(conf, attributesCacheFilename, conf.AttributeCacheAge, func() ([]WooAttribute, error) {
+		resp, err := newClient(conf).R().
+			SetHeader("Accept", "application/json").
+			Get(wooEndpoint(conf, "products/attributes"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch attributes: %w", err)
+		}
+		if resp.IsError() {
+			return nil, fmt.Errorf("error fetching attributes: %w", apiError(resp))
+		}
+		var attributes []WooAttribute
+		if err := decodeJSON(resp, &attributes); err != nil {
+			return nil, fmt.Errorf("failed to parse attributes: %w", err)
+		}
+		return attributes, nil
+	})
+}
+
+type CategoryMode string
+
+const (
+	CategoryModeReplace CategoryMode = "replace" // categories become exactly those in the file
+	CategoryModeAppend  CategoryMode = "append"  // categories in the file are added to existing ones
+)
+
+// ReassignCategoriesFromCSV sets product categories from a CSV of
+// "sku,category name[,category name...]" rows. Category names are matched
+// case-insensitively. Nothing is written if any SKU or category name cannot
+// be resolved.
+func ReassignCategoriesFromCSV(conf *Config, csvPath string, mode CategoryMode) error {
+	mapping, err := readCategoryCSV(csvPath)
+	if err != nil {
+		return err
+	}
+
+	categories, err := GetCategories(conf)
+	if err != nil {
+		return err
+	}
+	categoryIDs := make(map[string]int64, len(categories))
+	for _, c := range categories {
+		categoryIDs[strings.ToLower(c.Name)] = c.ID
+	}
+
+	cache, err := NewCache(conf)
+	if err != nil {
+		return err
+	}
+	products, err := GetProducts(conf, cache, 0)
+	if err != nil {
+		return err
+	}
+	bySku := make(map[string]WooProduct, len(products))
+	for _, p := range products {
+		if p.Sku != "" {
+			bySku[p.Sku] = p
+		}
+	}
+
+	var unresolved []string
+	var updates []map[string]interface{}
+	for _, row := range mapping {
+		product, ok := bySku[row.sku]
+		if !ok {
+			unresolved = append(unresolved, fmt.Sprintf("sku %q", row.sku))
+			continue
+		}
+
+		seen := make(map[int64]bool)
+		var assigned []map[string]interface{}
+		add := func(id int64) {
+			if !seen[id] {
+				seen[id] = true
+				assigned = append(assigned, map[string]interface{}{"id": id})
+			}
+		}
+		if mode == CategoryModeAppend {
+			for _, c := range product.Categories {
+				add(c.ID)
+			}
+		}
+		for _, name := range row.categories {
+			id, ok := categoryIDs[strings.ToLower(name)]
+			if !ok {
+				unresolved = append(unresolved, fmt.Sprintf("category %q", name))
+				continue
+			}
+			add(id)
+		}
+
+		updates = append(updates, map[string]interface{}{
+			"id":         product.ID,
+			"categories": assigned,
+		})
+	}
+
+	if len(unresolved) > 0 {
+		return fmt.Errorf("could not resolve %s", strings.Join(unresolved, ", "))
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if !confirmDestructive(len(updates), fmt.Sprintf("reassign categories (%s)", mode)) {
+		return ErrNotConfirmed
+	}
+	slog.Info("Reassigning categories", "products", len(updates), "mode", mode)
+	return BatchUpdateProducts(conf, updates)
+}
+
+type categoryRow struct {
+	sku        string
+	categories []string
+}
+
+func readCategoryCSV(path string) ([]categoryRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open category mapping: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var rows []categoryRow
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read category mapping: %w", err)
+		}
+		sku := strings.TrimSpace(record[0])
+		if line == 1 && strings.EqualFold(sku, "sku") {
+			continue
+		}
+		if sku == "" || len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected sku followed by at least one category", line)
+		}
+		row := categoryRow{sku: sku}
+		for _, name := range record[1:] {
+			if name = strings.TrimSpace(name); name != "" {
+				row.categories = append(row.categories, name)
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// maxSuggestedCategories caps how many categories SuggestCategories returns.
+const maxSuggestedCategories = 3
+
+func CategorySuggestionSystemPrompt(available []WooCategory) string {
+	names := make([]string, 0, len(available))
+	for _, c := range available {
+		names = append(names, "- "+c.Name)
+	}
+	return fmt.Sprintf(`
+You are an experienced e-commerce merchandiser.
+Choose the categories from the list below that best fit the product:
+%s
+- Pick at most %d categories, most relevant first.
+- Only use names exactly as they appear in the list.
+- Return one category name per line and nothing else. Return nothing if none fit.
+`, strings.Join(names, "\n"), maxSuggestedCategories)
+}
+
+// SuggestCategories asks OpenAI which of the available categories fit the
+// product described by input and returns their IDs. Names the model makes up
+// are ignored.
+func SuggestCategories(conf *Config, input SEOInput, available []WooCategory) ([]int, error) {
+	if len(available) == 0 {
+		return nil, nil
+	}
+	var reply string
+	if conf.OpenAIStub {
+		reply = stubCategories(input, available)
+	} else {
+		var err error
+		reply, err = generateText(context.Background(), conf, CategorySuggestionSystemPrompt(available), seoInputPrompt(input))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return matchCategoryNames(reply, available), nil
+}
+
+// matchCategoryNames maps the category names in reply, one per line, to the
+// IDs of available categories, case-insensitively and without duplicates.
+func matchCategoryNames(reply string, available []WooCategory) []int {
+	byName := make(map[string]int, len(available))
+	for _, c := range available {
+		byName[strings.ToLower(c.Name)] = int(c.ID)
+	}
+
+	var ids []int
+	seen := make(map[int]bool)
+	for _, line := range strings.Split(reply, "\n") {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*")))
+		id, ok := byName[name]
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+		if len(ids) == maxSuggestedCategories {
+			break
+		}
+	}
+	return ids
+}
@@ -0,0 +1,289 @@
+package wooh
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// categoryStore serves products 1..n from the products endpoint, filtered by
+// include, and records each products/batch request.
+type categoryStore struct {
+	n          int
+	categories func(id int) []int
+
+	mu       sync.Mutex
+	includes []int // IDs per include request
+	batches  [][]map[string]interface{}
+}
+
+func (s *categoryStore) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/products/batch"):
+		var body struct {
+			Update []map[string]interface{} `json:"update"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.batches = append(s.batches, body.Update)
+		result := map[string]interface{}{"update": body.Update}
+		json.NewEncoder(w).Encode(result)
+	case strings.HasSuffix(r.URL.Path, "/products"):
+		var products []map[string]interface{}
+		ids := strings.Split(r.URL.Query().Get("include"), ",")
+		s.includes = append(s.includes, len(ids))
+		for _, raw := range ids {
+			id, _ := strconv.Atoi(raw)
+			if id < 1 || id > s.n {
+				continue
+			}
+			var categories []map[string]interface{}
+			for _, c := range s.categories(id) {
+				categories = append(categories, map[string]interface{}{"id": c})
+			}
+			products = append(products, map[string]interface{}{"id": id, "categories": categories})
+		}
+		json.NewEncoder(w).Encode(products)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestAssignCategory(t *testing.T) {
+	// Every tenth product is already in category 5; all are in category 2.
+	newStore := func(n int) *categoryStore {
+		return &categoryStore{n: n, categories: func(id int) []int {
+			if id%10 == 0 {
+				return []int{2, 5}
+			}
+			return []int{2}
+		}}
+	}
+	ids := func(n int) []int {
+		var ids []int
+		for id := 1; id <= n; id++ {
+			ids = append(ids, id)
+		}
+		return ids
+	}
+
+	t.Run("adds to existing categories in chunks", func(t *testing.T) {
+		store := newStore(250)
+		conf, _ := newTestStore(t, store.handle)
+		if err := AssignCategory(conf, ids(250), 5); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(store.includes, []int{100, 100, 50}) {
+			t.Errorf("fetched products in chunks of %v", store.includes)
+		}
+		var sizes []int
+		updated := 0
+		for _, batch := range store.batches {
+			sizes = append(sizes, len(batch))
+			for _, update := range batch {
+				updated++
+				id := int(update["id"].(float64))
+				if id%10 == 0 {
+					t.Errorf("product %d already in the category was updated", id)
+				}
+				categories := update["categories"].([]interface{})
+				if len(categories) != 2 || categories[0].(map[string]interface{})["id"] != 2.0 || categories[1].(map[string]interface{})["id"] != 5.0 {
+					t.Errorf("product %d categories = %v, want [2 5]", id, categories)
+				}
+			}
+		}
+		if !slices.Equal(sizes, []int{100, 100, 25}) || updated != 225 {
+			t.Errorf("batch sizes %v, want [100 100 25]", sizes)
+		}
+	})
+
+	t.Run("already present is a no-op", func(t *testing.T) {
+		store := newStore(30)
+		conf, _ := newTestStore(t, store.handle)
+		if err := AssignCategory(conf, []int{10, 20, 30}, 5); err != nil {
+			t.Fatal(err)
+		}
+		if len(store.batches) != 0 {
+			t.Errorf("sent %d batch updates, want none", len(store.batches))
+		}
+	})
+
+	t.Run("missing product", func(t *testing.T) {
+		store := newStore(3)
+		conf, _ := newTestStore(t, store.handle)
+		err := AssignCategory(conf, []int{1, 4}, 5)
+		if err == nil || !strings.Contains(err.Error(), "product ID 4 not found") {
+			t.Errorf("AssignCategory = %v, want product 4 not found", err)
+		}
+		if len(store.batches) != 0 {
+			t.Errorf("sent %d batch updates after a lookup failure", len(store.batches))
+		}
+	})
+}
+
+func TestReassignCategoriesFromCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		mode    CategoryMode
+		want    map[int64][]int64 // product -> category IDs after the run
+		wantErr string
+	}{
+		{
+			name: "replace",
+			csv:  "sku,categories\nOAK-1,Flooring,doors\nOAK-2,Doors\n",
+			mode: CategoryModeReplace,
+			want: map[int64][]int64{1: {10, 30}, 2: {30}},
+		},
+		{
+			name: "append",
+			csv:  "OAK-1,Doors,Flooring\nOAK-2,Flooring\n",
+			mode: CategoryModeAppend,
+			want: map[int64][]int64{1: {10, 20, 30}, 2: {20, 10}},
+		},
+		{
+			name:    "unresolved sku",
+			csv:     "OAK-1,Doors\nOAK-9,Doors\n",
+			mode:    CategoryModeReplace,
+			want:    map[int64][]int64{1: {10, 20}, 2: {20}},
+			wantErr: `sku "OAK-9"`,
+		},
+		{
+			name:    "unresolved category",
+			csv:     "OAK-1,Windows\n",
+			mode:    CategoryModeAppend,
+			want:    map[int64][]int64{1: {10, 20}, 2: {20}},
+			wantErr: `category "Windows"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restore := assumeYes
+			t.Cleanup(func() { assumeYes = restore })
+			assumeYes = true
+
+			oak1, oak2 := testProduct(1, "Oak Board"), testProduct(2, "Oak Door")
+			oak1["sku"], oak2["sku"] = "OAK-1", "OAK-2"
+			oak1["categories"] = []interface{}{map[string]interface{}{"id": 10}, map[string]interface{}{"id": 20}}
+			oak2["categories"] = []interface{}{map[string]interface{}{"id": 20}}
+			store := newFakeStore(oak1, oak2)
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/products/categories") {
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`[{"id":10,"name":"Flooring"},{"id":20,"name":"Hardware"},{"id":30,"name":"Doors"}]`))
+					return
+				}
+				store.handle(w, r)
+			})
+			path := filepath.Join(t.TempDir(), "categories.csv")
+			if err := os.WriteFile(path, []byte(tt.csv), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ReassignCategoriesFromCSV(conf, path, tt.mode)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %s", err, tt.wantErr)
+				}
+				if len(store.written()) > 0 {
+					t.Errorf("wrote products %v despite unresolved rows", store.written())
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			for id, want := range tt.want {
+				var got []int64
+				for _, c := range store.product(id).Categories {
+					got = append(got, c.ID)
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("product %d categories = %v, want %v", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestCategories(t *testing.T) {
+	available := []WooCategory{{ID: 1, Name: "Oak"}, {ID: 2, Name: "Walnut"}, {ID: 3, Name: "Chopping Boards"}, {ID: 4, Name: "Worktops"}}
+	gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+		return textChoice("Chopping Boards\n- walnut\nCutting Mats\nOak\nchopping boards\nWorktops")
+	})
+	conf := &Config{}
+	applyDefaults(conf)
+	gen.use(conf)
+
+	ids, err := SuggestCategories(conf, SEOInput{Name: "Walnut Chopping Board"}, available)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Made-up and repeated names are dropped, and at most three are kept.
+	if !slices.Equal(ids, []int{3, 2, 1}) {
+		t.Errorf("suggested %v, want [3 2 1]", ids)
+	}
+	sent := gen.sent()
+	if len(sent) != 1 || !strings.Contains(sent[0].Messages[0].Content, "- Chopping Boards") || !strings.Contains(sent[0].Messages[1].Content, "Walnut Chopping Board") {
+		t.Errorf("prompt does not list the categories and product: %+v", sent)
+	}
+
+	if ids, err := SuggestCategories(conf, SEOInput{Name: "Oak Board"}, nil); ids != nil || err != nil || len(gen.sent()) != 1 {
+		t.Errorf("no categories to pick from = %v, %v after %d requests", ids, err, len(gen.sent()))
+	}
+
+	conf.OpenAIStub = true
+	if ids, _ := SuggestCategories(conf, SEOInput{Name: "Oak Worktop"}, available); !slices.Equal(ids, []int{1}) {
+		t.Errorf("stub suggested %v, want [1]", ids)
+	}
+}
+
+func TestUploadSuggestsCategories(t *testing.T) {
+	for _, suggest := range []bool{false, true} {
+		t.Run("suggest "+strconv.FormatBool(suggest), func(t *testing.T) {
+			store := &skuStore{}
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/products/categories") {
+					w.Header().Set("Content-Type", "application/json")
+					w.Write([]byte(`[{"id": 9, "name": "Boards"}, {"id": 1, "name": "Oak"}]`))
+					return
+				}
+				store.handle(w, r)
+			})
+			conf.ProductMeta.RegularPrice = "10.00"
+			conf.ProductMeta.Categories = []interface{}{9}
+			gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+				return textChoice("Boards\nOak")
+			})
+			gen.use(conf)
+
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, "oak-board.jpg"), []byte("x"), 0644)
+			if _, err := UploadImageToWordPress(conf, dir, UploadOptions{SuggestCategories: suggest, Quiet: true}); err != nil {
+				t.Fatal(err)
+			}
+			if len(store.bodies) != 1 {
+				t.Fatalf("created %d products, want 1", len(store.bodies))
+			}
+			want := []interface{}{map[string]interface{}{"id": float64(9)}}
+			if suggest {
+				want = append(want, map[string]interface{}{"id": float64(1)})
+			}
+			if got := store.bodies[0]["categories"]; !reflect.DeepEqual(got, want) {
+				t.Errorf("categories = %v, want %v", got, want)
+			}
+			if n := len(gen.sent()); (n > 0) != suggest {
+				t.Errorf("sent %d suggestion requests", n)
+			}
+		})
+	}
+}
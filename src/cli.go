@@ -1,18 +1,34 @@
 package wooh
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
 )
 
 func Run() {
-	if err := newRootCmd().Execute(); err != nil {
-		log.Fatal(err)
+	sd := listenForShutdown()
+	err := newRootCmd().ExecuteContext(sd.ctx)
+	sd.stop()
+	if code := sd.exitCode(); code != 0 {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			slog.Error(err.Error())
+		}
+		os.Exit(code)
+	}
+	if err != nil {
+		fatal(err.Error())
 	}
 }
 
@@ -22,55 +38,63 @@ func newRootCmd() *cobra.Command {
 		configPath      string
 		imagesPath      string
 		autofill        bool
-		resetAutoFill   bool
-		prompt          bool
 		listProductMeta bool
+		uploadDryRun    bool
+		envFile         string
+		trace           bool
+		quiet           bool
+		yes             bool
+		seo             seoFlags
 	)
 
-	_, currentFilePath, _, ok := runtime.Caller(0)
-	if !ok {
-		panic("No caller information")
-	}
-	dirPath := filepath.Dir(currentFilePath)
-	versionFilePath := filepath.Join(dirPath, "/../version")
-
-	ver, err := os.ReadFile(versionFilePath)
-	ErrChk(err)
+	ver := version()
 
 	var rootCmd = &cobra.Command{
 		Use:   "wooh",
-		Short: "Tool that helps turn images into woo commerce products" + string(ver),
+		Short: "Tool that helps turn images into woo commerce products " + ver,
 		Run: func(cmd *cobra.Command, args []string) {
 			if showVersion {
-				fmt.Println(string(ver))
+				fmt.Println(ver)
 				return
 			}
 
+			var err error
 			imagesPath, err = filepath.Abs(imagesPath)
 			if err != nil {
-				log.Fatalf("Failed to get absolute path: %v", err)
-			}
-
-			if configPath == "wooh.yaml" {
-				configPath, err = filepath.Abs(configPath)
-				if err != nil {
-					log.Fatalf("Failed to get absolute path: %v", err)
-				}
+				fatal("Failed to get absolute path", "err", err)
 			}
 
-			conf, err := GetConfig(configPath)
+			conf, err := loadConfig(configPath)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error loading config file '%s': %v\n", configPath, err)
 				cmd.Help()
 				return
 			}
+			if cmd.Flags().Changed("keep-debug") {
+				conf.KeepDebug = seo.keepDebug
+			}
 
 			if configPath != "" && PathExist(imagesPath) {
-				UploadImageToWordPress(conf, imagesPath)
+				_, err := UploadImageToWordPress(conf, imagesPath, UploadOptions{DryRun: uploadDryRun})
+				switch {
+				case errors.Is(err, ErrNoImages):
+					// Uploading from the working directory is implicit, so an
+					// empty one is only worth reporting when -i was given.
+					if cmd.Flags().Changed("images-path") {
+						slog.Warn(err.Error())
+					}
+				case err != nil:
+					slog.Error("Image upload failed", "err", err)
+				}
 			}
 
-			if autofill {
-				UpdateSEO(conf, resetAutoFill, prompt)
+			if autofill || seo.diff || seo.exportOnly != "" {
+				if err := seo.validate(); err != nil {
+					fatal(err.Error())
+				}
+				if err := runSEO(cmd, conf, configPath, &seo); err != nil {
+					slog.Error("SEO update failed", "err", err)
+				}
 			}
 
 			if listProductMeta {
@@ -79,47 +103,845 @@ func newRootCmd() *cobra.Command {
 
 		}}
 
+	rootCmd.Flags().BoolVar(&seo.allProfiles, "all-profiles", false, "Run autofill or diff against every store listed under profiles")
 	rootCmd.Flags().BoolVarP(&autofill, "autofill", "a", false, "Yoast SEO Meta Data Autofill")
-	rootCmd.Flags().StringVarP(&configPath, "config", "c", "wooh.yaml", "Custom config path")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "wooh.yaml", "Custom config path")
+	rootCmd.Flags().BoolVar(&seo.enrich, "enrich", false, "Expand descriptions shorter than min_description_words before generating meta")
+	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", ".env", "Load WOOH_* variables from this file; the process environment wins")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print warnings, errors and final summaries")
+	rootCmd.PersistentFlags().BoolVar(&trace, "trace", false, "Log DNS, connect, TLS and server timings of every store request")
+	rootCmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "Apply destructive bulk changes without asking for confirmation")
+	rootCmd.Flags().BoolVar(&seo.diff, "diff", false, "Show current vs generated SEO meta without writing")
+	rootCmd.Flags().StringVar(&seo.exportOnly, "export-only", "", "Write generated SEO meta to this file for review instead of updating products")
+	rootCmd.Flags().BoolVar(&seo.force, "force", false, "Reprocess products already recorded in the SEO tracker")
 	rootCmd.Flags().StringVarP(&imagesPath, "images-path", "i", ".", "Images Path")
+	rootCmd.Flags().IntVar(&seo.keepDebug, "keep-debug", 0, "Keep only the N most recent debug dumps in debug_dir")
 	rootCmd.Flags().BoolVarP(&listProductMeta, "listProductMeta", "l", false, "List Product Meta")
-	rootCmd.Flags().BoolVarP(&prompt, "prompt", "p", false, "Prompt for confirmation for each product")
-	rootCmd.Flags().BoolVarP(&resetAutoFill, "resetAutofill", "r", false, "Reset Yoast Cache and Products Data")
+	rootCmd.Flags().StringVar(&seo.orderBy, "order-by", "", "Process products in this order during autofill (\"sales\": best sellers first)")
+	rootCmd.Flags().BoolVarP(&seo.prompt, "prompt", "p", false, "Prompt for confirmation for each product")
+	rootCmd.Flags().BoolVar(&seo.regenerateDesc, "regenerate-descriptions", false, "Rewrite descriptions shorter than the configured minimum (destructive)")
+	rootCmd.Flags().BoolVarP(&seo.resetTracker, "resetAutofill", "r", false, "Reset Yoast Cache and Products Data")
+	rootCmd.Flags().BoolVar(&seo.restartRun, "restart", false, "Ignore the saved position of an interrupted autofill run")
+	rootCmd.Flags().BoolVar(&uploadDryRun, "upload-dry-run", false, "Preview products that would be created from images without uploading")
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Get Version")
 
+	// The flag-only interface is kept for one release; each flag points at
+	// the subcommand that replaces it.
+	for flag, replacement := range map[string]string{
+		"all-profiles":            "wooh seo --all-profiles",
+		"autofill":                "wooh seo",
+		"diff":                    "wooh seo --diff",
+		"enrich":                  "wooh seo --enrich",
+		"export-only":             "wooh seo --export-only",
+		"force":                   "wooh seo --force",
+		"images-path":             "wooh upload <dir>",
+		"keep-debug":              "wooh seo --keep-debug",
+		"listProductMeta":         "wooh list",
+		"order-by":                "wooh seo --order-by",
+		"prompt":                  "wooh seo --prompt",
+		"regenerate-descriptions": "wooh seo --regenerate-descriptions",
+		"resetAutofill":           "wooh seo --reset-tracker",
+		"restart":                 "wooh seo --restart",
+		"upload-dry-run":          "wooh upload --dry-run",
+	} {
+		rootCmd.Flags().MarkDeprecated(flag, "use '"+replacement+"' instead")
+	}
+
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		httpTrace = trace
+		quietLogs = quiet
+		assumeYes = yes
+		setConsoleLevel(quiet, trace)
+		setupConsoleLog()
+		return LoadDotEnv(envFile)
+	}
+
 	rootCmd.AddCommand(newCompletionCmd())
+	rootCmd.AddCommand(newCouponCmd(&configPath))
+	rootCmd.AddCommand(newImagesCmd())
+	rootCmd.AddCommand(newInitCmd())
+	rootCmd.AddCommand(newListCmd(&configPath))
+	rootCmd.AddCommand(newApplyCmd(&configPath))
+	rootCmd.AddCommand(newAuditCmd(&configPath))
+	rootCmd.AddCommand(newBackupCmd(&configPath))
+	rootCmd.AddCommand(newCacheCmd(&configPath))
+	rootCmd.AddCommand(newCategoriesCmd(&configPath))
+	rootCmd.AddCommand(newRestoreCmd(&configPath))
+	rootCmd.AddCommand(newResyncCmd(&configPath))
+	rootCmd.AddCommand(newSEOCmd(&configPath))
+	rootCmd.AddCommand(newSearchCmd(&configPath))
+	rootCmd.AddCommand(newSettingsCmd(&configPath))
+	rootCmd.AddCommand(newSkipCmd(&configPath))
+	rootCmd.AddCommand(newSKUsCmd(&configPath))
+	rootCmd.AddCommand(newTrackerCmd(&configPath))
+	rootCmd.AddCommand(newUploadCmd(&configPath))
+	rootCmd.AddCommand(newVisibilityCmd(&configPath))
 
 	return rootCmd
 }
 
-func newCompletionCmd() *cobra.Command {
+// Version is the wooh release. main sets it from the version file; a build
+// can also set it with -ldflags "-X github.com/asolopovas/woo-helpers/src.Version=v1.2.3".
+var Version string
+
+// version returns Version, else the module version the binary was built at,
+// else "dev". It is worked out once and never fails.
+var version = sync.OnceValue(func() string {
+	if v := strings.TrimSpace(Version); v != "" {
+		return v
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+})
+
+func loadConfig(configPath string) (*Config, error) {
+	if configPath == "wooh.yaml" {
+		absPath, err := filepath.Abs(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		configPath = absPath
+	}
+	conf, err := GetConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := SetupLogFile(conf); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// seoFlags are the flags of 'wooh seo'. The root command still accepts
+// them as deprecated aliases.
+type seoFlags struct {
+	resetTracker   bool
+	restartRun     bool
+	stubOpenAI     bool
+	ignoreIDs      []int
+	prompt         bool
+	regenerateDesc bool
+	diff           bool
+	exportOnly     string
+	orderBy        string
+	force          bool
+	enrich         bool
+	allProfiles    bool
+	keepDebug      int
+	onlyImprove    bool
+	minPrice       float64
+	maxPrice       float64
+}
+
+func (f *seoFlags) validate() error {
+	if f.orderBy != "" && f.orderBy != seoOrderBySales {
+		return fmt.Errorf("unsupported --order-by %q (allowed: %s)", f.orderBy, seoOrderBySales)
+	}
+	if f.minPrice < 0 || f.maxPrice < 0 || (f.maxPrice > 0 && f.minPrice > f.maxPrice) {
+		return fmt.Errorf("invalid price range: --min-price %g, --max-price %g", f.minPrice, f.maxPrice)
+	}
+	if f.allProfiles && (f.prompt || f.exportOnly != "") {
+		return fmt.Errorf("--all-profiles cannot be combined with --prompt or --export-only")
+	}
+	return nil
+}
+
+func (f *seoFlags) options() SEOOptions {
+	return SEOOptions{
+		RestartTracking:        f.resetTracker,
+		RestartRun:             f.restartRun,
+		Prompt:                 f.prompt,
+		RegenerateDescriptions: f.regenerateDesc,
+		Diff:                   f.diff,
+		OrderBy:                f.orderBy,
+		ExportOnly:             f.exportOnly,
+		Force:                  f.force,
+		Enrich:                 f.enrich,
+		IgnoreIDs:              f.ignoreIDs,
+		Quiet:                  quietLogs,
+		OnlyImprove:            f.onlyImprove,
+		MinPrice:               f.minPrice,
+		MaxPrice:               f.maxPrice,
+	}
+}
+
+// runSEO runs UpdateSEO against conf, or against every profile when
+// --all-profiles is set.
+func runSEO(cmd *cobra.Command, conf *Config, configPath string, f *seoFlags) error {
+	keepDebug := cmd.Flags().Changed("keep-debug")
+	if !f.allProfiles {
+		if keepDebug {
+			conf.KeepDebug = f.keepDebug
+		}
+		if f.stubOpenAI {
+			conf.OpenAIStub = true
+		}
+		opts := f.options()
+		opts.Context = cmd.Context()
+		_, err := UpdateSEO(conf, opts)
+		return ignoreShutdown(cmd, err)
+	}
+
+	configs, err := LoadProfiles(conf, configPath)
+	if err != nil {
+		return err
+	}
+	return ignoreShutdown(cmd, RunAcross(configs, func(c *Config) error {
+		if keepDebug {
+			c.KeepDebug = f.keepDebug
+		}
+		if f.stubOpenAI {
+			c.OpenAIStub = true
+		}
+		opts := f.options()
+		opts.Context = cmd.Context()
+		_, err := UpdateSEO(c, opts)
+		return err
+	}))
+}
+
+// ignoreShutdown drops the error of a run stopped by SIGINT or SIGTERM: the
+// run already printed its summary and Run exits with the signal's code.
+func ignoreShutdown(cmd *cobra.Command, err error) error {
+	if errors.Is(err, context.Canceled) && cmd.Context().Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func newSEOCmd(configPath *string) *cobra.Command {
+	var f seoFlags
+	cmd := &cobra.Command{
+		Use:   "seo",
+		Short: "Generate and write Yoast SEO meta for untracked products",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := f.validate(); err != nil {
+				return err
+			}
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			return runSEO(cmd, conf, *configPath, &f)
+		},
+	}
+	cmd.Flags().BoolVar(&f.allProfiles, "all-profiles", false, "Run against every store listed under profiles")
+	cmd.Flags().BoolVar(&f.diff, "diff", false, "Show current vs generated SEO meta without writing")
+	cmd.Flags().BoolVar(&f.enrich, "enrich", false, "Expand descriptions shorter than min_description_words before generating meta")
+	cmd.Flags().StringVar(&f.exportOnly, "export-only", "", "Write generated SEO meta to this file for review instead of updating products")
+	cmd.Flags().BoolVar(&f.force, "force", false, "Reprocess products already recorded in the SEO tracker")
+	cmd.Flags().IntSliceVar(&f.ignoreIDs, "ignore", nil, "Product IDs to leave untouched, in addition to ignore_ids (e.g. 1,2,3)")
+	cmd.Flags().IntVar(&f.keepDebug, "keep-debug", 0, "Keep only the N most recent debug dumps in debug_dir")
+	cmd.Flags().Float64Var(&f.maxPrice, "max-price", 0, "Only process products with a regular price of at most this")
+	cmd.Flags().Float64Var(&f.minPrice, "min-price", 0, "Only process products with a regular price of at least this")
+	cmd.Flags().BoolVar(&f.onlyImprove, "only-improve", false, "Only write meta that scores higher than the current meta by more than improve_margin")
+	cmd.Flags().StringVar(&f.orderBy, "order-by", "", "Process products in this order (\"sales\": best sellers first)")
+	cmd.Flags().BoolVarP(&f.prompt, "prompt", "p", false, "Prompt for confirmation for each product")
+	cmd.Flags().BoolVar(&f.regenerateDesc, "regenerate-descriptions", false, "Rewrite descriptions shorter than the configured minimum (destructive)")
+	cmd.Flags().BoolVar(&f.resetTracker, "reset-tracker", false, "Ignore the SEO tracker and start fresh")
+	cmd.Flags().BoolVar(&f.restartRun, "restart", false, "Ignore the saved position of an interrupted run")
+	cmd.Flags().BoolVar(&f.stubOpenAI, "stub-openai", false, "Generate deterministic meta from product names without calling OpenAI")
+	return cmd
+}
+
+func newUploadCmd(configPath *string) *cobra.Command {
+	var dryRun, suggestCategories bool
+	cmd := &cobra.Command{
+		Use:   "upload [dir]",
+		Short: "Create products from the images in dir",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			dir, err := filepath.Abs(dir)
+			if err != nil {
+				return fmt.Errorf("failed to get absolute path: %w", err)
+			}
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			_, err = UploadImageToWordPress(conf, dir, UploadOptions{DryRun: dryRun, SuggestCategories: suggestCategories, Quiet: quietLogs})
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview products that would be created without uploading")
+	cmd.Flags().BoolVar(&suggestCategories, "suggest-categories", false, "Add the store categories OpenAI picks for each product")
+	return cmd
+}
+
+func newListCmd(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the SEO meta and score of every product",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			ListProductMeta(conf)
+			return nil
+		},
+	}
+}
+
+func newAuditCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Report problems in the product catalog",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "duplicates",
+		Short: "List products sharing a name or SKU",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			report, err := FindDuplicates(conf)
+			if err != nil {
+				return err
+			}
+			printDuplicateGroups("name", report.ByName)
+			printDuplicateGroups("SKU", report.BySku)
+			return nil
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "meta-keys",
+		Short: "Count the meta_data keys used across the catalog",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			counts, err := MetaKeyHistogram(conf)
+			if err != nil {
+				return err
+			}
+			keys := make([]string, 0, len(counts))
+			for key := range counts {
+				keys = append(keys, key)
+			}
+			sort.Slice(keys, func(i, j int) bool {
+				if counts[keys[i]] != counts[keys[j]] {
+					return counts[keys[i]] > counts[keys[j]]
+				}
+				return keys[i] < keys[j]
+			})
+			for _, key := range keys {
+				fmt.Printf("%d\t%s\n", counts[key], key)
+			}
+			return nil
+		},
+	})
+	return cmd
+}
+
+func printDuplicateGroups(label string, groups []DuplicateGroup) {
+	if len(groups) == 0 {
+		fmt.Printf("No duplicate %ss\n", label)
+		return
+	}
+	fmt.Printf("Duplicate %ss: %d\n", label, len(groups))
+	for _, g := range groups {
+		fmt.Printf("%s %q:\n", label, g.Key)
+		for _, p := range g.Products {
+			fmt.Printf("  %d\t%s\t%s\n", p.ID, p.Name, p.Sku)
+		}
+	}
+}
+
+func newApplyCmd(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "apply <file>",
+		Short: "Write the approved entries of an --export-only file to the store",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			return ApplySEOFromFile(conf, args[0])
+		},
+	}
+}
+
+func newBackupCmd(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup <file>",
+		Short: "Back up the Yoast SEO meta of all products to a JSON file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			return BackupSEO(conf, args[0])
+		},
+	}
+}
+
+func newCategoriesCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "categories",
+		Short: "Manage product categories",
+	}
+
+	var replace, appendMode bool
+	reassign := &cobra.Command{
+		Use:   "reassign <csv>",
+		Short: "Set product categories from a sku,category[,category...] CSV",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if replace == appendMode {
+				return fmt.Errorf("exactly one of --replace or --append is required")
+			}
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			mode := CategoryModeAppend
+			if replace {
+				mode = CategoryModeReplace
+			}
+			return ReassignCategoriesFromCSV(conf, args[0], mode)
+		},
+	}
+	reassign.Flags().BoolVar(&appendMode, "append", false, "Add the listed categories to existing ones")
+	reassign.Flags().BoolVar(&replace, "replace", false, "Replace existing categories with the listed ones")
+	cmd.AddCommand(reassign)
+
+	return cmd
+}
+
+func newCouponCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "coupon",
+		Short: "Create and list coupons",
+	}
+
+	var coupon Coupon
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a coupon",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			id, err := CreateCoupon(conf, coupon)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Created coupon %s (ID %d)\n", coupon.Code, id)
+			return nil
+		},
+	}
+	create.Flags().StringVar(&coupon.Amount, "amount", "", "Discount amount")
+	create.Flags().StringVar(&coupon.Code, "code", "", "Coupon code")
+	create.Flags().StringVar(&coupon.DateExpires, "expires", "", "Expiry date (YYYY-MM-DD)")
+	create.Flags().StringVar(&coupon.DiscountType, "type", "percent", "Discount type: percent, fixed_cart or fixed_product")
+	cmd.AddCommand(create)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List coupons",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			coupons, err := ListCoupons(conf)
+			if err != nil {
+				return err
+			}
+			for _, c := range coupons {
+				expires := c.DateExpires
+				if expires == "" {
+					expires = "never"
+				}
+				fmt.Printf("%d\t%s\t%s\t%s\t%s\n", c.ID, c.Code, c.DiscountType, c.Amount, expires)
+			}
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+func newRestoreCmd(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <file>",
+		Short: "Restore Yoast SEO meta from a backup file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			return RestoreSEO(conf, args[0])
+		},
+	}
+}
+
+func newResyncCmd(configPath *string) *cobra.Command {
+	var id int
+	cmd := &cobra.Command{
+		Use:   "resync",
+		Short: "Refetch, regenerate, write and verify the SEO meta of one product",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if id <= 0 {
+				return fmt.Errorf("--id is required")
+			}
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			update, err := ResyncProduct(conf, id)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Resynced product %d: %s\n", update.Product.ID, update.Product.Name)
+			fmt.Println("Meta Title: " + update.MetaData.YoastTitle())
+			fmt.Println("Meta Description: " + update.MetaData.YoastDesc())
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&id, "id", 0, "Product ID")
+	return cmd
+}
+
+func newSearchCmd(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "search <term>",
+		Short: "Search products by keyword",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			products, err := SearchProducts(conf, strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			if len(products) == 0 {
+				fmt.Println("No products found")
+				return nil
+			}
+			for _, p := range products {
+				fmt.Printf("%d\t%s\n", p.ID, p.Name)
+			}
+			return nil
+		},
+	}
+}
+
+func newSettingsCmd(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "settings",
+		Short: "Show the store's currency and base location",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			settings, err := GetStoreSettings(conf)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Currency: %s (%s, e.g. %s)\n", settings.Currency, settings.CurrencyPosition, settings.FormatPrice(1234.5))
+			location := settings.BaseCountry
+			if settings.BaseState != "" {
+				location += ", " + settings.BaseState
+			}
+			if settings.City != "" {
+				location = settings.City + ", " + location
+			}
+			fmt.Printf("Base location: %s\n", location)
+			return nil
+		},
+	}
+}
+
+func newCacheCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect the product cache",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "drift",
+		Short: "Count products added, removed or modified in the store since they were cached",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			report, err := CacheDrift(conf)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Added: %d %v\n", len(report.Added), report.Added)
+			fmt.Printf("Removed: %d %v\n", len(report.Removed), report.Removed)
+			fmt.Printf("Modified: %d %v\n", len(report.Modified), report.Modified)
+			return nil
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "prune",
+		Short: "Remove cached products that no longer exist in the store",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			removed, err := PruneCache(conf)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed %d stale cache entries\n", removed)
+			return nil
+		},
+	})
+	return cmd
+}
+
+func newTrackerCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tracker",
+		Short: "Manage the SEO update tracker",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "prune",
+		Short: "Remove tracked IDs of products no longer in the store",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			removed, err := PruneTracker(conf)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed %d stale tracker entries\n", removed)
+			return nil
+		},
+	})
+	return cmd
+}
+
+func newVisibilityCmd(configPath *string) *cobra.Command {
 	return &cobra.Command{
-		Use:   "completion",
-		Short: "Generate fish completion script",
-		Run:   generateFishCompletion,
+		Use:   "visibility <id> <visible|catalog|search|hidden>",
+		Short: "Set where a product appears in the shop and search results",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid product ID %q", args[0])
+			}
+			if err := ValidateVisibility(args[1]); err != nil {
+				return err
+			}
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			if err := SetVisibility(conf, id, args[1]); err != nil {
+				return err
+			}
+			fmt.Printf("Set visibility of product %d to %s\n", id, args[1])
+			return nil
+		},
+	}
+}
+
+func newSkipCmd(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "skip <id>...",
+		Short: "Exclude products from SEO updates for good",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			for _, arg := range args {
+				id, err := strconv.Atoi(arg)
+				if err != nil {
+					return fmt.Errorf("invalid product ID %q", arg)
+				}
+				if err := AddToSkipList(conf, id); err != nil {
+					return err
+				}
+				fmt.Printf("Added product %d to the skip list\n", id)
+			}
+			return nil
+		},
+	}
+}
+
+func newSKUsCmd(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "skus",
+		Short: "Manage product SKUs",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "backfill <pattern>",
+		Short: "Give products without a SKU one built from a pattern with {id} or {slug}, e.g. OAK-{id}",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ValidateSKUPattern(args[0]); err != nil {
+				return err
+			}
+			conf, err := loadConfig(*configPath)
+			if err != nil {
+				return err
+			}
+			updated, err := BackfillSKUs(conf, args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Backfilled %d SKUs\n", updated)
+			return nil
+		},
+	})
+	return cmd
+}
+
+func newImagesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Inspect product images",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate [dir]",
+		Short: "Check images decode and are within size limits before upload",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			issues, err := ValidateImages(dir)
+			if err != nil {
+				return err
+			}
+			bad := 0
+			for _, issue := range issues {
+				if issue.OK() {
+					fmt.Printf("ok\t%s\t%dx%d\t%d bytes\n", issue.Path, issue.Width, issue.Height, issue.Size)
+					continue
+				}
+				bad++
+				fmt.Printf("FAIL\t%s\t%s\n", issue.Path, issue.Problem)
+			}
+			if bad > 0 {
+				return fmt.Errorf("%d of %d images failed validation", bad, len(issues))
+			}
+			fmt.Printf("All %d images valid\n", len(issues))
+			return nil
+		},
+	})
+	return cmd
+}
+
+func newInitCmd() *cobra.Command {
+	var (
+		configPath     string
+		nonInteractive bool
+		overwrite      bool
+		answers        InitAnswers
+	)
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Interactively create a config file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !nonInteractive {
+				var err error
+				answers, err = PromptInitAnswers(os.Stdin, cmd.OutOrStdout())
+				if err != nil {
+					return err
+				}
+			}
+			return WriteInitConfig(configPath, answers, overwrite)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "wooh.yaml", "Config path to create")
+	cmd.Flags().BoolVar(&nonInteractive, "non-interactive", false, "Take values from flags instead of prompting")
+	cmd.Flags().BoolVarP(&overwrite, "force", "f", false, "Overwrite an existing config file")
+	cmd.Flags().StringVar(&answers.Site, "site", "", "Site domain, optionally with a subpath")
+	cmd.Flags().StringVar(&answers.WooConsumerKey, "consumer-key", "", "WooCommerce consumer key")
+	cmd.Flags().StringVar(&answers.WooConsumerSecret, "consumer-secret", "", "WooCommerce consumer secret")
+	cmd.Flags().StringVar(&answers.WpUser, "wp-user", "", "WordPress user")
+	cmd.Flags().StringVar(&answers.WpKey, "wp-key", "", "WordPress application password")
+	cmd.Flags().StringVar(&answers.OpenAIKey, "openai-key", "", "OpenAI API key")
+
+	return cmd
+}
+
+func newCompletionCmd() *cobra.Command {
+	var stdout bool
+	cmd := &cobra.Command{
+		Use:       "completion [fish|bash|zsh]",
+		Short:     "Generate a shell completion script (fish by default)",
+		Long:      "Generate a shell completion script. The fish script is written to ~/.config/fish/completions unless --stdout is given; the others are always printed.",
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"fish", "bash", "zsh"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shell := "fish"
+			if len(args) == 1 {
+				shell = args[0]
+			}
+			if shell == "fish" && !stdout {
+				generateFishCompletion(cmd, args)
+				return nil
+			}
+			return writeCompletion(cmd.Root(), shell, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().BoolVar(&stdout, "stdout", false, "Print the fish script instead of writing it to ~/.config/fish/completions")
+	return cmd
+}
+
+func writeCompletion(root *cobra.Command, shell string, w io.Writer) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(w, true)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	default:
+		return root.GenFishCompletion(w, true)
 	}
 }
 
 func generateFishCompletion(cmd *cobra.Command, args []string) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		log.Fatalf("failed to get user home directory: %v", err)
+		fatal("Failed to get user home directory", "err", err)
 	}
 
 	fishCompletionDir := filepath.Join(homeDir, ".config", "fish", "completions")
 	if err := os.MkdirAll(fishCompletionDir, os.ModePerm); err != nil {
-		log.Fatalf("failed to create fish completions directory: %v", err)
+		fatal("Failed to create fish completions directory", "err", err)
 	}
 
 	fishCompletionFile := filepath.Join(fishCompletionDir, "gen-webmanifest.fish")
 	f, err := os.Create(fishCompletionFile)
 	if err != nil {
-		log.Fatalf("failed to create fish completion file: %v", err)
+		fatal("Failed to create fish completion file", "err", err)
 	}
 	defer f.Close()
 
 	if err := cmd.Root().GenFishCompletion(f, true); err != nil {
-		log.Fatalf("failed to generate fish completion script: %v", err)
+		fatal("Failed to generate fish completion script", "err", err)
 	}
 
 	fmt.Printf("Fish completion script generated at: %s\n", fishCompletionFile)
@@ -0,0 +1,228 @@
+package wooh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// runCLI runs the wooh command line with args against conf, written out as
+// the config file, and returns what it printed to stdout.
+func runCLI(t *testing.T, conf *Config, args ...string) (string, error) {
+	t.Helper()
+	restoreLogger(t)
+	trace, quiet, yes := httpTrace, quietLogs, assumeYes
+	t.Cleanup(func() { httpTrace, quietLogs, assumeYes = trace, quiet, yes })
+
+	configPath := filepath.Join(t.TempDir(), "wooh.yaml")
+	config := fmt.Sprintf("site: %s\nconsumer_key: %s\nconsumer_secret: %s\ncache_dir: %s\n",
+		conf.Site, conf.WooConsumerKey, conf.WooConsumerSecret, conf.CacheDir)
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	cmd := newRootCmd()
+	cmd.SetArgs(append(args, "--config", configPath, "--env-file", filepath.Join(t.TempDir(), ".env"), "--quiet"))
+	err = cmd.ExecuteContext(context.Background())
+	w.Close()
+	os.Stdout = stdout
+	return <-out, err
+}
+
+func TestCLISubcommands(t *testing.T) {
+	newStore := func() *fakeStore {
+		oak := testProduct(1, "Oak Board")
+		oak["sku"] = "OAK"
+		dup := testProduct(2, "Oak Board")
+		dup["sku"] = "OAK"
+		return newFakeStore(oak, dup)
+	}
+
+	t.Run("seo", func(t *testing.T) {
+		store := newStore()
+		conf, _ := newTestStore(t, store.handle)
+		if _, err := runCLI(t, conf, "seo", "--stub-openai", "--ignore", "2"); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(store.written(), []int64{1}) {
+			t.Errorf("wrote %v, want [1]", store.written())
+		}
+		if store.product(1).MetaData.YoastTitle() == "" {
+			t.Error("no meta title written")
+		}
+	})
+
+	t.Run("seo rejects bad flags", func(t *testing.T) {
+		conf, requests := newTestStore(t, newStore().handle)
+		_, err := runCLI(t, conf, "seo", "--order-by", "price")
+		if err == nil || !strings.Contains(err.Error(), "unsupported --order-by") {
+			t.Errorf("err = %v, want unsupported --order-by", err)
+		}
+		if requests.Load() != 0 {
+			t.Error("store contacted despite invalid flags")
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		conf, _ := newTestStore(t, newStore().handle)
+		out, err := runCLI(t, conf, "list")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "Oak Board") || !strings.Contains(out, "SEO Score:") {
+			t.Errorf("list printed %q", out)
+		}
+	})
+
+	t.Run("audit duplicates", func(t *testing.T) {
+		conf, _ := newTestStore(t, newStore().handle)
+		out, err := runCLI(t, conf, "audit", "duplicates")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "Duplicate names: 1") || !strings.Contains(out, "Duplicate SKUs: 1") {
+			t.Errorf("audit printed %q", out)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		conf, _ := newTestStore(t, newStore().handle)
+		if _, err := runCLI(t, conf, "skip", "1", "2"); err != nil {
+			t.Fatal(err)
+		}
+		skipList, err := LoadSkipList(mustCachePath(t, conf, conf.SkipListFilename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !skipList.contains(1) || !skipList.contains(2) {
+			t.Errorf("skip list = %v", skipList.IDs)
+		}
+		if _, err := runCLI(t, conf, "skip", "abc"); err == nil {
+			t.Error("skip abc = nil error")
+		}
+	})
+
+	t.Run("visibility", func(t *testing.T) {
+		store := newStore()
+		conf, _ := newTestStore(t, store.handle)
+		if _, err := runCLI(t, conf, "visibility", "2", "hidden"); err != nil {
+			t.Fatal(err)
+		}
+		if got := store.products[2]["catalog_visibility"]; got != "hidden" {
+			t.Errorf("catalog_visibility = %v", got)
+		}
+	})
+
+	t.Run("upload dry run", func(t *testing.T) {
+		store := newStore()
+		conf, _ := newTestStore(t, store.handle)
+		dir := t.TempDir()
+		writeJPEG(t, filepath.Join(dir, "walnut-board.jpg"))
+		out, err := runCLI(t, conf, "upload", dir, "--dry-run")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(store.written()) > 0 || len(store.products) != 2 {
+			t.Errorf("dry run changed the store: %v", store.written())
+		}
+		if !strings.Contains(strings.ToLower(out), "walnut") {
+			t.Errorf("dry run printed %q", out)
+		}
+	})
+}
+
+func TestCLIDeprecatedFlags(t *testing.T) {
+	root := newRootCmd()
+	for flag, replacement := range map[string]string{
+		"autofill":        "wooh seo",
+		"listProductMeta": "wooh list",
+		"images-path":     "wooh upload <dir>",
+		"resetAutofill":   "wooh seo --reset-tracker",
+	} {
+		f := root.Flags().Lookup(flag)
+		if f == nil {
+			t.Errorf("flag --%s was removed", flag)
+			continue
+		}
+		if !strings.Contains(f.Deprecated, replacement) {
+			t.Errorf("--%s deprecation = %q, want it to point at %q", flag, f.Deprecated, replacement)
+		}
+	}
+}
+
+func TestCompletionCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	fishFile := filepath.Join(home, ".config", "fish", "completions", "gen-webmanifest.fish")
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"completion", "--stdout"}, "complete -c wooh"},
+		{[]string{"completion", "fish", "--stdout"}, "complete -c wooh"},
+		{[]string{"completion", "bash"}, "__start_wooh"},
+		{[]string{"completion", "zsh"}, "#compdef wooh"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var out strings.Builder
+			cmd := newRootCmd()
+			cmd.SetOut(&out)
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output lacks %q:\n%.200s", tt.want, out.String())
+			}
+			if _, err := os.Stat(fishFile); err == nil {
+				t.Error("completion written to ~/.config/fish despite printing")
+			}
+		})
+	}
+	t.Run("completion", func(t *testing.T) {
+		cmd := newRootCmd()
+		cmd.SetArgs([]string{"completion"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(fishFile)
+		if err != nil || !strings.Contains(string(b), "complete -c wooh") {
+			t.Errorf("fish completion not written without --stdout: %v", err)
+		}
+	})
+}
+
+func TestCLIPriceRange(t *testing.T) {
+	for _, args := range [][]string{
+		{"--min-price", "50", "--max-price", "20"},
+		{"--min-price", "-1"},
+	} {
+		conf, requests := newTestStore(t, newFakeStore().handle)
+		_, err := runCLI(t, conf, append([]string{"seo"}, args...)...)
+		if err == nil || !strings.Contains(err.Error(), "invalid price range") {
+			t.Errorf("%v: err = %v, want invalid price range", args, err)
+		}
+		if requests.Load() != 0 {
+			t.Errorf("%v: store contacted despite an invalid range", args)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package wooh
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrNotConfirmed is returned when a destructive operation was declined or
+// could not be confirmed.
+var ErrNotConfirmed = errors.New("operation not confirmed")
+
+// assumeYes skips the confirmation of destructive operations; set by --yes.
+var assumeYes bool
+
+// confirmDestructive prints how many products op will change and asks for
+// confirmation. Without a terminal to ask on it refuses, unless --yes was
+// given.
+func confirmDestructive(count int, op string) bool {
+	fmt.Printf("About to %s for %d products.\n", op, count)
+	if assumeYes {
+		return true
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Println("Refusing to continue: stdin is not a terminal, so this cannot be confirmed. Rerun with --yes to proceed.")
+		return false
+	}
+	fmt.Print("Continue? (y/N): ")
+	input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
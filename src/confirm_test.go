@@ -0,0 +1,89 @@
+package wooh
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// withPipedStdin makes stdin a pipe, which is never a terminal, and restores
+// it and assumeYes when the test ends.
+func withPipedStdin(t *testing.T) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	stdin, yes := os.Stdin, assumeYes
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin, assumeYes = stdin, yes })
+}
+
+// captureStdout returns what fn prints to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	fn()
+	w.Close()
+	os.Stdout = stdout
+	return <-out
+}
+
+func TestConfirmDestructive(t *testing.T) {
+	withPipedStdin(t)
+
+	for _, yes := range []bool{false, true} {
+		assumeYes = yes
+		var confirmed bool
+		out := captureStdout(t, func() { confirmed = confirmDestructive(3, "reassign categories (replace)") })
+		if confirmed != yes {
+			t.Errorf("--yes %v: confirmed = %v", yes, confirmed)
+		}
+		if !strings.Contains(out, "About to reassign categories (replace) for 3 products.") {
+			t.Errorf("--yes %v: summary missing from %q", yes, out)
+		}
+		if refused := strings.Contains(out, "stdin is not a terminal") && strings.Contains(out, "--yes"); refused == yes {
+			t.Errorf("--yes %v: output %q", yes, out)
+		}
+	}
+}
+
+func TestUpdateSEORegenerateNeedsConfirmation(t *testing.T) {
+	withPipedStdin(t)
+
+	for _, yes := range []bool{false, true} {
+		thin := testProduct(1, "Oak Board")
+		thin["description"] = "<p>Oak.</p>"
+		store := newFakeStore(thin)
+		conf, _ := newTestStore(t, store.handle)
+		conf.OpenAIStub = true
+		assumeYes = yes
+
+		_, err := UpdateSEO(conf, SEOOptions{RegenerateDescriptions: true, Quiet: true})
+		if yes {
+			if err != nil || len(store.written()) != 1 {
+				t.Errorf("with --yes: err %v, wrote %v", err, store.written())
+			}
+			continue
+		}
+		if !errors.Is(err, ErrNotConfirmed) {
+			t.Errorf("err = %v, want ErrNotConfirmed", err)
+		}
+		if len(store.written()) != 0 {
+			t.Errorf("wrote %v without confirmation", store.written())
+		}
+	}
+}
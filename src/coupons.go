@@ -0,0 +1,65 @@
+package wooh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+var allowedDiscountTypes = []string{"percent", "fixed_cart", "fixed_product"}
+
+type Coupon struct {
+	ID           int64  `json:"id,omitempty"`
+	Code         string `json:"code"`
+	DiscountType string `json:"discount_type"`
+	Amount       string `json:"amount"`
+	DateExpires  string `json:"date_expires,omitempty"` // site-local ISO8601, empty for no expiry
+}
+
+func validateCoupon(c Coupon) error {
+	if strings.TrimSpace(c.Code) == "" {
+		return fmt.Errorf("coupon code is required")
+	}
+	valid := false
+	for _, t := range allowedDiscountTypes {
+		if c.DiscountType == t {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("unsupported discount type %q (allowed: %s)", c.DiscountType, strings.Join(allowedDiscountTypes, ", "))
+	}
+	if c.Amount == "" {
+		return fmt.Errorf("coupon amount is required")
+	}
+	return nil
+}
+
+// CreateCoupon creates c and returns the new coupon's ID.
+func CreateCoupon(conf *Config, c Coupon) (int, error) {
+	if err := validateCoupon(c); err != nil {
+		return 0, err
+	}
+
+	resp, err := newClient(conf).R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(c).
+		Post(wooEndpoint(conf, "coupons"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create coupon: %w", err)
+	}
+	if resp.IsError() {
+		return 0, fmt.Errorf("failed to create coupon: %w", apiError(resp))
+	}
+
+	var created Coupon
+	if err := decodeJSON(resp, &created); err != nil {
+		return 0, fmt.Errorf("failed to parse created coupon: %w", err)
+	}
+	return int(created.ID), nil
+}
+
+// ListCoupons fetches every coupon.
+func ListCoupons(conf *Config) ([]Coupon, error) {
+	return paginate[Coupon](context.Background(), newClient(conf), conf, "coupons", nil)
+}
@@ -0,0 +1,91 @@
+package wooh
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreateCoupon(t *testing.T) {
+	tests := []struct {
+		name     string
+		coupon   Coupon
+		wantBody map[string]interface{}
+		wantErr  string
+	}{
+		{
+			name:   "percent with expiry",
+			coupon: Coupon{Code: "OAK10", DiscountType: "percent", Amount: "10", DateExpires: "2026-12-31T23:59:59"},
+			wantBody: map[string]interface{}{
+				"code": "OAK10", "discount_type": "percent", "amount": "10", "date_expires": "2026-12-31T23:59:59",
+			},
+		},
+		{
+			name:     "fixed cart without expiry",
+			coupon:   Coupon{Code: "FIVEOFF", DiscountType: "fixed_cart", Amount: "5.00"},
+			wantBody: map[string]interface{}{"code": "FIVEOFF", "discount_type": "fixed_cart", "amount": "5.00"},
+		},
+		{name: "missing code", coupon: Coupon{Code: " ", DiscountType: "percent", Amount: "10"}, wantErr: "code is required"},
+		{name: "bad discount type", coupon: Coupon{Code: "X", DiscountType: "free", Amount: "10"}, wantErr: "unsupported discount type"},
+		{name: "missing amount", coupon: Coupon{Code: "X", DiscountType: "percent"}, wantErr: "amount is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			conf, requests := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/coupons") {
+					t.Errorf("got %s %s, want POST coupons", r.Method, r.URL.Path)
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": 42, "code": "oak10"}`))
+			})
+
+			id, err := CreateCoupon(conf, tt.coupon)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				if requests.Load() != 0 {
+					t.Error("sent an invalid coupon to the store")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != 42 {
+				t.Errorf("id = %d, want 42", id)
+			}
+			if !reflect.DeepEqual(body, tt.wantBody) {
+				t.Errorf("body = %v, want %v", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestListCoupons(t *testing.T) {
+	conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-WP-TotalPages", "1")
+		w.Write([]byte(`[
+			{"id": 1, "code": "oak10", "discount_type": "percent", "amount": "10.00", "date_expires": "2026-12-31T00:00:00", "usage_count": 3},
+			{"id": 2, "code": "fiveoff", "discount_type": "fixed_cart", "amount": "5.00", "date_expires": null}
+		]`))
+	})
+
+	coupons, err := ListCoupons(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Coupon{
+		{ID: 1, Code: "oak10", DiscountType: "percent", Amount: "10.00", DateExpires: "2026-12-31T00:00:00"},
+		{ID: 2, Code: "fiveoff", DiscountType: "fixed_cart", Amount: "5.00"},
+	}
+	if !reflect.DeepEqual(coupons, want) {
+		t.Errorf("coupons = %+v, want %+v", coupons, want)
+	}
+}
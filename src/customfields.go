@@ -0,0 +1,128 @@
+package wooh
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// decodeProducts decodes a product list response and fills each product's
+// Extra from conf.CustomFields and GTIN from structured_data.gtin_path.
+func decodeProducts(conf *Config, resp *resty.Response, products *[]WooProduct) error {
+	if err := decodeJSON(resp, products); err != nil {
+		return err
+	}
+	if !hasPathFields(conf) {
+		return nil
+	}
+	var raw []interface{}
+	if err := json.Unmarshal(resp.Body(), &raw); err != nil {
+		return err
+	}
+	for i := range *products {
+		if i < len(raw) {
+			decodePathFields(conf, &(*products)[i], raw[i])
+		}
+	}
+	return nil
+}
+
+// decodeProduct is decodeProducts for a single product response.
+func decodeProduct(conf *Config, resp *resty.Response, product *WooProduct) error {
+	if err := decodeJSON(resp, product); err != nil {
+		return err
+	}
+	if !hasPathFields(conf) {
+		return nil
+	}
+	var raw interface{}
+	if err := json.Unmarshal(resp.Body(), &raw); err != nil {
+		return err
+	}
+	decodePathFields(conf, product, raw)
+	return nil
+}
+
+// hasPathFields reports whether conf reads any product field by JSON path,
+// which needs the raw product alongside the decoded one.
+func hasPathFields(conf *Config) bool {
+	return len(conf.CustomFields) > 0 || conf.StructuredData.GTINPath != ""
+}
+
+// decodePathFields fills the fields of product read by JSON path from raw,
+// the same product decoded generically.
+func decodePathFields(conf *Config, product *WooProduct, raw interface{}) {
+	if len(conf.CustomFields) > 0 {
+		product.Extra = extractCustomFields(conf.CustomFields, raw)
+	}
+	if conf.StructuredData.GTINPath != "" {
+		product.GTIN = ""
+		if v, ok := lookupJSONPath(raw, conf.StructuredData.GTINPath); ok {
+			product.GTIN = gtinString(v)
+		}
+	}
+}
+
+// gtinString returns a GTIN read as a string or, from a JSON number, as its
+// digits. Anything else is not a usable identifier.
+func gtinString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// extractCustomFields resolves each name -> path mapping against a decoded
+// product. Paths that do not resolve are left out.
+func extractCustomFields(fields map[string]string, product interface{}) map[string]interface{} {
+	extra := make(map[string]interface{}, len(fields))
+	for name, path := range fields {
+		if v, ok := lookupJSONPath(product, path); ok {
+			extra[name] = v
+		}
+	}
+	return extra
+}
+
+// lookupJSONPath walks a dot-separated path through decoded JSON. Numeric
+// segments index arrays; other segments on an array pick the element whose
+// "key" matches and yield its "value", so "meta_data._brand" reads a meta
+// entry and "attributes.0.options" the first attribute's options.
+func lookupJSONPath(v interface{}, path string) (interface{}, bool) {
+	for _, segment := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			if i, err := strconv.Atoi(segment); err == nil {
+				if i < 0 || i >= len(node) {
+					return nil, false
+				}
+				v = node[i]
+				continue
+			}
+			found := false
+			for _, item := range node {
+				if entry, ok := item.(map[string]interface{}); ok && entry["key"] == segment {
+					v, found = entry["value"], true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,82 @@
+package wooh
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	var product interface{}
+	json.Unmarshal([]byte(`{
+		"name": "Oak Board",
+		"brand": {"name": "Acme", "id": 4},
+		"attributes": [{"name": "Colour", "options": ["Natural", "Smoked"]}],
+		"meta_data": [{"id": 1, "key": "_brand", "value": "Acme"}, {"id": 2, "key": "_origin", "value": {"country": "FR"}}]
+	}`), &product)
+
+	tests := []struct {
+		path   string
+		want   interface{}
+		wantOK bool
+	}{
+		{"name", "Oak Board", true},
+		{"brand.name", "Acme", true},
+		{"brand.id", 4.0, true},
+		{"attributes.0.options", []interface{}{"Natural", "Smoked"}, true},
+		{"attributes.0.options.1", "Smoked", true},
+		{"meta_data._brand", "Acme", true},
+		{"meta_data._origin.country", "FR", true},
+		{"meta_data._missing", nil, false},
+		{"attributes.3", nil, false},
+		{"attributes.-1", nil, false},
+		{"name.first", nil, false},
+		{"missing", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := lookupJSONPath(product, tt.path)
+		if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lookupJSONPath(%q) = %v, %v; want %v, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestGetProductsCustomFields(t *testing.T) {
+	product := testProduct(1, "Oak Board")
+	product["brand"] = map[string]interface{}{"name": "Acme"}
+	product["meta_data"] = []interface{}{map[string]interface{}{"id": 9, "key": "_warranty_years", "value": 10}}
+	store := newFakeStore(product, testProduct(2, "Ash Board"))
+	conf, _ := newTestStore(t, store.handle)
+	conf.CustomFields = map[string]string{
+		"brand":    "brand.name",
+		"warranty": "meta_data._warranty_years",
+	}
+
+	cache, err := NewCache(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	products, err := GetProducts(conf, cache, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"brand": "Acme", "warranty": 10.0},
+		{}, // unresolved paths are left out
+	}
+	for i, p := range products {
+		if !reflect.DeepEqual(p.Extra, want[i]) && !(len(p.Extra) == 0 && len(want[i]) == 0) {
+			t.Errorf("product %d Extra = %v, want %v", p.ID, p.Extra, want[i])
+		}
+	}
+
+	// Extra survives the product cache.
+	cached, err := GetProducts(conf, cache, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cached[0].Extra, want[0]) {
+		t.Errorf("cached Extra = %v, want %v", cached[0].Extra, want[0])
+	}
+}
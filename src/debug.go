@@ -0,0 +1,98 @@
+package wooh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type DebugDump struct {
+	Time         time.Time `json:"time"`
+	Kind         string    `json:"kind"`
+	SystemPrompt string    `json:"system_prompt"`
+	UserPrompt   string    `json:"user_prompt"`
+	Response     string    `json:"response"`
+	Error        string    `json:"error,omitempty"`
+}
+
+var debugDumpSeq atomic.Int64
+
+// writeDebugDump stores a prompt/response pair in conf.DebugDir when set.
+// Files are written under a temporary name and renamed so readers and
+// concurrent writers never see partial dumps. Failures are only logged.
+func writeDebugDump(conf *Config, dump DebugDump) {
+	if conf.DebugDir == "" {
+		return
+	}
+	if err := os.MkdirAll(conf.DebugDir, 0755); err != nil {
+		slog.Warn("Could not create debug dir", "err", err)
+		return
+	}
+
+	dump.Time = time.Now().UTC()
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		slog.Warn("Could not marshal debug dump", "err", err)
+		return
+	}
+
+	name := fmt.Sprintf("%s-%06d-%s.json", dump.Time.Format("20060102T150405.000000000"), debugDumpSeq.Add(1), dump.Kind)
+	tmp, err := os.CreateTemp(conf.DebugDir, ".dump-*")
+	if err != nil {
+		slog.Warn("Could not write debug dump", "err", err)
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		slog.Warn("Could not write debug dump", "err", errors.Join(writeErr, closeErr))
+		return
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(conf.DebugDir, name)); err != nil {
+		os.Remove(tmp.Name())
+		slog.Warn("Could not write debug dump", "err", err)
+		return
+	}
+
+	if conf.KeepDebug > 0 {
+		if err := PruneDebugDumps(conf.DebugDir, conf.KeepDebug); err != nil {
+			slog.Warn("Could not prune debug dumps", "err", err)
+		}
+	}
+}
+
+// PruneDebugDumps removes all but the keep most recent dumps in dir. Dump
+// names start with a timestamp, so lexical order is chronological.
+func PruneDebugDumps(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var dumps []string
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") && strings.HasSuffix(e.Name(), ".json") {
+			dumps = append(dumps, e.Name())
+		}
+	}
+	if len(dumps) <= keep {
+		return nil
+	}
+
+	sort.Strings(dumps)
+	for _, name := range dumps[:len(dumps)-keep] {
+		// Another worker may have pruned the same file already.
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package wooh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPruneDebugDumps(t *testing.T) {
+	tests := []struct {
+		name  string
+		dumps int
+		keep  int
+		want  []string
+	}{
+		{"under limit", 2, 3, []string{"20260101-1.json", "20260101-2.json"}},
+		{"at limit", 3, 3, []string{"20260101-1.json", "20260101-2.json", "20260101-3.json"}},
+		{"over limit", 5, 2, []string{"20260101-4.json", "20260101-5.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i := tt.dumps; i >= 1; i-- {
+				writeFile(t, filepath.Join(dir, fmt.Sprintf("20260101-%d.json", i)))
+			}
+			// Neither in-flight temp files nor other files are dumps.
+			writeFile(t, filepath.Join(dir, ".dump-123"))
+			writeFile(t, filepath.Join(dir, "notes.txt"))
+
+			if err := PruneDebugDumps(dir, tt.keep); err != nil {
+				t.Fatal(err)
+			}
+			want := append([]string{".dump-123"}, tt.want...)
+			want = append(want, "notes.txt")
+			if got := dirNames(t, dir); !slices.Equal(got, want) {
+				t.Errorf("left %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteDebugDumpKeepsNewest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "debug")
+	conf := &Config{DebugDir: dir, KeepDebug: 3}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			writeDebugDump(conf, DebugDump{Kind: "meta", Response: "{}"})
+		}()
+	}
+	wg.Wait()
+	writeDebugDump(conf, DebugDump{Kind: "last", Response: "{}"})
+
+	names := dirNames(t, dir)
+	if len(names) != 3 {
+		t.Fatalf("kept %v, want 3 dumps", names)
+	}
+	if last := names[len(names)-1]; !strings.HasSuffix(last, "-last.json") {
+		t.Errorf("newest dump %q is not the last one written", last)
+	}
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
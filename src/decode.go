@@ -0,0 +1,148 @@
+package wooh
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// Product payloads differ between WooCommerce versions, HPOS setups and
+// plugins: optional fields go missing or null, empty lists come back as
+// PHP's "{}", and empty strings as false. The decoders below read what
+// they can and leave the rest at its zero value instead of failing the
+// whole page.
+
+// UnmarshalJSON decodes a product, tolerating missing, null and
+// oddly-typed optional fields.
+func (p *WooProduct) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*p = WooProduct{
+		ID:               flexInt(raw["id"]),
+		Name:             flexString(raw["name"]),
+		Sku:              flexString(raw["sku"]),
+		Description:      flexString(raw["description"]),
+		ShortDescription: flexString(raw["short_description"]),
+		RegularPrice:     flexString(raw["regular_price"]),
+		DateModifiedGMT:  flexString(raw["date_modified_gmt"]),
+	}
+	for _, c := range jsonList(raw["categories"]) {
+		var category WooCategory
+		if json.Unmarshal(c, &category) == nil {
+			p.Categories = append(p.Categories, category)
+		}
+	}
+	if err := json.Unmarshal(orNull(raw["meta_data"]), &p.MetaData); err != nil {
+		return err
+	}
+	if extra, ok := raw["extra"]; ok {
+		_ = json.Unmarshal(extra, &p.Extra)
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes meta_data given as a list, as an object keyed by
+// index, or as null.
+func (m *MetaData) UnmarshalJSON(data []byte) error {
+	*m = nil
+	for _, item := range jsonList(data) {
+		var entry MetaEntry
+		if err := json.Unmarshal(item, &entry); err != nil {
+			continue
+		}
+		*m = append(*m, entry)
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a meta entry whose ID may be a number or a numeric
+// string.
+func (e *MetaEntry) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ID    json.RawMessage `json:"id"`
+		Key   json.RawMessage `json:"key"`
+		Value interface{}     `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = MetaEntry{ID: flexInt(raw.ID), Key: flexString(raw.Key), Value: raw.Value}
+	return nil
+}
+
+// jsonList returns the items of a JSON array, or the values of a JSON object
+// ordered by key, which is how PHP encodes a list that lost its indexes.
+// Anything else yields nil.
+func jsonList(data json.RawMessage) []json.RawMessage {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	switch data[0] {
+	case '[':
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) == nil {
+			return items
+		}
+	case '{':
+		var byKey map[string]json.RawMessage
+		if json.Unmarshal(data, &byKey) != nil {
+			return nil
+		}
+		keys := make([]string, 0, len(byKey))
+		for k := range byKey {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			a, errA := strconv.Atoi(keys[i])
+			b, errB := strconv.Atoi(keys[j])
+			if errA == nil && errB == nil {
+				return a < b
+			}
+			return keys[i] < keys[j]
+		})
+		items := make([]json.RawMessage, 0, len(keys))
+		for _, k := range keys {
+			items = append(items, byKey[k])
+		}
+		return items
+	}
+	return nil
+}
+
+// flexString reads a string, a number as its text, and anything else
+// (null, false, objects) as "".
+func flexString(data json.RawMessage) string {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return s
+	}
+	var n json.Number
+	if json.Unmarshal(data, &n) == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// flexInt reads a number or a numeric string, and anything else as 0.
+func flexInt(data json.RawMessage) int64 {
+	var n int64
+	if json.Unmarshal(data, &n) == nil {
+		return n
+	}
+	if v, err := strconv.ParseInt(flexString(data), 10, 64); err == nil {
+		return v
+	}
+	return 0
+}
+
+func orNull(data json.RawMessage) json.RawMessage {
+	if len(data) == 0 {
+		return json.RawMessage("null")
+	}
+	return data
+}
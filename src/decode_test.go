@@ -0,0 +1,88 @@
+package wooh
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeProductsAcrossVersions(t *testing.T) {
+	boards := WooCategory{ID: 12, Name: "Boards", Slug: "boards"}
+	tests := []struct {
+		fixture string
+		want    WooProduct
+	}{
+		{"wc7-products.json", WooProduct{
+			ID: 101, Name: "Oak Board", Sku: "OAK-1", RegularPrice: "24.00",
+			Description: "<p>Solid oak board, oiled.</p>\n", ShortDescription: "<p>Solid oak.</p>\n",
+			DateModifiedGMT: "2023-03-14T09:12:45",
+			Categories:      []WooCategory{boards},
+			MetaData: MetaData{
+				{ID: 5501, Key: yoastTitleKey, Value: "Oak Board | Shop"},
+				{ID: 5502, Key: yoastDescKey, Value: "Solid oak board."},
+			},
+		}},
+		// HPOS stores drop short_description and encode lists as objects.
+		{"wc8-products.json", WooProduct{
+			ID: 202, Name: "Walnut Board", RegularPrice: "31.5", Description: "<p>Walnut.</p>",
+			DateModifiedGMT: "2024-01-09T16:40:02",
+			Categories:      []WooCategory{boards, {ID: 14, Name: "Walnut", Slug: "walnut"}},
+			MetaData: MetaData{
+				{ID: 6601, Key: yoastTitleKey, Value: "Walnut Board"},
+				{ID: 6602, Key: yoastDescKey, Value: "Walnut board."},
+			},
+		}},
+		{"wc9-products.json", WooProduct{ID: 303, Name: "Ash Board", RegularPrice: "18"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var products []WooProduct
+			if err := json.Unmarshal(data, &products); err != nil {
+				t.Fatal(err)
+			}
+			if len(products) != 1 || !reflect.DeepEqual(products[0], tt.want) {
+				t.Errorf("decoded %+v\nwant %+v", products, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetProductsDecodesEachVersion(t *testing.T) {
+	for _, fixture := range []string{"wc7-products.json", "wc8-products.json", "wc9-products.json"} {
+		t.Run(fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(data)
+			})
+			cache, _ := NewCache(conf)
+			products, err := GetProducts(conf, cache, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(products) != 1 || products[0].ID == 0 || products[0].Name == "" {
+				t.Errorf("got %+v", products)
+			}
+		})
+	}
+}
+
+func TestDecodeProductRejectsNonObjects(t *testing.T) {
+	for _, data := range []string{`[]`, `"product"`, `42`} {
+		var p WooProduct
+		if err := json.Unmarshal([]byte(data), &p); err == nil {
+			t.Errorf("decoding %s returned no error", data)
+		}
+	}
+}
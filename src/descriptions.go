@@ -0,0 +1,153 @@
+package wooh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultDescriptionMinLength      = 300
+	defaultShortDescriptionMinLength = 60
+	defaultMinDescriptionWords       = 50
+)
+
+type SEOInput struct {
+	Name             string
+	ShortDescription string
+	Description      string
+	Categories       []WooCategory
+
+	// Set when include_reviews is on and the product has reviews.
+	AverageRating float64
+	ReviewCount   int
+	TopReview     string
+}
+
+func seoInputFromProduct(conf *Config, p WooProduct) (SEOInput, error) {
+	description, err := cleanHTMLToMarkdown(p.Description, conf.Markdown)
+	if err != nil {
+		return SEOInput{}, err
+	}
+	shortDescription, err := cleanHTMLToMarkdown(p.ShortDescription, conf.Markdown)
+	if err != nil {
+		return SEOInput{}, err
+	}
+	return SEOInput{
+		Name:             p.Name,
+		ShortDescription: shortDescription,
+		Description:      description,
+		Categories:       p.Categories,
+	}, nil
+}
+
+func DescriptionSystemPrompt() string {
+	return `
+You are an experienced e-commerce copywriter with expertise in flooring materials.
+Rewrite thin product copy into a complete, accurate product description:
+- Use only facts present in the provided product information; do not invent specifications.
+- Structure the text with short paragraphs and a bullet list of key features.
+- Return plain HTML using only <p>, <ul>, <li> and <strong> tags.
+- Do not include the product name as a heading.
+`
+}
+func ShortDescriptionSystemPrompt() string {
+	return `
+You are an experienced e-commerce copywriter.
+Write a one or two sentence summary of the product for display next to its price.
+- Use only facts present in the provided product information.
+- Return plain text without HTML or Markdown.
+`
+}
+
+func EnrichmentSystemPrompt() string {
+	return `
+You are an experienced e-commerce copywriter with expertise in flooring materials.
+Expand the provided product information into a fuller plain-text description of about 150 words:
+- Explain what the product is, what it is used for and which details in the information matter to a buyer.
+- Do not invent specifications, materials or dimensions that are not given.
+- Return plain text without HTML or Markdown.
+`
+}
+
+// EnrichDescription asks OpenAI to expand a thin description. The result is
+// only used as input for meta generation; it is not written to the store.
+func EnrichDescription(ctx context.Context, conf *Config, input SEOInput) (string, error) {
+	if conf.OpenAIStub {
+		return stubText("enriched description", input), nil
+	}
+	return generateText(ctx, conf, EnrichmentSystemPrompt(), seoInputPrompt(input))
+}
+
+// wordCount counts the whitespace-separated words of s.
+func wordCount(s string) int {
+	return len(strings.Fields(s))
+}
+
+// GenerateDescription asks OpenAI for a full product description in HTML.
+func GenerateDescription(ctx context.Context, conf *Config, input SEOInput) (string, error) {
+	if conf.OpenAIStub {
+		return "<p>" + stubText("description", input) + "</p>", nil
+	}
+	return generateText(ctx, conf, DescriptionSystemPrompt(), seoInputPrompt(input))
+}
+
+// GenerateShortDescription asks OpenAI for a short plain-text product summary.
+func GenerateShortDescription(ctx context.Context, conf *Config, input SEOInput) (string, error) {
+	if conf.OpenAIStub {
+		return stubText("short description", input), nil
+	}
+	return generateText(ctx, conf, ShortDescriptionSystemPrompt(), seoInputPrompt(input))
+}
+
+func seoInputPrompt(input SEOInput) string {
+	prompt := OpenAIUserPrompt(input.Name, input.ShortDescription, input.Description, input.Categories)
+	if input.ReviewCount > 0 {
+		prompt += fmt.Sprintf("- Average Rating: %.1f out of 5 from %d reviews\n", input.AverageRating, input.ReviewCount)
+		if input.TopReview != "" {
+			prompt += fmt.Sprintf("- Top Review: %q\n", input.TopReview)
+		}
+	}
+	return prompt
+}
+
+// generateText asks the configured generator for free-form copy.
+func generateText(ctx context.Context, conf *Config, systemPrompt string, userPrompt string) (string, error) {
+	return newGenerator(conf).Text(ctx, systemPrompt, userPrompt)
+}
+
+func openAIText(ctx context.Context, conf *Config, systemPrompt string, userPrompt string) (string, error) {
+	client := newOpenAIClient(conf)
+
+	resp, err := client.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: conf.GeneratorModel,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+				{Role: openai.ChatMessageRoleUser, Content: userPrompt},
+			},
+			Temperature: 0.7,
+		},
+	)
+	if err != nil {
+		writeDebugDump(conf, DebugDump{Kind: "text", SystemPrompt: systemPrompt, UserPrompt: userPrompt, Error: err.Error()})
+		return "", fmt.Errorf("failed to get chat completion: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned by OpenAI API")
+	}
+	writeDebugDump(conf, DebugDump{Kind: "text", SystemPrompt: systemPrompt, UserPrompt: userPrompt, Response: resp.Choices[0].Message.Content})
+
+	if refusal := contentPolicyError(resp.Choices[0]); refusal != nil {
+		return "", refusal
+	}
+
+	text := strings.TrimSpace(resp.Choices[0].Message.Content)
+	if text == "" {
+		return "", fmt.Errorf("OpenAI returned an empty response")
+	}
+	return text, nil
+}
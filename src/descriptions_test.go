@@ -0,0 +1,146 @@
+package wooh
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// copyWriter answers meta requests with fixed meta and text requests with
+// copy naming the prompt it was asked for.
+func copyWriter(req chatRequest) openai.ChatCompletionChoice {
+	if isMetaRequest(req) {
+		return textChoice(metaJSON(map[string]string{"meta_title": "Oak Board", "meta_description": "Solid oak board."}))
+	}
+	switch req.Messages[0].Content {
+	case DescriptionSystemPrompt():
+		return textChoice("<p>A full description.</p>")
+	case ShortDescriptionSystemPrompt():
+		return textChoice("A short summary.")
+	case EnrichmentSystemPrompt():
+		return textChoice("An enriched description of the board, with much more to say.")
+	}
+	return textChoice("Other copy.")
+}
+
+func TestGenerateDescription(t *testing.T) {
+	gen := newFakeGenerator(t, copyWriter)
+	conf := &Config{}
+	applyDefaults(conf)
+	gen.use(conf)
+
+	input := SEOInput{Name: "Oak Board", Description: "Oak."}
+	description, err := GenerateDescription(context.Background(), conf, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	short, err := GenerateShortDescription(context.Background(), conf, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if description != "<p>A full description.</p>" || short != "A short summary." {
+		t.Errorf("description %q, short description %q", description, short)
+	}
+	if sent := gen.sent(); len(sent) != 2 || !strings.Contains(sent[0].Messages[1].Content, "Oak Board") {
+		t.Errorf("prompts did not carry the product: %+v", sent)
+	}
+}
+
+func TestUpdateSEORegeneratesThinDescriptions(t *testing.T) {
+	restore := assumeYes
+	assumeYes = true
+	t.Cleanup(func() { assumeYes = restore })
+
+	for _, regenerate := range []bool{false, true} {
+		name := "meta only"
+		if regenerate {
+			name = "regenerate descriptions"
+		}
+		t.Run(name, func(t *testing.T) {
+			thin := testProduct(1, "Oak Board")
+			thin["description"] = "<p>Oak.</p>"
+			thin["short_description"] = "Oak"
+			full := testProduct(2, "Walnut Board")
+			full["description"] = "<p>" + strings.Repeat("Solid walnut, oiled and ready to fit in any room. ", 10) + "</p>"
+			full["short_description"] = "A solid walnut board, oiled and ready to fit in any room."
+			store := newFakeStore(thin, full)
+			conf, _ := newTestStore(t, store.handle)
+			newFakeGenerator(t, copyWriter).use(conf)
+
+			if _, err := UpdateSEO(conf, SEOOptions{RegenerateDescriptions: regenerate, Quiet: true}); err != nil {
+				t.Fatal(err)
+			}
+
+			p := store.product(1)
+			if regenerate && (p.Description != "<p>A full description.</p>" || p.ShortDescription != "A short summary.") {
+				t.Errorf("thin product kept description %q, short description %q", p.Description, p.ShortDescription)
+			}
+			if !regenerate && p.Description != "<p>Oak.</p>" {
+				t.Errorf("description rewritten without --regenerate-descriptions: %q", p.Description)
+			}
+			if p.MetaData.YoastTitle() != "Oak Board" {
+				t.Errorf("meta title = %q", p.MetaData.YoastTitle())
+			}
+			if got := store.product(2); got.Description != full["description"] || got.ShortDescription != full["short_description"] {
+				t.Errorf("long enough descriptions were rewritten: %q", got.ShortDescription)
+			}
+		})
+	}
+}
+
+func TestUpdateSEOEnrichesShortDescriptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		enrich       bool
+		wantEnriched []int
+	}{
+		{"enrich", true, []int{2}},
+		{"without --enrich", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Product 1 has 11 words of description, product 2 three.
+			thin := testProduct(2, "Ash Board")
+			thin["description"] = "<p>Short ash board.</p>"
+			store := newFakeStore(testProduct(1, "Oak Board"), thin)
+			conf, _ := newTestStore(t, store.handle)
+			conf.MinDescriptionWords = 10
+			gen := newFakeGenerator(t, copyWriter)
+			gen.use(conf)
+
+			result, err := UpdateSEO(conf, SEOOptions{Enrich: tt.enrich, Quiet: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(result.Enriched, tt.wantEnriched) {
+				t.Errorf("enriched %v, want %v", result.Enriched, tt.wantEnriched)
+			}
+
+			enrichments := 0
+			for _, req := range gen.sent() {
+				prompt := req.Messages[len(req.Messages)-1].Content
+				if !isMetaRequest(req) {
+					enrichments++
+					if !strings.Contains(prompt, "Ash Board") {
+						t.Errorf("enriched a product above the threshold: %s", prompt)
+					}
+					continue
+				}
+				// Meta for the thin product is generated from the enriched copy.
+				if strings.Contains(prompt, "Ash Board") && tt.enrich != strings.Contains(prompt, "An enriched description") {
+					t.Errorf("meta prompt used enriched copy %v, want %v", !tt.enrich, tt.enrich)
+				}
+			}
+			if enrichments != len(tt.wantEnriched) {
+				t.Errorf("sent %d enrichment requests, want %d", enrichments, len(tt.wantEnriched))
+			}
+			// Enrichment only feeds meta generation; the store keeps its copy.
+			if got := store.product(2).Description; got != "<p>Short ash board.</p>" {
+				t.Errorf("description written back: %q", got)
+			}
+		})
+	}
+}
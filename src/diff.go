@@ -0,0 +1,52 @@
+package wooh
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+const (
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorReset = "\033[0m"
+)
+
+type MetaChange struct {
+	Key string
+	Old string
+	New string
+}
+
+// DiffMeta compares the product's current meta against proposed values
+// and returns only the keys that would change.
+func DiffMeta(p WooProduct, proposed MetaData) []MetaChange {
+	var changes []MetaChange
+	for _, entry := range proposed {
+		value := proposed.Get(entry.Key)
+		if current := p.MetaData.Get(entry.Key); current != value {
+			changes = append(changes, MetaChange{Key: entry.Key, Old: current, New: value})
+		}
+	}
+	return changes
+}
+
+func PrintMetaDiff(w io.Writer, p WooProduct, changes []MetaChange) {
+	red, green, reset := colorRed, colorGreen, colorReset
+	if f, ok := w.(*os.File); !ok || !term.IsTerminal(int(f.Fd())) {
+		red, green, reset = "", "", ""
+	}
+
+	fmt.Fprintf(w, "--- product %d: %s\n", p.ID, p.Name)
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "  unchanged")
+		return
+	}
+	for _, c := range changes {
+		fmt.Fprintf(w, "  %s\n", c.Key)
+		fmt.Fprintf(w, "%s  - %s%s\n", red, c.Old, reset)
+		fmt.Fprintf(w, "%s  + %s%s\n", green, c.New, reset)
+	}
+}
@@ -0,0 +1,77 @@
+package wooh
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDiffMeta(t *testing.T) {
+	var current MetaData
+	current.Set(yoastTitleKey, "Oak Board")
+	current.Set(yoastDescKey, "Solid oak.")
+	product := WooProduct{ID: 1, Name: "Oak Board", MetaData: current}
+
+	tests := []struct {
+		name     string
+		proposed map[string]string
+		want     []MetaChange
+	}{
+		{"unchanged", map[string]string{yoastTitleKey: "Oak Board", yoastDescKey: "Solid oak."}, nil},
+		{"changed", map[string]string{yoastTitleKey: "Oak Board | Acme", yoastDescKey: "Solid oak."},
+			[]MetaChange{{Key: yoastTitleKey, Old: "Oak Board", New: "Oak Board | Acme"}}},
+		{"new key", map[string]string{yoastFocusKWKey: "oak board"},
+			[]MetaChange{{Key: yoastFocusKWKey, Old: "", New: "oak board"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var proposed MetaData
+			for _, key := range []string{yoastTitleKey, yoastDescKey, yoastFocusKWKey} {
+				if v, ok := tt.proposed[key]; ok {
+					proposed.Set(key, v)
+				}
+			}
+			if got := DiffMeta(product, proposed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffMeta = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintMetaDiff(t *testing.T) {
+	product := WooProduct{ID: 7, Name: "Oak Board"}
+
+	var buf bytes.Buffer
+	PrintMetaDiff(&buf, product, nil)
+	if want := "--- product 7: Oak Board\n  unchanged\n"; buf.String() != want {
+		t.Errorf("unchanged product printed %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	PrintMetaDiff(&buf, product, []MetaChange{{Key: yoastTitleKey, Old: "Oak", New: "Oak Board"}})
+	// Not a terminal, so no colour codes.
+	want := "--- product 7: Oak Board\n  " + yoastTitleKey + "\n  - Oak\n  + Oak Board\n"
+	if buf.String() != want {
+		t.Errorf("changed product printed %q, want %q", buf.String(), want)
+	}
+}
+
+func TestUpdateSEODiffWritesNothing(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Walnut Board"))
+	conf, _ := newTestStore(t, store.handle)
+	conf.OpenAIStub = true
+
+	if _, err := UpdateSEO(conf, SEOOptions{Diff: true, Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	if written := store.written(); len(written) != 0 {
+		t.Errorf("diff mode wrote products %v", written)
+	}
+	// Nothing was written, so a real run still has every product to do.
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	if written := store.written(); len(written) != 2 {
+		t.Errorf("run after diff wrote %v, want both products", written)
+	}
+}
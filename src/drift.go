@@ -0,0 +1,133 @@
+package wooh
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+)
+
+// DriftReport compares the product cache with the live store.
+type DriftReport struct {
+	Added    []int64 // in the store but not the cache
+	Removed  []int64 // in the cache but no longer in the store
+	Modified []int64 // modified in the store since they were cached
+}
+
+// CacheDrift fetches the ID and modification date of every live product and
+// compares them with the cache, however old it is. Products cached without a
+// modification date, such as those read through the Store API, are never
+// reported as modified.
+func CacheDrift(conf *Config) (DriftReport, error) {
+	cacheFilePath, err := CachePath(conf, conf.CacheFilename)
+	if err != nil {
+		return DriftReport{}, err
+	}
+	cache, err := NewCache(conf)
+	if err != nil {
+		return DriftReport{}, err
+	}
+	cached, ok, err := cache.Fetch(time.Duration(math.MaxInt64))
+	if err != nil {
+		return DriftReport{}, fmt.Errorf("failed to read product cache: %w", err)
+	}
+	if !ok {
+		return DriftReport{}, fmt.Errorf("no product cache at %s", cacheFilePath)
+	}
+
+	live, err := fetchProductPages(conf, map[string]string{"_fields": "id,date_modified_gmt"})
+	if err != nil {
+		return DriftReport{}, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	return compareDrift(cached, live), nil
+}
+
+func compareDrift(cached, live []WooProduct) DriftReport {
+	cachedByID := make(map[int64]WooProduct, len(cached))
+	for _, p := range cached {
+		cachedByID[p.ID] = p
+	}
+
+	var report DriftReport
+	for _, p := range live {
+		old, ok := cachedByID[p.ID]
+		if !ok {
+			report.Added = append(report.Added, p.ID)
+			continue
+		}
+		delete(cachedByID, p.ID)
+		if old.DateModifiedGMT != "" && p.DateModifiedGMT != "" && old.DateModifiedGMT != p.DateModifiedGMT {
+			report.Modified = append(report.Modified, p.ID)
+		}
+	}
+	for id := range cachedByID {
+		report.Removed = append(report.Removed, id)
+	}
+	sort.Slice(report.Removed, func(i, j int) bool { return report.Removed[i] < report.Removed[j] })
+	return report
+}
+
+// PruneCache drops cached products that no longer exist in the store, so a
+// cache kept across runs does not feed deleted products back into them. The
+// cache keeps its age; only the live ID list is fetched.
+func PruneCache(conf *Config) (removed int, err error) {
+	cacheFilePath, err := CachePath(conf, conf.CacheFilename)
+	if err != nil {
+		return 0, err
+	}
+
+	live, err := fetchProductPages(conf, map[string]string{"_fields": "id"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	validIDs := make(map[int]bool, len(live))
+	for _, p := range live {
+		validIDs[int(p.ID)] = true
+	}
+
+	if conf.CacheFormat == CacheFormatGob {
+		removed, err = pruneGobCache(cacheFilePath, validIDs)
+	} else {
+		removed, err = pruneJSONCache(cacheFilePath, validIDs)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if removed > 0 {
+		slog.Info("Pruned deleted products from the cache", "removed", removed)
+	}
+	return removed, nil
+}
+
+func pruneJSONCache(cacheFilePath string, validIDs map[int]bool) (int, error) {
+	var pc ProductCache
+	cached, err := pc.FetchFromCache(cacheFilePath, time.Duration(math.MaxInt64))
+	if err != nil || cached == nil {
+		return 0, err
+	}
+	removed := pc.Prune(validIDs)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, pc.write(cacheFilePath)
+}
+
+func pruneGobCache(cacheFilePath string, validIDs map[int]bool) (int, error) {
+	cache, err := readGobCache(cacheFilePath)
+	if err != nil || cache == nil {
+		return 0, err
+	}
+	kept := cache.Products[:0]
+	for _, p := range cache.Products {
+		if validIDs[int(p.ID)] {
+			kept = append(kept, p)
+		}
+	}
+	removed := len(cache.Products) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	cache.Products = kept
+	return removed, writeGobCache(cacheFilePath, *cache)
+}
@@ -0,0 +1,111 @@
+package wooh
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCacheDrift(t *testing.T) {
+	oak := testProduct(1, "Oak Board")
+	walnut := testProduct(2, "Walnut Board")
+	ash := testProduct(3, "Ash Board")
+	for _, p := range []map[string]interface{}{oak, walnut, ash} {
+		p["date_modified_gmt"] = "2024-05-01T10:00:00"
+	}
+	store := newFakeStore(oak, walnut, ash)
+	conf, _ := newTestStore(t, store.handle)
+
+	if _, err := CacheDrift(conf); err == nil {
+		t.Error("CacheDrift without a cache returned no error")
+	}
+
+	cache, err := NewCache(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetProducts(conf, cache, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	report, err := CacheDrift(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Added)+len(report.Removed)+len(report.Modified) != 0 {
+		t.Errorf("fresh cache drifted: %+v", report)
+	}
+
+	store.mu.Lock()
+	store.products[2]["date_modified_gmt"] = "2024-06-01T09:30:00"
+	delete(store.products, 3)
+	store.products[4] = testProduct(4, "Elm Board")
+	store.products[5] = testProduct(5, "Pine Board")
+	store.mu.Unlock()
+
+	report, err = CacheDrift(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := slices.Clone(report.Added)
+	slices.Sort(added)
+	if !slices.Equal(added, []int64{4, 5}) {
+		t.Errorf("added %v, want [4 5]", report.Added)
+	}
+	if !slices.Equal(report.Modified, []int64{2}) {
+		t.Errorf("modified %v, want [2]", report.Modified)
+	}
+	if !slices.Equal(report.Removed, []int64{3}) {
+		t.Errorf("removed %v, want [3]", report.Removed)
+	}
+}
+
+func TestCompareDriftWithoutDates(t *testing.T) {
+	cached := []WooProduct{{ID: 1}, {ID: 2, DateModifiedGMT: "2024-05-01T10:00:00"}}
+	live := []WooProduct{{ID: 1, DateModifiedGMT: "2024-06-01T10:00:00"}, {ID: 2, DateModifiedGMT: "2024-05-01T10:00:00"}}
+	if report := compareDrift(cached, live); len(report.Modified) != 0 {
+		t.Errorf("products cached without a date reported modified: %v", report.Modified)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	for _, format := range []string{CacheFormatJSON, CacheFormatGob} {
+		t.Run(format, func(t *testing.T) {
+			store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Walnut Board"), testProduct(3, "Ash Board"))
+			conf, _ := newTestStore(t, store.handle)
+			conf.CacheFormat = format
+			conf.CacheFilename = "products-cache." + format
+			cache, err := NewCache(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := GetProducts(conf, cache, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+
+			store.mu.Lock()
+			delete(store.products, 2)
+			store.mu.Unlock()
+			removed, err := PruneCache(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed != 1 {
+				t.Errorf("removed %d, want 1", removed)
+			}
+
+			// The pruned cache is still fresh and holds only current products.
+			cache, _ = NewCache(conf)
+			cached, ok, err := cache.Fetch(time.Hour)
+			if err != nil || !ok {
+				t.Fatalf("Fetch after prune = %v, %v", ok, err)
+			}
+			var ids []int64
+			for _, p := range cached {
+				ids = append(ids, p.ID)
+			}
+			if !slices.Equal(ids, []int64{1, 3}) {
+				t.Errorf("cache holds %v, want [1 3]", ids)
+			}
+		})
+	}
+}
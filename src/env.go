@@ -0,0 +1,114 @@
+package wooh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// envOverrides maps WOOH_* environment variables onto config fields. Set
+// variables take precedence over the config file.
+var envOverrides = []struct {
+	name  string
+	field func(*Config) *string
+}{
+	{"WOOH_SITE", func(c *Config) *string { return &c.Site }},
+	{"WOOH_CONSUMER_KEY", func(c *Config) *string { return &c.WooConsumerKey }},
+	{"WOOH_CONSUMER_SECRET", func(c *Config) *string { return &c.WooConsumerSecret }},
+	{"WOOH_WP_USER", func(c *Config) *string { return &c.WpUser }},
+	{"WOOH_WP_KEY", func(c *Config) *string { return &c.WpKey }},
+	{"WOOH_OPENAI_KEY", func(c *Config) *string { return &c.OpenAIKey }},
+	{"WOOH_ANTHROPIC_KEY", func(c *Config) *string { return &c.AnthropicKey }},
+}
+
+func applyEnvOverrides(conf *Config) {
+	for _, o := range envOverrides {
+		if v, ok := os.LookupEnv(o.name); ok && v != "" {
+			*o.field(conf) = v
+		}
+	}
+}
+
+// LoadDotEnv sets the variables of a .env file that are not already set in
+// the process environment. A missing file is not an error.
+func LoadDotEnv(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	vars, err := parseDotEnv(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for key, value := range vars {
+		if _, set := os.LookupEnv(key); !set {
+			os.Setenv(key, value)
+		}
+	}
+	return nil
+}
+
+// parseDotEnv reads KEY=VALUE lines. Blank lines, # comments and an
+// "export " prefix are ignored; values may be single-quoted (literal),
+// double-quoted (with \n, \" and \\ escapes) or bare, where a " #" starts a
+// trailing comment.
+func parseDotEnv(r io.Reader) (map[string]string, error) {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+
+		key, value, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", line)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated double quote", line)
+			}
+			value = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(value[1:end])
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quote", line)
+			}
+			value = value[1 : end+1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		vars[key] = value
+	}
+	return vars, scanner.Err()
+}
+
+// closingQuote returns the index of the unescaped " ending a double-quoted
+// value that starts at s[0], or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,87 @@
+package wooh
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	input := `
+# WooCommerce credentials
+WOOH_SITE=shop.example
+export WOOH_CONSUMER_KEY = ck_123
+WOOH_CONSUMER_SECRET="cs \"quoted\" #not a comment"
+WOOH_WP_KEY='abc $HOME \n #literal'
+WOOH_OPENAI_KEY=sk-test # trailing comment
+WOOH_WP_USER="line\nbreak\\"
+WOOH_EMPTY=
+WOOH_HASH=a#b
+`
+	vars, err := parseDotEnv(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"WOOH_SITE":            "shop.example",
+		"WOOH_CONSUMER_KEY":    "ck_123",
+		"WOOH_CONSUMER_SECRET": `cs "quoted" #not a comment`,
+		"WOOH_WP_KEY":          `abc $HOME \n #literal`,
+		"WOOH_OPENAI_KEY":      "sk-test",
+		"WOOH_WP_USER":         "line\nbreak\\",
+		"WOOH_EMPTY":           "",
+		"WOOH_HASH":            "a#b",
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("vars = %q\nwant %q", vars, want)
+	}
+}
+
+func TestParseDotEnvErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"WOOH_SITE", "line 1: expected KEY=VALUE"},
+		{"# comment\n=value", "line 2: expected KEY=VALUE"},
+		{`WOOH_SITE="shop`, "line 1: unterminated double quote"},
+		{`WOOH_SITE="shop\"`, "line 1: unterminated double quote"},
+		{"WOOH_SITE='shop", "line 1: unterminated single quote"},
+	}
+	for _, tt := range tests {
+		if _, err := parseDotEnv(strings.NewReader(tt.input)); err == nil || err.Error() != tt.want {
+			t.Errorf("parseDotEnv(%q) err = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestLoadDotEnvPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, ".env")
+	os.WriteFile(envPath, []byte("WOOH_SITE=dotenv.example\nWOOH_CONSUMER_KEY=ck_dotenv\n"), 0644)
+	configPath := filepath.Join(dir, "wooh.yaml")
+	os.WriteFile(configPath, []byte("site: config.example\nconsumer_key: ck_config\nconsumer_secret: cs_config\n"), 0644)
+
+	// The process environment wins over .env, which wins over the config.
+	t.Setenv("WOOH_CONSUMER_KEY", "ck_process")
+	// Setenv restores WOOH_SITE after the test, which LoadDotEnv sets.
+	t.Setenv("WOOH_SITE", "")
+	os.Unsetenv("WOOH_SITE")
+
+	if err := LoadDotEnv(envPath); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := GetConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Site != "dotenv.example" || conf.WooConsumerKey != "ck_process" || conf.WooConsumerSecret != "cs_config" {
+		t.Errorf("site %q, key %q, secret %q", conf.Site, conf.WooConsumerKey, conf.WooConsumerSecret)
+	}
+
+	if err := LoadDotEnv(filepath.Join(dir, "missing.env")); err != nil {
+		t.Errorf("missing .env = %v, want nil", err)
+	}
+}
@@ -0,0 +1,105 @@
+package wooh
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+)
+
+// SEOExportEntry is generated meta written by an export-only UpdateSEO run.
+// A reviewer sets Approved on the entries ApplySEOFromFile should write.
+type SEOExportEntry struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	FocusKW     string `json:"focuskw,omitempty"`
+	Approved    bool   `json:"approved"`
+}
+
+type seoExport struct {
+	mu      sync.Mutex
+	entries []SEOExportEntry
+}
+
+func (e *seoExport) add(u *ProductUpdate) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.entries = append(e.entries, SEOExportEntry{
+		ID:          u.Product.ID,
+		Title:       u.MetaData.YoastTitle(),
+		Description: u.MetaData.YoastDesc(),
+		FocusKW:     u.MetaData.YoastFocusKW(),
+	})
+}
+
+func (e *seoExport) write(path string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	entries := append([]SEOExportEntry{}, e.entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SEO export: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write SEO export: %w", err)
+	}
+	slog.Info("Exported generated SEO meta", "products", len(entries), "path", path)
+	return nil
+}
+
+// ApplySEOFromFile writes the approved entries of an export file to the
+// store and marks them as updated in the tracker.
+func ApplySEOFromFile(conf *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read SEO export: %w", err)
+	}
+
+	var entries []SEOExportEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse SEO export: %w", err)
+	}
+
+	var updates []map[string]interface{}
+	for _, e := range entries {
+		if !e.Approved {
+			continue
+		}
+		var meta MetaData
+		meta.Set(yoastTitleKey, e.Title)
+		meta.Set(yoastDescKey, e.Description)
+		if e.FocusKW != "" {
+			meta.Set(yoastFocusKWKey, e.FocusKW)
+		}
+		updates = append(updates, map[string]interface{}{
+			"id":        e.ID,
+			"meta_data": meta,
+		})
+	}
+
+	slog.Info("Applying approved entries", "applying", len(updates), "entries", len(entries), "path", path, "not_approved", len(entries)-len(updates))
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := BatchUpdateProducts(conf, updates); err != nil {
+		return err
+	}
+
+	trackerFilepath, err := CachePath(conf, conf.TrackerFilename)
+	if err != nil {
+		return err
+	}
+	tracker, err := TrackerLoad(trackerFilepath)
+	if err != nil {
+		return fmt.Errorf("failed to load SEO update tracker: %w", err)
+	}
+	for _, u := range updates {
+		tracker.UpdatedIDs[int(u["id"].(int64))] = true
+	}
+	return tracker.save(trackerFilepath)
+}
@@ -0,0 +1,78 @@
+package wooh
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExportOnlyRoundTrip(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Ash Board"), testProduct(3, "Pine Board"))
+	conf, _ := newTestStore(t, store.handle)
+	conf.OpenAIStub = true
+	exportPath := filepath.Join(t.TempDir(), "seo.json")
+
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true, ExportOnly: exportPath}); err != nil {
+		t.Fatal(err)
+	}
+	if written := store.written(); len(written) > 0 {
+		t.Fatalf("export-only run wrote products %v", written)
+	}
+
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []SEOExportEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("exported %d entries, want 3", len(entries))
+	}
+	for i, e := range entries {
+		if e.ID != int64(i+1) || e.Title == "" || e.Description == "" || e.Approved {
+			t.Errorf("entry %d = %+v", i, e)
+		}
+	}
+
+	// A reviewer approves products 1 and 3 and edits the title of 3.
+	entries[0].Approved = true
+	entries[2].Approved = true
+	entries[2].Title = "Pine Board | Reviewed"
+	data, _ = json.Marshal(entries)
+	if err := os.WriteFile(exportPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplySEOFromFile(conf, exportPath); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		meta := store.product(e.ID).MetaData
+		if !e.Approved {
+			if meta.YoastTitle() != "" {
+				t.Errorf("unapproved product %d was written", e.ID)
+			}
+			continue
+		}
+		if meta.YoastTitle() != e.Title || meta.YoastDesc() != e.Description || meta.YoastFocusKW() != e.FocusKW {
+			t.Errorf("product %d meta = %+v, want %+v", e.ID, meta, e)
+		}
+	}
+
+	tracker, err := TrackerLoad(mustCachePath(t, conf, conf.TrackerFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tracked []int
+	for id := range tracker.UpdatedIDs {
+		tracked = append(tracked, id)
+	}
+	slices.Sort(tracked)
+	if !slices.Equal(tracked, []int{1, 3}) {
+		t.Errorf("tracker holds %v, want [1 3]", tracked)
+	}
+}
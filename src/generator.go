@@ -0,0 +1,176 @@
+package wooh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	GeneratorOpenAI    = "openai"
+	GeneratorAnthropic = "anthropic"
+	GeneratorLocal     = "local" // any OpenAI-compatible server, e.g. Ollama or llama.cpp
+)
+
+const (
+	defaultAnthropicModel   = "claude-3-5-haiku-latest"
+	defaultAnthropicURL     = "https://api.anthropic.com/v1"
+	anthropicVersion        = "2023-06-01"
+	defaultAnthropicTokens  = 1024
+	defaultGeneratorBaseURL = "http://localhost:11434/v1"
+)
+
+// SEOGenerator writes product copy. The SEO pipeline only talks to this
+// interface, so the backend is a matter of configuration.
+type SEOGenerator interface {
+	// Meta returns the meta fields for a product, limited to maxTokens
+	// output tokens (0 for the backend default).
+	Meta(ctx context.Context, systemPrompt, userPrompt string, maxTokens int) (JSONResponse, error)
+	// Text returns free-form copy such as a description.
+	Text(ctx context.Context, systemPrompt, userPrompt string) (string, error)
+}
+
+func ValidateGeneratorBackend(backend string) error {
+	switch backend {
+	case GeneratorOpenAI, GeneratorAnthropic, GeneratorLocal:
+		return nil
+	}
+	return fmt.Errorf("unsupported generator_backend %q (allowed: %s, %s, %s)", backend, GeneratorOpenAI, GeneratorAnthropic, GeneratorLocal)
+}
+
+// newGenerator returns the generator_backend generator for conf.
+func newGenerator(conf *Config) SEOGenerator {
+	if conf.GeneratorBackend == GeneratorAnthropic {
+		return anthropicGenerator{conf: conf}
+	}
+	// local differs from openai only in newOpenAIClient's base URL.
+	return openAIGenerator{conf: conf}
+}
+
+type openAIGenerator struct{ conf *Config }
+
+func (g openAIGenerator) Meta(ctx context.Context, systemPrompt, userPrompt string, maxTokens int) (JSONResponse, error) {
+	return OpenAIProcess(ctx, g.conf, systemPrompt, userPrompt, maxTokens)
+}
+
+func (g openAIGenerator) Text(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return openAIText(ctx, g.conf, systemPrompt, userPrompt)
+}
+
+// anthropicGenerator calls the Anthropic Messages API. It has no structured
+// output mode, so the meta schema goes into the system prompt.
+type anthropicGenerator struct{ conf *Config }
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+}
+
+func (g anthropicGenerator) Meta(ctx context.Context, systemPrompt, userPrompt string, maxTokens int) (JSONResponse, error) {
+	schema, systemPrompt, err := metaSchema(g.conf, systemPrompt)
+	if err != nil {
+		return JSONResponse{}, err
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return JSONResponse{}, fmt.Errorf("failed to encode JSON schema: %w", err)
+	}
+	systemPrompt += "\nRespond with a single JSON object matching this JSON schema and nothing else:\n" + string(schemaJSON) + "\n"
+
+	content, err := g.complete(ctx, "meta", systemPrompt, userPrompt, maxTokens)
+	if err != nil {
+		return JSONResponse{}, err
+	}
+	content = stripCodeFence(content)
+	if isTruncatedJSON(content) {
+		return JSONResponse{}, fmt.Errorf("%w; raw content: %s", ErrTruncatedOutput, content)
+	}
+	return parseMetaJSON(g.conf, content)
+}
+
+func (g anthropicGenerator) Text(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	text, err := g.complete(ctx, "text", systemPrompt, userPrompt, 0)
+	if errors.Is(err, ErrTruncatedOutput) {
+		return "", fmt.Errorf("Anthropic output was truncated")
+	}
+	return text, err
+}
+
+func (g anthropicGenerator) complete(ctx context.Context, kind, systemPrompt, userPrompt string, maxTokens int) (string, error) {
+	if maxTokens <= 0 {
+		maxTokens = defaultAnthropicTokens
+	}
+	baseURL := g.conf.GeneratorBaseURL
+	if baseURL == "" {
+		baseURL = defaultAnthropicURL
+	}
+
+	// Not newClient: that one is for the store and refuses to send offline.
+	client := resty.NewWithClient(generatorHTTPClient(g.conf.ProxyURL))
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("x-api-key", g.conf.AnthropicKey).
+		SetHeader("anthropic-version", anthropicVersion).
+		SetHeader("Content-Type", "application/json").
+		SetBody(map[string]interface{}{
+			"model":       g.conf.GeneratorModel,
+			"max_tokens":  maxTokens,
+			"system":      systemPrompt,
+			"temperature": 0.7,
+			"messages":    []map[string]string{{"role": "user", "content": userPrompt}},
+		}).
+		Post(strings.TrimRight(baseURL, "/") + "/messages")
+	if err != nil {
+		writeDebugDump(g.conf, DebugDump{Kind: kind, SystemPrompt: systemPrompt, UserPrompt: userPrompt, Error: err.Error()})
+		return "", fmt.Errorf("failed to call Anthropic: %w", err)
+	}
+	if resp.IsError() {
+		writeDebugDump(g.conf, DebugDump{Kind: kind, SystemPrompt: systemPrompt, UserPrompt: userPrompt, Error: resp.String()})
+		return "", fmt.Errorf("Anthropic returned %s: %s", resp.Status(), strings.TrimSpace(resp.String()))
+	}
+
+	var result anthropicResponse
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return "", fmt.Errorf("failed to parse Anthropic response: %w", err)
+	}
+	var text strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	writeDebugDump(g.conf, DebugDump{Kind: kind, SystemPrompt: systemPrompt, UserPrompt: userPrompt, Response: text.String()})
+
+	switch result.StopReason {
+	case "refusal":
+		return "", ErrContentPolicy
+	case "max_tokens":
+		return "", fmt.Errorf("%w; raw content: %s", ErrTruncatedOutput, text.String())
+	}
+	content := strings.TrimSpace(text.String())
+	if content == "" {
+		return "", fmt.Errorf("Anthropic returned an empty response")
+	}
+	return content, nil
+}
+
+// stripCodeFence removes the Markdown code fence models sometimes put around
+// JSON despite being asked not to.
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
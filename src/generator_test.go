@@ -0,0 +1,67 @@
+package wooh
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApplyDefaultsGeneratorModel(t *testing.T) {
+	tests := []struct {
+		name string
+		conf Config
+		want string
+	}{
+		{"openai default", Config{}, defaultOpenAIModel},
+		{"anthropic default", Config{GeneratorBackend: GeneratorAnthropic}, defaultAnthropicModel},
+		{"openai_model still honoured", Config{GeneratorBackend: GeneratorAnthropic, OpenAIModel: "claude-legacy"}, "claude-legacy"},
+		{"generator_model wins", Config{GeneratorBackend: GeneratorAnthropic, OpenAIModel: "gpt-4o", GeneratorModel: "claude-sonnet"}, "claude-sonnet"},
+		{"local with generator_model", Config{GeneratorBackend: GeneratorLocal, GeneratorModel: "llama3.1"}, "llama3.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := tt.conf
+			applyDefaults(&conf)
+			if conf.GeneratorModel != tt.want {
+				t.Errorf("GeneratorModel = %q, want %q", conf.GeneratorModel, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnthropicGeneratorModel(t *testing.T) {
+	var models []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		models = append(models, body.Model)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content": [{"type": "text", "text": "Copy"}], "stop_reason": "end_turn"}`))
+	}))
+	defer srv.Close()
+
+	conf := &Config{
+		GeneratorBackend: GeneratorAnthropic,
+		GeneratorBaseURL: srv.URL,
+		OpenAIModel:      "gpt-4o-mini",
+		GeneratorModel:   "claude-sonnet",
+		CategoryModels:   map[int]string{7: "claude-opus"},
+	}
+	applyDefaults(conf)
+
+	if _, err := newGenerator(conf).Text(context.Background(), "system", "user"); err != nil {
+		t.Fatal(err)
+	}
+	withCategory := *conf
+	withCategory.GeneratorModel = ModelFor(conf, []WooCategory{{ID: 7}})
+	if _, err := newGenerator(&withCategory).Text(context.Background(), "system", "user"); err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 || models[0] != "claude-sonnet" || models[1] != "claude-opus" {
+		t.Errorf("Anthropic was sent models %v, want [claude-sonnet claude-opus]", models)
+	}
+}
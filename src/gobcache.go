@@ -0,0 +1,89 @@
+package wooh
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	CacheFormatJSON = "json"
+	CacheFormatGob  = "gob"
+)
+
+var allowedCacheFormats = []string{CacheFormatJSON, CacheFormatGob}
+
+func init() {
+	// Extra and meta values hold whatever the JSON decoder produced.
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// gobCache is the gob form of the product cache. Unlike the JSON cache it
+// stores WooProduct directly, skipping the map round trip.
+type gobCache struct {
+	Products   []WooProduct
+	LastUpdate time.Time
+}
+
+func ValidateCacheFormat(format string) error {
+	for _, allowed := range allowedCacheFormats {
+		if format == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported cache_format %q (allowed: json, gob)", format)
+}
+
+// loadGobCache returns the cached products, or nil when the cache is
+// missing or older than maxAge.
+func loadGobCache(cacheFilePath string, maxAge time.Duration) ([]WooProduct, error) {
+	cache, err := readGobCache(cacheFilePath)
+	if err != nil || cache == nil {
+		return nil, err
+	}
+	if time.Since(cache.LastUpdate) > maxAge {
+		return nil, nil
+	}
+	slog.Info("Returning products from cache", "path", cacheFilePath)
+	return cache.Products, nil
+}
+
+func saveGobCache(cacheFilePath string, products []WooProduct) {
+	if err := writeGobCache(cacheFilePath, gobCache{Products: products, LastUpdate: time.Now()}); err != nil {
+		slog.Warn("Could not save product cache", "err", err)
+	}
+}
+
+func readGobCache(cacheFilePath string) (*gobCache, error) {
+	data, err := os.ReadFile(cacheFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
+	}
+	var cache gobCache
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cache); err != nil {
+		return nil, fmt.Errorf("failed to parse cache file: %w", err)
+	}
+	return &cache, nil
+}
+
+func writeGobCache(cacheFilePath string, cache gobCache) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cache); err != nil {
+		return fmt.Errorf("could not encode cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cacheFilePath), 0755); err != nil {
+		return fmt.Errorf("could not create directory for cache file: %w", err)
+	}
+	if err := os.WriteFile(cacheFilePath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("could not save cache file: %w", err)
+	}
+	return nil
+}
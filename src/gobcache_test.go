@@ -0,0 +1,80 @@
+package wooh
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchProducts is a catalog shaped like a real store's: HTML descriptions,
+// categories, Yoast meta and custom fields.
+func benchProducts(n int) []WooProduct {
+	products := make([]WooProduct, n)
+	description := "<p>" + strings.Repeat("Solid oak board, kiln dried and oiled. ", 40) + "</p>"
+	for i := range products {
+		products[i] = WooProduct{
+			ID:               int64(i + 1),
+			Name:             fmt.Sprintf("Oak Board %d", i+1),
+			Sku:              fmt.Sprintf("OAK-%05d", i+1),
+			Description:      description,
+			ShortDescription: "<p>Solid oak board.</p>",
+			RegularPrice:     "24.99",
+			Categories:       []WooCategory{{ID: 3, Name: "Boards", Slug: "boards"}, {ID: 9, Name: "Oak", Slug: "oak"}},
+			MetaData: MetaData{
+				{Key: "_yoast_wpseo_title", Value: "Oak Board | Buy Online"},
+				{Key: "_yoast_wpseo_metadesc", Value: "Buy solid oak boards online with fast delivery."},
+			},
+			DateModifiedGMT: "2024-05-01T10:00:00",
+			Extra:           map[string]interface{}{"brand": "Acme", "dimensions": map[string]interface{}{"length": "120"}},
+			GTIN:            "4006381333931",
+		}
+	}
+	return products
+}
+
+func TestGobCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.gob")
+	products := benchProducts(3)
+	products[2].Extra = map[string]interface{}{"tags": []interface{}{"oak", "oiled"}}
+	products[1].MetaData = nil
+
+	saveGobCache(path, products)
+	got, err := loadGobCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, products) {
+		t.Errorf("round trip changed the products:\ngot  %+v\nwant %+v", got, products)
+	}
+
+	if got, err := loadGobCache(path, 0); err != nil || got != nil {
+		t.Errorf("expired cache = %v, %v; want nil", got, err)
+	}
+	if got, err := loadGobCache(filepath.Join(t.TempDir(), "missing.gob"), time.Hour); err != nil || got != nil {
+		t.Errorf("missing cache = %v, %v; want nil", got, err)
+	}
+}
+
+func benchmarkCache(b *testing.B, format string) {
+	products := benchProducts(2000)
+	cache := &fileCache{path: filepath.Join(b.TempDir(), "products."+format), format: format}
+	defer func(l *slog.Logger) { slog.SetDefault(l) }(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cache.Save(products); err != nil {
+			b.Fatal(err)
+		}
+		if _, ok, err := cache.Fetch(time.Hour); !ok || err != nil {
+			b.Fatalf("Fetch = %v, %v", ok, err)
+		}
+	}
+}
+
+func BenchmarkCacheJSON(b *testing.B) { benchmarkCache(b, CacheFormatJSON) }
+func BenchmarkCacheGob(b *testing.B)  { benchmarkCache(b, CacheFormatGob) }
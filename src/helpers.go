@@ -3,10 +3,9 @@ package wooh
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sync"
 	"time"
 
@@ -14,15 +13,80 @@ import (
 )
 
 type Config struct {
-	Site              string      `yaml:"site"`
-	OpenAIKey         string      `yaml:"openai_key"`
-	WpUser            string      `yaml:"wp_user"`
-	WpKey             string      `yaml:"wp_key"`
-	WooConsumerKey    string      `yaml:"consumer_key"`
-	WooConsumerSecret string      `yaml:"consumer_secret"`
-	CacheFilename     string      `yaml:"cache_filename"`
-	TrackerFilename   string      `yaml:"tracker_filename"`
-	ProductMeta       ProductMeta `yaml:"product_meta"`
+	Site                      string            `yaml:"site"`
+	ApiNamespace              string            `yaml:"api_namespace"`
+	AllowCustomNamespace      bool              `yaml:"allow_custom_namespace"`
+	OpenAIKey                 string            `yaml:"openai_key"`
+	WpUser                    string            `yaml:"wp_user"`
+	WpKey                     string            `yaml:"wp_key"`
+	WooConsumerKey            string            `yaml:"consumer_key"`
+	WooConsumerSecret         string            `yaml:"consumer_secret"`
+	CacheFilename             string            `yaml:"cache_filename"`
+	TrackerFilename           string            `yaml:"tracker_filename"`
+	ProductMeta               ProductMeta       `yaml:"product_meta"`
+	Markdown                  MarkdownOptions   `yaml:"markdown"`
+	DescriptionMinLength      int               `yaml:"description_min_length"`
+	ShortDescriptionMinLength int               `yaml:"short_description_min_length"`
+	FocusKeyphrase            bool              `yaml:"focus_keyphrase"`
+	CategoryPrompts           map[int]string    `yaml:"category_prompts"`
+	OrderBy                   string            `yaml:"order_by"`
+	Order                     string            `yaml:"order"`
+	CacheDir                  string            `yaml:"cache_dir"`
+	OpenAIMaxTokens           int               `yaml:"openai_max_tokens"`
+	OpenAIModel               string            `yaml:"openai_model"`
+	CategoryModels            map[int]string    `yaml:"category_models"` // primary category ID -> model
+	PageDelay                 time.Duration     `yaml:"page_delay"`
+	PageDelayJitter           time.Duration     `yaml:"page_delay_jitter"`
+	UserAgent                 string            `yaml:"user_agent"`
+	ConvertToWebP             bool              `yaml:"convert_to_webp"`
+	WebPQuality               float32           `yaml:"webp_quality"`
+	StructuredData            StructuredData    `yaml:"structured_data"`
+	VerifyWrites              bool              `yaml:"verify_writes"`
+	DebugDir                  string            `yaml:"debug_dir"`
+	KeepDebug                 int               `yaml:"keep_debug"`
+	ReadMode                  string            `yaml:"read_mode"`          // "rest" or "store" (read-only, unauthenticated)
+	OpenAIConcurrency         int               `yaml:"openai_concurrency"` // parallel OpenAI generations
+	WooConcurrency            int               `yaml:"woo_concurrency"`    // parallel WooCommerce writes
+	ProxyURL                  string            `yaml:"proxy_url"`          // http, https or socks5; empty uses HTTP(S)_PROXY
+	LogFile                   string            `yaml:"log_file"`
+	LogMaxSize                int64             `yaml:"log_max_size"` // bytes before rotating log_file
+	LogBackups                int               `yaml:"log_backups"`  // rotated files to keep; negative keeps none
+	Media                     MediaTemplates    `yaml:"media"`
+	RunStateFilename          string            `yaml:"run_state_filename"`
+	NameRules                 NameRules         `yaml:"name_rules"`
+	KeyphraseInTitle          bool              `yaml:"keyphrase_in_title"`       // retry when the title lacks the focus keyphrase
+	Profiles                  map[string]string `yaml:"profiles"`                 // name -> config path, used by --all-profiles
+	PerProductTimeout         time.Duration     `yaml:"per_product_timeout"`      // per pipeline stage; negative disables
+	CustomFields              map[string]string `yaml:"custom_fields"`            // name -> JSON path, decoded into WooProduct.Extra
+	EmptyDescriptionPolicy    string            `yaml:"empty_description_policy"` // skip, name_only or flag
+	WALFilename               string            `yaml:"wal_filename"`
+	MinDescriptionWords       int               `yaml:"min_description_words"`
+	DisableCompression        bool              `yaml:"disable_compression"`       // ask WooCommerce for uncompressed responses
+	IncludeReviews            bool              `yaml:"include_reviews"`           // add the average rating and top review to the SEO prompt
+	CacheFormat               string            `yaml:"cache_format"`              // json (default) or gob
+	TitleSuffix               string            `yaml:"title_suffix"`              // appended to every meta title, e.g. the brand
+	TitleSeparator            string            `yaml:"title_separator"`           // between the generated title and title_suffix
+	CircuitBreakerThreshold   int               `yaml:"circuit_breaker_threshold"` // consecutive store failures before pausing writes; negative disables
+	CircuitBreakerCooldown    time.Duration     `yaml:"circuit_breaker_cooldown"`  // pause before probing the store again
+	PageConcurrency           int               `yaml:"page_concurrency"`          // parallel product page fetches
+	OpenAIStub                bool              `yaml:"openai_stub"`               // generate deterministic meta locally instead of calling OpenAI
+	IgnoreIDs                 []int             `yaml:"ignore_ids"`                // products UpdateSEO never modifies, even with --force
+	SEOWriteMode              string            `yaml:"seo_write_mode"`            // "woo_meta" or "yoast_rest"
+	CacheBackend              string            `yaml:"cache_backend"`             // "file" or "memory" (no disk I/O, lasts for the process)
+	OfflineProducts           string            `yaml:"offline_products"`          // products JSON to read instead of the store; writes are only logged
+	GeneratorBackend          string            `yaml:"generator_backend"`         // "openai", "anthropic" or "local"
+	GeneratorBaseURL          string            `yaml:"generator_base_url"`        // API root for local (default Ollama's) or a proxy for anthropic
+	GeneratorModel            string            `yaml:"generator_model"`           // model for generator_backend; empty uses openai_model, then the backend default
+	AnthropicKey              string            `yaml:"anthropic_key"`
+	CategoryCacheAge          time.Duration     `yaml:"category_cache_age"`       // negative disables the category cache
+	AttributeCacheAge         time.Duration     `yaml:"attribute_cache_age"`      // negative disables the attribute cache
+	ImproveMargin             int               `yaml:"improve_margin"`           // points generated meta must gain over the current score under --only-improve
+	CompletionWebhook         string            `yaml:"completion_webhook"`       // URL that receives a RunResult when an SEO run ends
+	SkipListFilename          string            `yaml:"skip_list_filename"`       // products never to process, added from --prompt
+	HostRateLimitCooldown     time.Duration     `yaml:"host_rate_limit_cooldown"` // pause after a managed host (WP Engine, Kinsta...) rate-limits a write
+	Fields                    []string          `yaml:"fields"`                   // product fields requested with _fields; [] requests all
+	AuditLog                  string            `yaml:"audit_log"`                // JSON-lines file every SEO write is appended to
+	UseToolCalling            bool              `yaml:"use_tool_calling"`         // request meta as a function call instead of a JSON schema response
 }
 type ProductCache struct {
 	Products   []map[string]interface{} `json:"products"`
@@ -56,6 +120,15 @@ func (t *TrackerUpdate) save(trackerFilepath string) error {
 	ErrChk(err)
 	return os.WriteFile(trackerFilepath, data, 0644)
 }
+
+// markUpdated records id as done and persists the tracker. It is safe to call
+// from concurrent workers.
+func (t *TrackerUpdate) markUpdated(id int, trackerFilepath string) error {
+	t.mu.Lock()
+	t.UpdatedIDs[id] = true
+	t.mu.Unlock()
+	return t.save(trackerFilepath)
+}
 func (pc *ProductCache) FetchFromCache(cacheFilePath string, maxAge time.Duration) ([]map[string]interface{}, error) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
@@ -70,7 +143,7 @@ func (pc *ProductCache) FetchFromCache(cacheFilePath string, maxAge time.Duratio
 		return nil, fmt.Errorf("failed to parse cache file: %w", err)
 	}
 	if time.Since(pc.LastUpdate) <= maxAge {
-		log.Println("Returning products from cache...")
+		slog.Info("Returning products from cache", "path", cacheFilePath)
 		return pc.Products, nil
 	}
 	return nil, nil
@@ -84,10 +157,14 @@ func (pc *ProductCache) SaveToCache(cacheFilePath string, products []WooProduct)
 		productMap := map[string]interface{}{
 			"id":                p.ID,
 			"name":              p.Name,
+			"sku":               p.Sku,
 			"description":       p.Description,
 			"short_description": p.ShortDescription,
+			"regular_price":     p.RegularPrice,
 			"categories":        p.Categories,
 			"meta_data":         p.MetaData,
+			"date_modified_gmt": p.DateModifiedGMT,
+			"extra":             p.Extra,
 		}
 		productMaps = append(productMaps, productMap)
 	}
@@ -95,34 +172,117 @@ func (pc *ProductCache) SaveToCache(cacheFilePath string, products []WooProduct)
 	pc.Products = productMaps
 	pc.LastUpdate = time.Now()
 
+	if err := pc.write(cacheFilePath); err != nil {
+		slog.Warn("Could not save product cache", "err", err)
+	}
+}
+
+func (pc *ProductCache) write(cacheFilePath string) error {
 	data, err := json.Marshal(pc)
 	if err != nil {
-		log.Printf("Warning: could not marshal cache: %v", err)
-		return
+		return fmt.Errorf("could not marshal cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cacheFilePath), 0755); err != nil {
+		return fmt.Errorf("could not create directory for cache file: %w", err)
 	}
+	if err := os.WriteFile(cacheFilePath, data, 0644); err != nil {
+		return fmt.Errorf("could not save cache file: %w", err)
+	}
+	return nil
+}
+
+// Prune drops cached products whose IDs are not in validIDs and returns how
+// many were removed. LastUpdate is left alone.
+func (pc *ProductCache) Prune(validIDs map[int]bool) (removed int) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
 
-	dir := filepath.Dir(cacheFilePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Printf("Warning: could not create directory for cache file: %v", err)
-		return
+	kept := pc.Products[:0]
+	for _, p := range pc.Products {
+		if validIDs[cachedProductID(p)] {
+			kept = append(kept, p)
+		} else {
+			removed++
+		}
 	}
+	pc.Products = kept
+	return removed
+}
 
-	if err := os.WriteFile(cacheFilePath, data, 0644); err != nil {
-		log.Printf("Warning: could not save cache file: %v", err)
+// cachedProductID reads the ID of a cached product map, which is an int64
+// when freshly built and a float64 once read back from JSON.
+func cachedProductID(p map[string]interface{}) int {
+	switch id := p["id"].(type) {
+	case float64:
+		return int(id)
+	case int64:
+		return int(id)
+	case int:
+		return id
 	}
+	return 0
 }
 
-func Contains(strRange []string, pattern string) bool {
-	for _, val := range strRange {
-		match, _ := regexp.MatchString(pattern, val)
-		return match
+// CachePath resolves a cache or tracker filename. Absolute names are used as
+// is; relative ones are placed under conf.CacheDir, which defaults to
+// <user cache dir>/wooh. The containing directory is created if needed.
+func CachePath(conf *Config, name string) (string, error) {
+	path := name
+	if !filepath.IsAbs(name) {
+		dir := conf.CacheDir
+		if dir == "" {
+			var err error
+			if dir, err = defaultCacheDir(); err != nil {
+				return "", err
+			}
+		}
+		path = filepath.Join(dir, name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
 	}
+	return path, nil
+}
 
+// legacyCacheDir is where cache and tracker files were kept, relative to the
+// working directory, before cache_dir existed.
+const legacyCacheDir = ".wooh-output"
+
+var legacyCacheNotice sync.Once
+
+// defaultCacheDir is <user cache dir>/wooh, unless the working directory
+// still has a legacy .wooh-output: its tracker records what was already
+// updated, so it keeps being used until cache_dir says otherwise.
+func defaultCacheDir() (string, error) {
+	if info, err := os.Stat(legacyCacheDir); err == nil && info.IsDir() {
+		dir, err := filepath.Abs(legacyCacheDir)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", legacyCacheDir, err)
+		}
+		legacyCacheNotice.Do(func() {
+			slog.Warn("Using cache and tracker files from the legacy directory; set cache_dir to keep them elsewhere", "dir", dir)
+		})
+		return dir, nil
+	}
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve user cache dir: %w", err)
+	}
+	return filepath.Join(userCacheDir, "wooh"), nil
+}
+
+// Contains reports whether s is one of strRange.
+func Contains(strRange []string, s string) bool {
+	for _, val := range strRange {
+		if val == s {
+			return true
+		}
+	}
 	return false
 }
 func ErrChk(err error) {
 	if err != nil {
-		log.Fatal(err)
+		fatal(err.Error())
 	}
 }
 func Filter(arr []string, cond func(string) bool) []string {
@@ -134,15 +294,17 @@ func Filter(arr []string, cond func(string) bool) []string {
 	}
 	return result
 }
-func GetConfig(configPath string) (*Config, error) {
-	defaultConfig := &Config{
+
+// defaultConfig is the config written for a new install: placeholder
+// credentials and product fields plus every default from applyDefaults.
+func defaultConfig() *Config {
+	config := &Config{
 		Site:              "domain.com",
 		WpUser:            "user",
 		WpKey:             "",
 		WooConsumerKey:    "woo_consumer_key",
 		WooConsumerSecret: "woo_consumer_secret",
-		TrackerFilename:   "tracker-state.json",
-		CacheFilename:     "products-cache.json",
+		Markdown:          DefaultMarkdownOptions(),
 		ProductMeta: ProductMeta{
 			Type:             "simple",
 			RegularPrice:     "0.00",
@@ -153,15 +315,160 @@ func GetConfig(configPath string) (*Config, error) {
 			},
 		},
 	}
+	applyDefaults(config)
+	return config
+}
+
+// applyDefaults fills every optional field left at its zero value with its
+// default. It runs on every config read, so no caller has to repeat them.
+func applyDefaults(config *Config) {
+	if config.ApiNamespace == "" {
+		config.ApiNamespace = defaultApiNamespace
+	}
+	if config.CategoryCacheAge == 0 {
+		config.CategoryCacheAge = defaultListCacheAge
+	}
+	if config.AttributeCacheAge == 0 {
+		config.AttributeCacheAge = defaultListCacheAge
+	}
+	if config.CacheBackend == "" {
+		config.CacheBackend = CacheBackendFile
+	}
+	if config.CacheFormat == "" {
+		config.CacheFormat = CacheFormatJSON
+	}
+	if config.CacheFilename == "" {
+		config.CacheFilename = "products-cache." + config.CacheFormat
+	}
+	// A field left out keeps its default; one rendering only whitespace,
+	// e.g. caption: " ", is not sent.
+	defaultMedia := defaultMediaTemplates()
+	if config.Media.Title == "" {
+		config.Media.Title = defaultMedia.Title
+	}
+	if config.Media.Caption == "" {
+		config.Media.Caption = defaultMedia.Caption
+	}
+	if config.TrackerFilename == "" {
+		config.TrackerFilename = "tracker-state.json"
+	}
+	if config.SkipListFilename == "" {
+		config.SkipListFilename = "skip-list.json"
+	}
+	if config.RunStateFilename == "" {
+		config.RunStateFilename = "run-state.json"
+	}
+	if config.WALFilename == "" {
+		config.WALFilename = "pending-writes.jsonl"
+	}
+	if config.DescriptionMinLength == 0 {
+		config.DescriptionMinLength = defaultDescriptionMinLength
+	}
+	if config.ShortDescriptionMinLength == 0 {
+		config.ShortDescriptionMinLength = defaultShortDescriptionMinLength
+	}
+	if config.MinDescriptionWords == 0 {
+		config.MinDescriptionWords = defaultMinDescriptionWords
+	}
+	if config.Markdown.HeadingRemap == nil {
+		config.Markdown.HeadingRemap = DefaultMarkdownOptions().HeadingRemap
+	}
+	if config.OpenAIMaxTokens == 0 {
+		config.OpenAIMaxTokens = defaultOpenAIMaxTokens
+	}
+	if config.GeneratorBackend == "" {
+		config.GeneratorBackend = GeneratorOpenAI
+	}
+	if config.GeneratorBackend == GeneratorLocal && config.GeneratorBaseURL == "" {
+		config.GeneratorBaseURL = defaultGeneratorBaseURL
+	}
+	if config.GeneratorModel == "" {
+		// openai_model predates generator_model and still picks the model
+		// for any backend when generator_model is unset.
+		config.GeneratorModel = config.OpenAIModel
+	}
+	if config.GeneratorModel == "" {
+		config.GeneratorModel = defaultOpenAIModel
+		if config.GeneratorBackend == GeneratorAnthropic {
+			config.GeneratorModel = defaultAnthropicModel
+		}
+	}
+	if config.WebPQuality == 0 {
+		config.WebPQuality = defaultWebPQuality
+	}
+	if config.StructuredData.GoogleCategoryKey == "" {
+		config.StructuredData.GoogleCategoryKey = defaultGoogleCategoryKey
+	}
+	if config.StructuredData.GTINKey == "" {
+		config.StructuredData.GTINKey = defaultGTINKey
+	}
+	if config.TitleSeparator == "" {
+		config.TitleSeparator = defaultTitleSeparator
+	}
+	if config.OrderBy == "" {
+		config.OrderBy = defaultOrderBy
+	}
+	if config.Order == "" {
+		config.Order = defaultOrder
+	}
+	if config.CircuitBreakerThreshold == 0 {
+		config.CircuitBreakerThreshold = defaultCircuitBreakerThreshold
+	}
+	if config.CircuitBreakerCooldown == 0 {
+		config.CircuitBreakerCooldown = defaultCircuitBreakerCooldown
+	}
+	if config.HostRateLimitCooldown == 0 {
+		config.HostRateLimitCooldown = defaultHostRateLimitCooldown
+	}
+	if config.PerProductTimeout == 0 {
+		config.PerProductTimeout = defaultPerProductTimeout
+	}
+	if config.OpenAIConcurrency <= 0 {
+		config.OpenAIConcurrency = defaultOpenAIConcurrency
+	}
+	if config.WooConcurrency <= 0 {
+		config.WooConcurrency = defaultWooConcurrency
+	}
+	if config.PageConcurrency <= 0 {
+		config.PageConcurrency = defaultPageConcurrency
+	}
+	if config.ProductMeta.Status == "" {
+		config.ProductMeta.Status = defaultProductStatus
+	}
+	if config.EmptyDescriptionPolicy == "" {
+		config.EmptyDescriptionPolicy = EmptyDescriptionNameOnly
+	}
+	if config.ReadMode == "" {
+		config.ReadMode = ReadModeRest
+	}
+	// An explicit empty list ("fields: []") is kept and requests every field.
+	if config.Fields == nil {
+		config.Fields = defaultProductFields
+	}
+	if config.SEOWriteMode == "" {
+		config.SEOWriteMode = SEOWriteModeWooMeta
+	}
+}
+
+// GetConfig reads configPath, writing the defaults there first if it does
+// not exist, and applies WOOH_* environment overrides.
+func GetConfig(configPath string) (*Config, error) {
+	defaultConfig := defaultConfig()
 
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		if err := WriteDefaultConfig(configPath, defaultConfig); err != nil {
 			return nil, err
 		}
+		applyEnvOverrides(defaultConfig)
 		return defaultConfig, nil
 	}
 
-	return ReadConfig(configPath)
+	config, err := ReadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	applyEnvOverrides(config)
+	return config, nil
 }
 func PathExist(path string) bool {
 	_, err := os.Stat(path)
@@ -184,6 +491,51 @@ func ReadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config file: %w", err)
 	}
 
+	applyDefaults(config)
+
+	if err := ValidateGeneratorBackend(config.GeneratorBackend); err != nil {
+		return nil, err
+	}
+	if err := ValidateCacheBackend(config.CacheBackend); err != nil {
+		return nil, err
+	}
+	if err := ValidateCacheFormat(config.CacheFormat); err != nil {
+		return nil, err
+	}
+	if n := len(config.titleSuffix()); n > maxTitleLength-minTitleBudget {
+		return nil, fmt.Errorf("title_separator and title_suffix take %d of the %d meta title characters, leaving fewer than %d", n, maxTitleLength, minTitleBudget)
+	}
+	if _, err := normalizePrice(config.ProductMeta.RegularPrice, ""); err != nil {
+		return nil, fmt.Errorf("product_meta.regular_price: %w", err)
+	}
+	if err := ValidateShippingFields(config.ProductMeta); err != nil {
+		return nil, err
+	}
+	if err := ValidateProductStatus(config.ProductMeta.Status); err != nil {
+		return nil, err
+	}
+	if err := ValidateEmptyDescriptionPolicy(config.EmptyDescriptionPolicy); err != nil {
+		return nil, err
+	}
+	if err := ValidateSEOWriteMode(config.SEOWriteMode); err != nil {
+		return nil, err
+	}
+	if err := ValidateReadMode(config.ReadMode); err != nil {
+		return nil, err
+	}
+	if err := ValidateNameRules(config.NameRules); err != nil {
+		return nil, fmt.Errorf("invalid name_rules.sku_pattern: %w", err)
+	}
+	if err := ValidateProxyURL(config.ProxyURL); err != nil {
+		return nil, err
+	}
+	if err := ValidateApiNamespace(config); err != nil {
+		return nil, err
+	}
+	if err := ValidateOrdering(config); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 func WriteDefaultConfig(configPath string, defaultConfig *Config) error {
@@ -0,0 +1,432 @@
+package wooh
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// newTestStore starts a fake store served by handler and returns a config
+// pointing at it, with its own cache directory, and the number of requests
+// the store has received.
+func newTestStore(t *testing.T, handler http.HandlerFunc) (*Config, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	conf := &Config{
+		Site:              srv.URL,
+		WooConsumerKey:    "ck_test",
+		WooConsumerSecret: "cs_test",
+		CacheDir:          t.TempDir(),
+	}
+	applyDefaults(conf)
+	return conf, &requests
+}
+
+// fakeStore is an in-memory WooCommerce products endpoint. It lists,
+// fetches and updates products, one at a time or through products/batch,
+// merging meta_data entries by key, and answers anything else with an empty
+// list.
+type fakeStore struct {
+	mu       sync.Mutex
+	products map[int64]map[string]interface{}
+	writes   []int64 // IDs of updated products, in order
+}
+
+func newFakeStore(products ...map[string]interface{}) *fakeStore {
+	s := &fakeStore{products: make(map[int64]map[string]interface{})}
+	for _, p := range products {
+		s.products[int64(p["id"].(int))] = p
+	}
+	return s
+}
+
+func (s *fakeStore) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	_, rest, ok := strings.Cut(r.URL.Path, "/products")
+	if !ok || strings.HasPrefix(rest, "/reviews") || strings.HasPrefix(rest, "/categories") {
+		w.Write([]byte(`[]`))
+		return
+	}
+	if rest == "" {
+		var list []map[string]interface{}
+		if r.URL.Query().Get("page") == "" || r.URL.Query().Get("page") == "1" {
+			for _, p := range s.products {
+				list = append(list, p)
+			}
+			sort.Slice(list, func(i, j int) bool { return list[i]["id"].(int) < list[j]["id"].(int) })
+		}
+		w.Header().Set("X-WP-Total", strconv.Itoa(len(list)))
+		w.Header().Set("X-WP-TotalPages", "1")
+		json.NewEncoder(w).Encode(append([]map[string]interface{}{}, list...))
+		return
+	}
+
+	if rest == "/batch" && r.Method == http.MethodPost {
+		var body struct {
+			Update []map[string]interface{} `json:"update"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var updated []map[string]interface{}
+		for _, update := range body.Update {
+			id := int64(update["id"].(float64))
+			product, found := s.products[id]
+			if !found {
+				updated = append(updated, map[string]interface{}{"id": id, "error": map[string]interface{}{
+					"code": "woocommerce_rest_product_invalid_id", "message": "Invalid ID.",
+				}})
+				continue
+			}
+			s.apply(id, product, update)
+			updated = append(updated, product)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"update": updated})
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.Trim(rest, "/"), 10, 64)
+	product, found := s.products[id]
+	if err != nil || !found {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code": "woocommerce_rest_product_invalid_id", "message": "Invalid ID."}`))
+		return
+	}
+	if r.Method == http.MethodPut || r.Method == http.MethodPost {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.apply(id, product, body)
+	}
+	json.NewEncoder(w).Encode(product)
+}
+
+// apply writes the fields of update over product id.
+func (s *fakeStore) apply(id int64, product, update map[string]interface{}) {
+	for key, value := range update {
+		if key == "id" {
+			continue
+		}
+		if key == "meta_data" {
+			product[key] = mergeMeta(product[key], value)
+			continue
+		}
+		product[key] = value
+	}
+	s.writes = append(s.writes, id)
+}
+
+// mergeMeta sets the entries of update over those of current, by key.
+func mergeMeta(current, update interface{}) []interface{} {
+	merged, _ := current.([]interface{})
+	merged = append([]interface{}{}, merged...)
+	for _, u := range update.([]interface{}) {
+		entry := u.(map[string]interface{})
+		replaced := false
+		for i, c := range merged {
+			if c.(map[string]interface{})["key"] == entry["key"] {
+				merged[i] = entry
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, entry)
+		}
+	}
+	return merged
+}
+
+// written returns the IDs of the products updated so far.
+func (s *fakeStore) written() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64{}, s.writes...)
+}
+
+// product returns a copy of product id as the store holds it.
+func (s *fakeStore) product(id int64) WooProduct {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var p WooProduct
+	b, _ := json.Marshal(s.products[id])
+	json.Unmarshal(b, &p)
+	return p
+}
+
+// testProduct is a product with enough text for the stub generator.
+func testProduct(id int, name string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                id,
+		"name":              name,
+		"description":       "<p>Solid " + strings.ToLower(name) + ", oiled and ready to fit in any room.</p>",
+		"short_description": name,
+		"regular_price":     "10.00",
+		"meta_data":         []interface{}{},
+	}
+}
+
+func TestContains(t *testing.T) {
+	exts := []string{".jpg", ".jpeg", ".png", ".gif"}
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{".jpg", true},
+		{".png", true},
+		{".gif", true},
+		{"", false},
+		{".j.g", false},
+		{"jpg", false},
+		{".webp", false},
+	}
+	for _, tt := range tests {
+		if got := Contains(exts, tt.s); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCachePath(t *testing.T) {
+	work := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(work, "xdg"))
+	t.Setenv("HOME", work)
+	configured := filepath.Join(work, "configured")
+
+	tests := []struct {
+		name     string
+		cacheDir string
+		file     string
+		legacy   bool // a .wooh-output from before cache_dir exists
+		want     string
+	}{
+		{"relative under cache_dir", configured, "products-cache.json", false, filepath.Join(configured, "products-cache.json")},
+		{"nested relative under cache_dir", configured, "store/tracker.json", false, filepath.Join(configured, "store", "tracker.json")},
+		{"absolute kept", configured, filepath.Join(work, "abs", "tracker.json"), false, filepath.Join(work, "abs", "tracker.json")},
+		{"user cache dir by default", "", "tracker.json", false, filepath.Join(work, "xdg", "wooh", "tracker.json")},
+		{"legacy directory kept", "", "tracker.json", true, filepath.Join(work, legacyCacheDir, "tracker.json")},
+		{"cache_dir wins over legacy", configured, "tracker.json", true, filepath.Join(configured, "tracker.json")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.RemoveAll(legacyCacheDir)
+			if tt.legacy {
+				if err := os.Mkdir(legacyCacheDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := CachePath(&Config{CacheDir: tt.cacheDir}, tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("CachePath = %s, want %s", got, tt.want)
+			}
+			if _, err := os.Stat(filepath.Dir(got)); err != nil {
+				t.Errorf("directory not created: %v", err)
+			}
+		})
+	}
+}
+
+// chatRequest is the part of a chat completion request the fake generator
+// reads. openai.ChatCompletionRequest cannot be decoded: its JSON schema is
+// an interface.
+type chatRequest struct {
+	Model          string                         `json:"model"`
+	Messages       []openai.ChatCompletionMessage `json:"messages"`
+	MaxTokens      int                            `json:"max_tokens"`
+	ResponseFormat json.RawMessage                `json:"response_format"`
+	Tools          []openai.Tool                  `json:"tools"`
+}
+
+// fakeGenerator is an OpenAI-compatible chat completions endpoint that
+// answers each request with reply. use points a config at it.
+type fakeGenerator struct {
+	reply func(req chatRequest) openai.ChatCompletionChoice
+	url   string
+
+	mu       sync.Mutex
+	requests []chatRequest
+}
+
+func newFakeGenerator(t *testing.T, reply func(req chatRequest) openai.ChatCompletionChoice) *fakeGenerator {
+	t.Helper()
+	g := &fakeGenerator{reply: reply}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		g.mu.Lock()
+		g.requests = append(g.requests, req)
+		g.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{g.reply(req)}})
+	}))
+	t.Cleanup(srv.Close)
+	g.url = srv.URL
+	return g
+}
+
+// use makes conf generate through g instead of OpenAI.
+func (g *fakeGenerator) use(conf *Config) {
+	conf.OpenAIStub = false
+	conf.GeneratorBackend = GeneratorLocal
+	conf.GeneratorBaseURL = g.url
+}
+
+// sent returns the requests received so far.
+func (g *fakeGenerator) sent() []chatRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]chatRequest{}, g.requests...)
+}
+
+// textChoice is a completed reply with content.
+func textChoice(content string) openai.ChatCompletionChoice {
+	return openai.ChatCompletionChoice{
+		Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+		FinishReason: openai.FinishReasonStop,
+	}
+}
+
+// isMetaRequest reports whether req asks for meta rather than free text.
+func isMetaRequest(req chatRequest) bool {
+	return len(req.ResponseFormat) > 0 || len(req.Tools) > 0
+}
+
+// metaJSON is a meta response holding fields, e.g. meta_title.
+func metaJSON(fields map[string]string) string {
+	b, _ := json.Marshal(fields)
+	return string(b)
+}
+
+func TestReadConfigAppliesDefaults(t *testing.T) {
+	dir := t.TempDir()
+	minimal := filepath.Join(dir, "minimal.yaml")
+	os.WriteFile(minimal, []byte("site: shop.example\nconsumer_key: ck\nconsumer_secret: cs\n"), 0644)
+
+	conf, err := ReadConfig(minimal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"ApiNamespace":           defaultApiNamespace,
+		"CategoryCacheAge":       defaultListCacheAge,
+		"CacheBackend":           CacheBackendFile,
+		"CacheFilename":          "products-cache." + CacheFormatJSON,
+		"TrackerFilename":        "tracker-state.json",
+		"WALFilename":            "pending-writes.jsonl",
+		"OpenAIMaxTokens":        defaultOpenAIMaxTokens,
+		"GeneratorBackend":       GeneratorOpenAI,
+		"GeneratorModel":         defaultOpenAIModel,
+		"TitleSeparator":         defaultTitleSeparator,
+		"OrderBy":                defaultOrderBy,
+		"PerProductTimeout":      defaultPerProductTimeout,
+		"OpenAIConcurrency":      defaultOpenAIConcurrency,
+		"WooConcurrency":         defaultWooConcurrency,
+		"PageConcurrency":        defaultPageConcurrency,
+		"EmptyDescriptionPolicy": EmptyDescriptionNameOnly,
+		"ReadMode":               ReadModeRest,
+		"SEOWriteMode":           SEOWriteModeWooMeta,
+	}
+	v := reflect.ValueOf(conf).Elem()
+	for field, value := range want {
+		if got := v.FieldByName(field).Interface(); !reflect.DeepEqual(got, value) {
+			t.Errorf("%s = %v, want %v", field, got, value)
+		}
+	}
+	if conf.ProductMeta.Status != defaultProductStatus || conf.StructuredData.GTINKey != defaultGTINKey || len(conf.Fields) == 0 {
+		t.Errorf("nested defaults missing: status %q, gtin key %q, fields %v", conf.ProductMeta.Status, conf.StructuredData.GTINKey, conf.Fields)
+	}
+
+	// Defaults are only filled in, so applying them again changes nothing.
+	again := *conf
+	applyDefaults(&again)
+	if !reflect.DeepEqual(&again, conf) {
+		t.Error("applyDefaults is not idempotent")
+	}
+}
+
+func TestReadConfigKeepsExplicitValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wooh.yaml")
+	os.WriteFile(path, []byte(`site: shop.example
+cache_format: gob
+openai_concurrency: 2
+openai_model: legacy-model
+generator_backend: anthropic
+fields: []
+`), 0644)
+
+	conf, err := ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.CacheFilename != "products-cache.gob" || conf.OpenAIConcurrency != 2 {
+		t.Errorf("cache filename %q, concurrency %d", conf.CacheFilename, conf.OpenAIConcurrency)
+	}
+	// openai_model still picks the model when generator_model is unset.
+	if conf.GeneratorModel != "legacy-model" {
+		t.Errorf("generator model = %q, want legacy-model", conf.GeneratorModel)
+	}
+	if conf.Fields == nil || len(conf.Fields) != 0 {
+		t.Errorf("fields = %v, want the explicit empty list", conf.Fields)
+	}
+}
+
+func TestProductCachePrune(t *testing.T) {
+	last := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	pc := ProductCache{
+		Products: []map[string]interface{}{
+			{"id": int64(1), "name": "Oak Board"},
+			{"id": float64(2), "name": "Walnut Board"},
+			{"id": float64(3), "name": "Ash Board"},
+		},
+		LastUpdate: last,
+	}
+	if removed := pc.Prune(map[int]bool{1: true, 3: true, 4: true}); removed != 1 {
+		t.Errorf("removed %d, want 1", removed)
+	}
+	if len(pc.Products) != 2 || pc.Products[0]["name"] != "Oak Board" || pc.Products[1]["name"] != "Ash Board" {
+		t.Errorf("kept %v", pc.Products)
+	}
+	if !pc.LastUpdate.Equal(last) {
+		t.Errorf("LastUpdate changed to %s", pc.LastUpdate)
+	}
+	if removed := pc.Prune(map[int]bool{1: true, 3: true}); removed != 0 {
+		t.Errorf("second prune removed %d", removed)
+	}
+}
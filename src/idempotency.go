@@ -0,0 +1,55 @@
+package wooh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// idempotencyMetaKey holds the idempotency key of every product wooh creates.
+const idempotencyMetaKey = "_wooh_idempotency_key"
+
+// idempotencyKey derives a stable key for an operation on one source item,
+// so a rerun after a timeout can tell whether the first attempt landed.
+func idempotencyKey(operation string, parts ...string) string {
+	h := sha256.New()
+	h.Write([]byte(operation))
+	for _, p := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// findByIdempotencyKey returns the product named name that was created with
+// key, if any. The REST API cannot filter by meta, so products are looked up
+// by name and their meta checked.
+func findByIdempotencyKey(client *resty.Client, conf *Config, name, key string) (WooProduct, bool, error) {
+	resp, err := client.R().
+		SetHeader("Accept", "application/json").
+		SetQueryParams(map[string]string{
+			"search":   name,
+			"status":   "any",
+			"per_page": fmt.Sprintf("%d", batchLimit),
+		}).
+		Get(wooEndpoint(conf, "products"))
+	if err != nil {
+		return WooProduct{}, false, fmt.Errorf("failed to look up existing products: %w", err)
+	}
+	if resp.IsError() {
+		return WooProduct{}, false, fmt.Errorf("error looking up existing products: %w", apiError(resp))
+	}
+
+	var products []WooProduct
+	if err := decodeJSON(resp, &products); err != nil {
+		return WooProduct{}, false, fmt.Errorf("failed to parse existing products: %w", err)
+	}
+	for _, p := range products {
+		if p.MetaData.Get(idempotencyMetaKey) == key {
+			return p, true, nil
+		}
+	}
+	return WooProduct{}, false, nil
+}
@@ -0,0 +1,108 @@
+package wooh
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	key := idempotencyKey("create-product", "oak board", "oak-board.jpg")
+	if len(key) != 32 || key != idempotencyKey("create-product", "oak board", "oak-board.jpg") {
+		t.Errorf("key %q is not stable", key)
+	}
+	for _, other := range []string{
+		idempotencyKey("update-product", "oak board", "oak-board.jpg"),
+		idempotencyKey("create-product", "oak board", "oak-board.png"),
+		idempotencyKey("create-product", "oak boar", "doak-board.jpg"),
+	} {
+		if other == key {
+			t.Errorf("different inputs share the key %q", key)
+		}
+	}
+}
+
+// createdStore keeps the products created through it and finds them again
+// by search, as WooCommerce does, recording each create's Idempotency-Key.
+type createdStore struct {
+	mu       sync.Mutex
+	products []map[string]interface{}
+	headers  []string
+	media    int
+}
+
+func (s *createdStore) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/wp/v2/media"):
+		s.media++
+		fmt.Fprintf(w, `{"id": %d, "source_url": "http://img/%d.jpg"}`, 100+s.media, s.media)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/products"):
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		body["id"] = 200 + len(s.products)
+		s.products = append(s.products, body)
+		s.headers = append(s.headers, r.Header.Get("Idempotency-Key"))
+		json.NewEncoder(w).Encode(body)
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/products"):
+		found := []map[string]interface{}{}
+		search := strings.ToLower(r.URL.Query().Get("search"))
+		for _, p := range s.products {
+			if search != "" && strings.Contains(strings.ToLower(p["name"].(string)), search) {
+				found = append(found, p)
+			}
+		}
+		json.NewEncoder(w).Encode(found)
+	default:
+		fmt.Fprint(w, `[]`)
+	}
+}
+
+func TestUploadRetryDoesNotDuplicate(t *testing.T) {
+	store := &createdStore{}
+	conf, _ := newTestStore(t, store.handle)
+	conf.ProductMeta.RegularPrice = "10.00"
+	conf.ProductMeta.SkuPrefix = "OAK-"
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "oak-board.jpg"), []byte("x"), 0644)
+
+	first, err := UploadImageToWordPress(conf, dir, UploadOptions{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The rerun after an apparent failure finds the product already made.
+	again, err := UploadImageToWordPress(conf, dir, UploadOptions{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.products) != 1 || store.media != 1 {
+		t.Fatalf("created %d products and %d media, want 1 each", len(store.products), store.media)
+	}
+	if len(first) != 1 || len(again) != 1 || again[0].ID != first[0].ID || again[0].Sku != "OAK-001" {
+		t.Errorf("first run %+v, rerun %+v", first, again)
+	}
+
+	key := idempotencyKey("create-product", conf.NameRules.normalize("oak-board"), "oak-board.jpg")
+	meta, _ := json.Marshal(store.products[0]["meta_data"])
+	if store.headers[0] != key || !strings.Contains(string(meta), key) {
+		t.Errorf("create sent header %q and meta %s, want key %q in both", store.headers[0], meta, key)
+	}
+
+	// A product with the same name but another key is not mistaken for it.
+	os.WriteFile(filepath.Join(dir, "oak-board.png"), []byte("x"), 0644)
+	os.Remove(filepath.Join(dir, "oak-board.jpg"))
+	if _, err := UploadImageToWordPress(conf, dir, UploadOptions{Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.products) != 2 {
+		t.Errorf("created %d products, want a second one for the new file", len(store.products))
+	}
+}
@@ -0,0 +1,96 @@
+package wooh
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxImageBytes is the size above which an image is flagged as oversized;
+// most WordPress hosts reject uploads well before this.
+const maxImageBytes = 20 << 20
+
+var imageExtensions = []string{".jpg", ".jpeg", ".png", ".gif"}
+
+// ImageIssue describes one image in a directory. Problem is empty when the
+// file decoded cleanly and is within limits.
+type ImageIssue struct {
+	Path    string
+	Size    int64
+	Width   int
+	Height  int
+	Problem string
+}
+
+func (i ImageIssue) OK() bool {
+	return i.Problem == ""
+}
+
+// ValidateImages checks every image in dir decodes, and flags zero-byte,
+// corrupt and oversized files. One entry is returned per image.
+func ValidateImages(dir string) ([]ImageIssue, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var issues []ImageIssue
+	for _, file := range files {
+		if file.IsDir() || !isImageFile(file.Name()) {
+			continue
+		}
+		issues = append(issues, validateImage(filepath.Join(dir, file.Name())))
+	}
+	return issues, nil
+}
+
+func isImageFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range imageExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func validateImage(path string) ImageIssue {
+	issue := ImageIssue{Path: path}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		issue.Problem = err.Error()
+		return issue
+	}
+	issue.Size = info.Size()
+	if issue.Size == 0 {
+		issue.Problem = "zero-byte file"
+		return issue
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		issue.Problem = err.Error()
+		return issue
+	}
+	defer f.Close()
+
+	// Decode the whole image rather than just the header so truncated
+	// files are caught too.
+	img, _, err := image.Decode(f)
+	if err != nil {
+		issue.Problem = fmt.Sprintf("corrupt: %v", err)
+		return issue
+	}
+	issue.Width, issue.Height = img.Bounds().Dx(), img.Bounds().Dy()
+
+	if issue.Size > maxImageBytes {
+		issue.Problem = fmt.Sprintf("oversized: %d bytes (max %d)", issue.Size, maxImageBytes)
+	}
+	return issue
+}
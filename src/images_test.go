@@ -0,0 +1,68 @@
+package wooh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateImages(t *testing.T) {
+	dir := t.TempDir()
+	writeJPEG(t, filepath.Join(dir, "valid.jpg"))
+	writeJPEG(t, filepath.Join(dir, "upper.JPEG"))
+
+	valid, err := os.ReadFile(filepath.Join(dir, "valid.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"truncated.jpg": valid[:len(valid)/2],
+		"garbage.png":   []byte("not an image"),
+		"empty.gif":     nil,
+		"notes.txt":     []byte("skipped"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested.jpg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := ValidateImages(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{ // file -> problem prefix
+		"valid.jpg":     "",
+		"upper.JPEG":    "",
+		"truncated.jpg": "corrupt",
+		"garbage.png":   "corrupt",
+		"empty.gif":     "zero-byte",
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(issues), len(want), issues)
+	}
+	for _, issue := range issues {
+		name := filepath.Base(issue.Path)
+		prefix, ok := want[name]
+		if !ok {
+			t.Errorf("unexpected result for %s", name)
+			continue
+		}
+		if !strings.HasPrefix(issue.Problem, prefix) || (prefix == "") != issue.OK() {
+			t.Errorf("%s: problem %q, want prefix %q", name, issue.Problem, prefix)
+		}
+		if issue.OK() && (issue.Width != 32 || issue.Height != 32 || issue.Size == 0) {
+			t.Errorf("%s: got %dx%d, %d bytes", name, issue.Width, issue.Height, issue.Size)
+		}
+	}
+}
+
+func TestValidateImagesMissingDir(t *testing.T) {
+	if _, err := ValidateImages(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("ValidateImages on a missing directory = nil error")
+	}
+}
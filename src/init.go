@@ -0,0 +1,105 @@
+package wooh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+type InitAnswers struct {
+	Site              string
+	WooConsumerKey    string
+	WooConsumerSecret string
+	WpUser            string
+	WpKey             string
+	OpenAIKey         string
+}
+
+func ValidateInitAnswers(a InitAnswers) error {
+	site := strings.TrimSpace(a.Site)
+	if site == "" {
+		return fmt.Errorf("site is required")
+	}
+	if strings.ContainsAny(site, " \t") {
+		return fmt.Errorf("site %q must not contain whitespace", site)
+	}
+	if !strings.HasPrefix(a.WooConsumerKey, "ck_") {
+		return fmt.Errorf("consumer key must start with \"ck_\"")
+	}
+	if !strings.HasPrefix(a.WooConsumerSecret, "cs_") {
+		return fmt.Errorf("consumer secret must start with \"cs_\"")
+	}
+	if a.OpenAIKey != "" && !strings.HasPrefix(a.OpenAIKey, "sk-") {
+		return fmt.Errorf("OpenAI key must start with \"sk-\"")
+	}
+	return nil
+}
+
+// PromptInitAnswers asks for each config value on out and reads the answers
+// from in. Secrets are read without echo when in is a terminal.
+func PromptInitAnswers(in io.Reader, out io.Writer) (InitAnswers, error) {
+	var a InitAnswers
+	reader := bufio.NewReader(in)
+
+	fields := []struct {
+		label  string
+		dst    *string
+		secret bool
+	}{
+		{"Site (e.g. example.com or example.com/shop)", &a.Site, false},
+		{"WooCommerce consumer key", &a.WooConsumerKey, true},
+		{"WooCommerce consumer secret", &a.WooConsumerSecret, true},
+		{"WordPress user", &a.WpUser, false},
+		{"WordPress application password", &a.WpKey, true},
+		{"OpenAI key (optional)", &a.OpenAIKey, true},
+	}
+
+	for _, f := range fields {
+		fmt.Fprintf(out, "%s: ", f.label)
+		value, err := readInitValue(in, reader, f.secret)
+		if err != nil {
+			return a, fmt.Errorf("failed to read %s: %w", f.label, err)
+		}
+		if f.secret {
+			fmt.Fprintln(out)
+		}
+		*f.dst = value
+	}
+
+	return a, nil
+}
+
+func readInitValue(in io.Reader, reader *bufio.Reader, secret bool) (string, error) {
+	if f, ok := in.(*os.File); ok && secret && term.IsTerminal(int(f.Fd())) {
+		b, err := term.ReadPassword(int(f.Fd()))
+		return strings.TrimSpace(string(b)), err
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func WriteInitConfig(configPath string, a InitAnswers, overwrite bool) error {
+	if err := ValidateInitAnswers(a); err != nil {
+		return err
+	}
+	if PathExist(configPath) && !overwrite {
+		return fmt.Errorf("config file %s already exists; use --force to overwrite", configPath)
+	}
+
+	conf := defaultConfig()
+	conf.Site = strings.TrimSpace(a.Site)
+	conf.WooConsumerKey = a.WooConsumerKey
+	conf.WooConsumerSecret = a.WooConsumerSecret
+	conf.WpUser = a.WpUser
+	conf.WpKey = a.WpKey
+	conf.OpenAIKey = a.OpenAIKey
+
+	return WriteDefaultConfig(configPath, conf)
+}
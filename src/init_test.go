@@ -0,0 +1,74 @@
+package wooh
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateInitAnswers(t *testing.T) {
+	valid := InitAnswers{Site: "example.com/shop", WooConsumerKey: "ck_1", WooConsumerSecret: "cs_1"}
+	tests := []struct {
+		name    string
+		edit    func(*InitAnswers)
+		wantErr string
+	}{
+		{"valid", func(*InitAnswers) {}, ""},
+		{"valid with OpenAI key", func(a *InitAnswers) { a.OpenAIKey = "sk-1" }, ""},
+		{"no site", func(a *InitAnswers) { a.Site = "  " }, "site is required"},
+		{"site with spaces", func(a *InitAnswers) { a.Site = "example .com" }, "whitespace"},
+		{"bad consumer key", func(a *InitAnswers) { a.WooConsumerKey = "1" }, "consumer key"},
+		{"bad consumer secret", func(a *InitAnswers) { a.WooConsumerSecret = "1" }, "consumer secret"},
+		{"bad OpenAI key", func(a *InitAnswers) { a.OpenAIKey = "key" }, "OpenAI key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := valid
+			tt.edit(&a)
+			err := ValidateInitAnswers(a)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ValidateInitAnswers = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInitConfig(t *testing.T) {
+	in := strings.NewReader("example.com/shop\nck_abc\ncs_def\nadmin\nxxxx yyyy\n\n")
+	var out bytes.Buffer
+	answers, err := PromptInitAnswers(in, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := InitAnswers{Site: "example.com/shop", WooConsumerKey: "ck_abc", WooConsumerSecret: "cs_def", WpUser: "admin", WpKey: "xxxx yyyy"}
+	if answers != want {
+		t.Errorf("answers = %+v, want %+v", answers, want)
+	}
+	if !strings.Contains(out.String(), "WooCommerce consumer key: ") {
+		t.Errorf("prompts = %q", out.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "wooh.yaml")
+	if err := WriteInitConfig(path, answers, false); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Site != "example.com/shop" || conf.WooConsumerKey != "ck_abc" || conf.WpKey != "xxxx yyyy" || conf.ApiNamespace != defaultApiNamespace {
+		t.Errorf("config read back = %+v", conf)
+	}
+
+	if err := WriteInitConfig(path, answers, false); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("second init = %v, want an already exists error", err)
+	}
+	answers.Site = "example.org"
+	if err := WriteInitConfig(path, answers, true); err != nil {
+		t.Fatal(err)
+	}
+	if conf, err := ReadConfig(path); err != nil || conf.Site != "example.org" {
+		t.Errorf("overwritten config site = %v, %v", conf, err)
+	}
+}
@@ -0,0 +1,91 @@
+package wooh
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	categoriesCacheFilename = "categories-cache.json"
+	attributesCacheFilename = "attributes-cache.json"
+	defaultListCacheAge     = 24 * time.Hour
+)
+
+// listCacheFile is the on-disk form of a cached lookup list such as the
+// store's categories.
+type listCacheFile[T any] struct {
+	Items      []T       `json:"items"`
+	LastUpdate time.Time `json:"last_update"`
+}
+
+// memoryList is the cache_backend memory form of listCacheFile.
+type memoryList[T any] struct {
+	mu    sync.Mutex
+	cache listCacheFile[T]
+}
+
+// cachedList returns the list cached under name when it is younger than
+// maxAge, and otherwise calls fetch and caches its result. It follows
+// cache_backend like the product cache; a negative maxAge disables caching.
+func cachedList[T any](conf *Config, name string, maxAge time.Duration, fetch func() ([]T, error)) ([]T, error) {
+	if maxAge < 0 {
+		return fetch()
+	}
+	path, err := CachePath(conf, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.CacheBackend == CacheBackendMemory {
+		v, _ := caches.LoadOrStore(CacheBackendMemory+":"+path, &memoryList[T]{})
+		mem := v.(*memoryList[T])
+		mem.mu.Lock()
+		defer mem.mu.Unlock()
+		if mem.cache.Items != nil && time.Since(mem.cache.LastUpdate) <= maxAge {
+			return mem.cache.Items, nil
+		}
+		items, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		mem.cache = listCacheFile[T]{Items: items, LastUpdate: time.Now()}
+		return items, nil
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		var cached listCacheFile[T]
+		if err := json.Unmarshal(data, &cached); err != nil {
+			slog.Warn("Ignoring unreadable cache", "path", path, "err", err)
+		} else if cached.Items != nil && time.Since(cached.LastUpdate) <= maxAge {
+			return cached.Items, nil
+		}
+	}
+
+	items, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeListCache(path, listCacheFile[T]{Items: items, LastUpdate: time.Now()}); err != nil {
+		slog.Warn("Could not save cache", "path", path, "err", err)
+	}
+	return items, nil
+}
+
+func writeListCache[T any](path string, cache listCacheFile[T]) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("could not marshal cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create directory for cache file: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("could not save cache file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package wooh
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// listStore serves one category and one attribute, counting the requests
+// for each.
+type listStore struct {
+	categories, attributes atomic.Int32
+}
+
+func (s *listStore) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/products/categories"):
+		s.categories.Add(1)
+		w.Write([]byte(`[{"id": 9, "name": "Boards", "slug": "boards"}]`))
+	case strings.HasSuffix(r.URL.Path, "/products/attributes"):
+		s.attributes.Add(1)
+		w.Write([]byte(`[{"id": 1, "name": "Colour", "slug": "pa_colour"}]`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestGetCategoriesCache(t *testing.T) {
+	t.Run("hit and expiry", func(t *testing.T) {
+		store := &listStore{}
+		conf, _ := newTestStore(t, store.handle)
+		conf.CategoryCacheAge = time.Hour
+
+		for i := 0; i < 3; i++ {
+			categories, err := GetCategories(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(categories) != 1 || categories[0].Name != "Boards" {
+				t.Fatalf("categories = %+v", categories)
+			}
+		}
+		if n := store.categories.Load(); n != 1 {
+			t.Errorf("fetched categories %d times, want 1", n)
+		}
+
+		// An entry older than category_cache_age is fetched again.
+		path := mustCachePath(t, conf, categoriesCacheFilename)
+		writeListCache(path, listCacheFile[WooCategory]{Items: []WooCategory{{ID: 9}}, LastUpdate: time.Now().Add(-2 * time.Hour)})
+		if categories, _ := GetCategories(conf); categories[0].Name != "Boards" || store.categories.Load() != 2 {
+			t.Errorf("stale cache served %+v after %d fetches", categories, store.categories.Load())
+		}
+
+		// So is an unreadable one.
+		os.WriteFile(path, []byte("{"), 0644)
+		if _, err := GetCategories(conf); err != nil || store.categories.Load() != 3 {
+			t.Errorf("unreadable cache: %v after %d fetches", err, store.categories.Load())
+		}
+	})
+
+	t.Run("negative age disables it", func(t *testing.T) {
+		store := &listStore{}
+		conf, _ := newTestStore(t, store.handle)
+		conf.CategoryCacheAge = -1
+		GetCategories(conf)
+		GetCategories(conf)
+		if n := store.categories.Load(); n != 2 {
+			t.Errorf("fetched categories %d times, want 2", n)
+		}
+		if _, err := os.Stat(mustCachePath(t, conf, categoriesCacheFilename)); err == nil {
+			t.Error("disabled cache was written")
+		}
+	})
+
+	t.Run("memory backend", func(t *testing.T) {
+		store := &listStore{}
+		conf, _ := newTestStore(t, store.handle)
+		conf.CacheBackend = CacheBackendMemory
+		GetCategories(conf)
+		GetCategories(conf)
+		if n := store.categories.Load(); n != 1 {
+			t.Errorf("fetched categories %d times, want 1", n)
+		}
+		if _, err := os.Stat(mustCachePath(t, conf, categoriesCacheFilename)); err == nil {
+			t.Error("memory backend wrote a cache file")
+		}
+	})
+}
+
+func TestListCachesKeepTheirOwnAges(t *testing.T) {
+	store := &listStore{}
+	conf, _ := newTestStore(t, store.handle)
+	conf.CategoryCacheAge = time.Hour
+	conf.AttributeCacheAge = -1
+
+	for i := 0; i < 2; i++ {
+		if _, err := GetCategories(conf); err != nil {
+			t.Fatal(err)
+		}
+		attributes, err := GetAttributes(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(attributes) != 1 || attributes[0].Slug != "pa_colour" {
+			t.Fatalf("attributes = %+v", attributes)
+		}
+	}
+	if store.categories.Load() != 1 || store.attributes.Load() != 2 {
+		t.Errorf("fetched categories %d and attributes %d times, want 1 and 2", store.categories.Load(), store.attributes.Load())
+	}
+}
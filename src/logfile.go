@@ -0,0 +1,195 @@
+package wooh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+const (
+	defaultLogMaxSize = 10 << 20 // bytes
+	defaultLogBackups = 3
+)
+
+// rotatingFile is an io.Writer that appends to path and, once the file would
+// grow past maxSize, renames it to path.1 (shifting older backups up to
+// path.<backups>) and starts a fresh file.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	f       *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	if r.backups > 0 {
+		for i := r.backups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	} else if err := os.Truncate(r.path, 0); err != nil {
+		return fmt.Errorf("failed to truncate log file: %w", err)
+	}
+	return r.open()
+}
+
+// SetupLogFile tees the default logger to conf.LogFile as JSON lines,
+// keeping stderr output. It is a no-op when no log file is configured.
+func SetupLogFile(conf *Config) error {
+	if conf.LogFile == "" {
+		return nil
+	}
+	maxSize := conf.LogMaxSize
+	if maxSize <= 0 {
+		maxSize = defaultLogMaxSize
+	}
+	backups := conf.LogBackups
+	if backups == 0 {
+		backups = defaultLogBackups
+	}
+	f, err := openRotatingFile(conf.LogFile, maxSize, backups)
+	if err != nil {
+		return err
+	}
+	file := slog.NewJSONHandler(f, &slog.HandlerOptions{Level: fileLogLevel()})
+	slog.SetDefault(slog.New(teeHandler{consoleHandler(os.Stderr), file}))
+	return nil
+}
+
+// fatal logs msg and args as an error and exits, for failures the CLI
+// cannot go on from.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// setupConsoleLog makes the default logger write to stderr only.
+func setupConsoleLog() {
+	slog.SetDefault(slog.New(consoleHandler(os.Stderr)))
+}
+
+// consoleLevel is the least severe level written to the console: info,
+// warn under --quiet or debug under --trace.
+var consoleLevel = new(slog.LevelVar)
+
+// setConsoleLevel sets consoleLevel from the --quiet and --trace flags.
+// Trace wins, since asking for request timings means wanting to see them.
+func setConsoleLevel(quiet, trace bool) {
+	switch {
+	case trace:
+		consoleLevel.Set(slog.LevelDebug)
+	case quiet:
+		consoleLevel.Set(slog.LevelWarn)
+	default:
+		consoleLevel.Set(slog.LevelInfo)
+	}
+}
+
+// fileLogLevel is the least severe level written to log_file: info, or
+// debug under --trace. --quiet only quiets the console.
+func fileLogLevel() slog.Level {
+	return min(consoleLevel.Level(), slog.LevelInfo)
+}
+
+// consoleHandler writes records to w as text lines, timestamped to the
+// second.
+func consoleHandler(w io.Writer) slog.Handler {
+	return slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: consoleLevel,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				a.Value = slog.StringValue(a.Value.Time().Format("2006-01-02T15:04:05"))
+			}
+			return a
+		},
+	})
+}
+
+// teeHandler sends each record to every handler that takes its level.
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// quietLogs suppresses progress output; set by --quiet, which also raises
+// consoleLevel to warn.
+var quietLogs bool
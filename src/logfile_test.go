@@ -0,0 +1,155 @@
+package wooh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// restoreLogger puts back the default logger when the test ends.
+func restoreLogger(t *testing.T) {
+	t.Helper()
+	logger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(logger) })
+}
+
+func TestSetupLogFile(t *testing.T) {
+	restoreLogger(t)
+	path := filepath.Join(t.TempDir(), "wooh.log")
+	if err := SetupLogFile(&Config{LogFile: path}); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("Updated SEO", "product_id", 7)
+	slog.Warn("Could not save skip list", "err", "disk full")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log file has %d lines, want 2:\n%s", len(lines), data)
+	}
+	var entry struct {
+		Level     string `json:"level"`
+		Msg       string `json:"msg"`
+		ProductID int    `json:"product_id"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if entry.Level != "INFO" || entry.Msg != "Updated SEO" || entry.ProductID != 7 {
+		t.Errorf("log entry = %+v", entry)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		backups     int
+		writes      int
+		wantBackups []string
+	}{
+		{"under the limit", 3, 2, nil},
+		{"rotates past the limit", 3, 3, []string{".1"}},
+		{"shifts older backups", 3, 8, []string{".1", ".2", ".3"}},
+		{"no backups truncates", 0, 8, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wooh.log")
+			f, err := openRotatingFile(path, 50, tt.backups)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tt.writes; i++ {
+				fmt.Fprintf(f, "line %d of the log file\n", i) // 23 bytes, two per file
+			}
+			f.f.Close()
+
+			last, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := fmt.Sprintf("line %d of", tt.writes-1); !bytes.Contains(last, []byte(want)) {
+				t.Errorf("current file = %q, want the last line", last)
+			}
+			var backups []string
+			for _, suffix := range []string{".1", ".2", ".3", ".4"} {
+				if _, err := os.Stat(path + suffix); err == nil {
+					backups = append(backups, suffix)
+				}
+			}
+			if fmt.Sprint(backups) != fmt.Sprint(tt.wantBackups) {
+				t.Errorf("backups = %v, want %v", backups, tt.wantBackups)
+			}
+		})
+	}
+}
+
+func TestConsoleLevel(t *testing.T) {
+	restoreLogger(t)
+	t.Cleanup(func() { setConsoleLevel(false, false) })
+
+	tests := []struct {
+		name         string
+		quiet, trace bool
+		wantConsole  []string
+		wantFile     []string
+	}{
+		{
+			name:        "default",
+			wantConsole: []string{"Updated SEO", "Could not save skip list"},
+			wantFile:    []string{"Updated SEO", "Could not save skip list"},
+		},
+		{
+			name:        "quiet keeps warnings on the console only",
+			quiet:       true,
+			wantConsole: []string{"Could not save skip list"},
+			wantFile:    []string{"Updated SEO", "Could not save skip list"},
+		},
+		{
+			name:        "trace",
+			trace:       true,
+			wantConsole: []string{"Request timings", "Updated SEO", "Could not save skip list"},
+			wantFile:    []string{"Request timings", "Updated SEO", "Could not save skip list"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConsoleLevel(tt.quiet, tt.trace)
+			var console, file bytes.Buffer
+			slog.SetDefault(slog.New(teeHandler{
+				consoleHandler(&console),
+				slog.NewJSONHandler(&file, &slog.HandlerOptions{Level: fileLogLevel()}),
+			}))
+
+			slog.Debug("Request timings", "status", 200)
+			// An info line that mentions failure is still an info line.
+			slog.Info("Updated SEO", "product", "Fail-safe Hinge")
+			slog.Warn("Could not save skip list", "err", "disk full")
+
+			for _, out := range []struct {
+				name string
+				got  string
+				want []string
+			}{{"console", console.String(), tt.wantConsole}, {"file", file.String(), tt.wantFile}} {
+				lines := strings.Split(strings.TrimSpace(out.got), "\n")
+				if len(lines) != len(out.want) {
+					t.Errorf("%s has %d lines, want %d:\n%s", out.name, len(lines), len(out.want), out.got)
+					continue
+				}
+				for i, msg := range out.want {
+					if !strings.Contains(lines[i], msg) {
+						t.Errorf("%s line %d = %q, want %q", out.name, i, lines[i], msg)
+					}
+				}
+			}
+		})
+	}
+}
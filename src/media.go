@@ -0,0 +1,75 @@
+package wooh
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	defaultMediaTitle   = "{{.ProductName}}"
+	defaultMediaCaption = "{{.Config.ProductMeta.Description}}"
+)
+
+// MediaTemplates are Go templates for the metadata of uploaded images,
+// rendered with MediaTemplateData. Fields that render empty are not sent.
+type MediaTemplates struct {
+	Title       string `yaml:"title"`
+	Caption     string `yaml:"caption"`
+	Alt         string `yaml:"alt"`
+	Description string `yaml:"description"`
+}
+
+type MediaTemplateData struct {
+	ProductName string // file name without extension
+	FileName    string
+	Sku         string
+	Config      *Config
+}
+
+func defaultMediaTemplates() MediaTemplates {
+	return MediaTemplates{Title: defaultMediaTitle, Caption: defaultMediaCaption}
+}
+
+// deleteMedia permanently deletes the media attachment id, skipping the
+// trash, which attachments do not support.
+func deleteMedia(client *resty.Client, conf *Config, id int64) error {
+	resp, err := client.R().
+		SetBasicAuth(conf.WpUser, conf.WpKey).
+		SetQueryParam("force", "true").
+		Delete(wpEndpoint(conf, fmt.Sprintf("wp/v2/media/%d", id)))
+	if err != nil {
+		return fmt.Errorf("failed to delete media %d: %w", id, err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("error deleting media %d: %w", id, apiError(resp))
+	}
+	return nil
+}
+
+// RenderMediaFields renders t into the form fields of a wp/v2/media upload.
+func RenderMediaFields(t MediaTemplates, data MediaTemplateData) (map[string]string, error) {
+	fields := make(map[string]string)
+	for _, f := range []struct{ field, text string }{
+		{"title", t.Title},
+		{"caption", t.Caption},
+		{"alt_text", t.Alt},
+		{"description", t.Description},
+	} {
+		tmpl, err := template.New(f.field).Option("missingkey=error").Parse(f.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid media %s template: %w", f.field, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render media %s template: %w", f.field, err)
+		}
+		if value := strings.TrimSpace(buf.String()); value != "" {
+			fields[f.field] = value
+		}
+	}
+	return fields, nil
+}
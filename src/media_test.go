@@ -0,0 +1,72 @@
+package wooh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenderMediaFields(t *testing.T) {
+	conf := &Config{ProductMeta: ProductMeta{Description: "Solid oak flooring"}}
+	data := MediaTemplateData{ProductName: "Oak Plank", FileName: "oak_plank.jpg", Sku: "OAK-001", Config: conf}
+
+	tests := []struct {
+		name      string
+		templates MediaTemplates
+		want      map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "defaults",
+			templates: defaultMediaTemplates(),
+			want:      map[string]string{"title": "Oak Plank", "caption": "Solid oak flooring"},
+		},
+		{
+			name: "every field",
+			templates: MediaTemplates{
+				Title:       "{{.ProductName}} ({{.Sku}})",
+				Caption:     "{{.Config.ProductMeta.Description}}",
+				Alt:         "Photo of {{.ProductName}}",
+				Description: "Uploaded from {{.FileName}}",
+			},
+			want: map[string]string{
+				"title":       "Oak Plank (OAK-001)",
+				"caption":     "Solid oak flooring",
+				"alt_text":    "Photo of Oak Plank",
+				"description": "Uploaded from oak_plank.jpg",
+			},
+		},
+		{
+			name:      "blank fields are not sent",
+			templates: MediaTemplates{Title: "{{.ProductName}}", Caption: " "},
+			want:      map[string]string{"title": "Oak Plank"},
+		},
+		{name: "unknown field", templates: MediaTemplates{Title: "{{.Colour}}"}, wantErr: true},
+		{name: "bad syntax", templates: MediaTemplates{Alt: "{{.ProductName"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderMediaFields(tt.templates, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsMediaTemplates(t *testing.T) {
+	conf := &Config{Media: MediaTemplates{Alt: "{{.ProductName}}"}}
+	applyDefaults(conf)
+	want := MediaTemplates{Title: defaultMediaTitle, Caption: defaultMediaCaption, Alt: "{{.ProductName}}"}
+	if conf.Media != want {
+		t.Errorf("media templates = %+v, want %+v", conf.Media, want)
+	}
+
+	conf = &Config{Media: MediaTemplates{Title: "{{.Sku}}"}}
+	applyDefaults(conf)
+	if conf.Media.Title != "{{.Sku}}" {
+		t.Errorf("configured title replaced by %q", conf.Media.Title)
+	}
+}
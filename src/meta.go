@@ -0,0 +1,48 @@
+package wooh
+
+import "fmt"
+
+const (
+	yoastTitleKey   = "_yoast_wpseo_title"
+	yoastDescKey    = "_yoast_wpseo_metadesc"
+	yoastFocusKWKey = "_yoast_wpseo_focuskw"
+)
+
+// MetaEntry is one item of a product's meta_data. ID is set for entries
+// read from the store and omitted for new ones.
+type MetaEntry struct {
+	ID    int64       `json:"id,omitempty"`
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+type MetaData []MetaEntry
+
+// Get returns the value of key as a string, or "" if it is unset.
+func (m MetaData) Get(key string) string {
+	for _, entry := range m {
+		if entry.Key == key {
+			if v, ok := entry.Value.(string); ok {
+				return v
+			}
+			return fmt.Sprintf("%v", entry.Value)
+		}
+	}
+	return ""
+}
+
+// Set overwrites the value of key, keeping the entry's ID, or appends a new
+// entry when the key is not present.
+func (m *MetaData) Set(key string, value interface{}) {
+	for i := range *m {
+		if (*m)[i].Key == key {
+			(*m)[i].Value = value
+			return
+		}
+	}
+	*m = append(*m, MetaEntry{Key: key, Value: value})
+}
+
+func (m MetaData) YoastTitle() string   { return m.Get(yoastTitleKey) }
+func (m MetaData) YoastDesc() string    { return m.Get(yoastDescKey) }
+func (m MetaData) YoastFocusKW() string { return m.Get(yoastFocusKWKey) }
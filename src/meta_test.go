@@ -0,0 +1,72 @@
+package wooh
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMetaDataGet(t *testing.T) {
+	meta := MetaData{
+		{ID: 1, Key: yoastTitleKey, Value: "Oak Board"},
+		{ID: 2, Key: "_stock_count", Value: 12},
+		{ID: 3, Key: yoastTitleKey, Value: "Shadowed"},
+	}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{yoastTitleKey, "Oak Board"}, // first entry wins
+		{"_stock_count", "12"},
+		{"_missing", ""},
+	}
+	for _, tt := range tests {
+		if got := meta.Get(tt.key); got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestMetaDataSet(t *testing.T) {
+	meta := MetaData{{ID: 7, Key: yoastTitleKey, Value: "Old"}}
+	meta.Set(yoastTitleKey, "New")
+	meta.Set(yoastDescKey, "Solid oak.")
+	meta.Set(yoastDescKey, "Oiled oak.")
+
+	want := MetaData{
+		{ID: 7, Key: yoastTitleKey, Value: "New"},
+		{Key: yoastDescKey, Value: "Oiled oak."},
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("meta = %+v, want %+v", meta, want)
+	}
+
+	var empty MetaData
+	empty.Set(yoastFocusKWKey, "oak board")
+	if len(empty) != 1 || empty.YoastFocusKW() != "oak board" {
+		t.Errorf("Set on nil meta = %+v", empty)
+	}
+}
+
+func TestMetaDataYoastAccessors(t *testing.T) {
+	var meta MetaData
+	if err := json.Unmarshal([]byte(`[
+		{"id": 1, "key": "_yoast_wpseo_title", "value": "Oak Board"},
+		{"id": 2, "key": "_yoast_wpseo_metadesc", "value": "Solid oak."},
+		{"id": 3, "key": "_yoast_wpseo_focuskw", "value": "oak board"}
+	]`), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.YoastTitle() != "Oak Board" || meta.YoastDesc() != "Solid oak." || meta.YoastFocusKW() != "oak board" {
+		t.Errorf("accessors = %q, %q, %q", meta.YoastTitle(), meta.YoastDesc(), meta.YoastFocusKW())
+	}
+
+	// New entries are written without an ID so WooCommerce creates them.
+	b, err := json.Marshal(MetaData{{Key: yoastTitleKey, Value: "Oak"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != `[{"key":"_yoast_wpseo_title","value":"Oak"}]` {
+		t.Errorf("marshalled new entry = %s", got)
+	}
+}
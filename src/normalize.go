@@ -0,0 +1,73 @@
+package wooh
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// defaultSkuTokenPattern matches trailing tokens like "OAK-001", "SKU123",
+// "#10045" or "(AB-7)"; plain numbers shorter than three digits and sizes
+// like "120mm" are left alone.
+const defaultSkuTokenPattern = `^[(\[]?#?(?:[A-Za-z]{1,6}[-_]?\d{3,}|[A-Za-z]{1,6}[-_]\d+|\d{3,})[)\]]?$`
+
+var defaultSkuTokenRegex = regexp.MustCompile(defaultSkuTokenPattern)
+
+// NameRules controls how product names are normalized for duplicate
+// detection and matching. The zero value applies every rule.
+type NameRules struct {
+	KeepCase        bool   `yaml:"keep_case"`
+	KeepPunctuation bool   `yaml:"keep_punctuation"`
+	KeepSkuSuffix   bool   `yaml:"keep_sku_suffix"`
+	SkuPattern      string `yaml:"sku_pattern"` // regexp for a trailing SKU-like token
+}
+
+func ValidateNameRules(r NameRules) error {
+	if r.SkuPattern == "" {
+		return nil
+	}
+	_, err := regexp.Compile(r.SkuPattern)
+	return err
+}
+
+// normalize reduces s to the form names are compared in: by default it
+// lowercases, replaces punctuation with spaces, drops trailing SKU-like
+// tokens and collapses whitespace.
+func (r NameRules) normalize(s string) string {
+	fields := strings.Fields(s)
+
+	if !r.KeepSkuSuffix {
+		skuToken := defaultSkuTokenRegex
+		if r.SkuPattern != "" {
+			if re, err := regexp.Compile(r.SkuPattern); err == nil {
+				skuToken = re
+			}
+		}
+		for len(fields) > 1 && skuToken.MatchString(fields[len(fields)-1]) {
+			fields = fields[:len(fields)-1]
+		}
+	}
+
+	name := strings.Join(fields, " ")
+	if !r.KeepPunctuation {
+		name = strings.Map(func(c rune) rune {
+			if unicode.IsLetter(c) || unicode.IsDigit(c) || unicode.IsSpace(c) {
+				return c
+			}
+			return ' '
+		}, name)
+	}
+	if !r.KeepCase {
+		name = strings.ToLower(name)
+	}
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// displayName tidies a product name derived from a file name: underscores
+// become spaces, trailing SKU-like tokens are dropped unless
+// keep_sku_suffix is set, and whitespace is collapsed. Case and
+// punctuation are kept.
+func (r NameRules) displayName(s string) string {
+	r.KeepCase, r.KeepPunctuation = true, true
+	return r.normalize(strings.ReplaceAll(s, "_", " "))
+}
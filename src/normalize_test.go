@@ -0,0 +1,52 @@
+package wooh
+
+import "testing"
+
+func TestNameRulesNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules NameRules
+		in    string
+		want  string
+	}{
+		{"case and spaces", NameRules{}, "  Oak   PLANK\tFloor ", "oak plank floor"},
+		{"punctuation", NameRules{}, "Oak-Plank, Floor!", "oak plank floor"},
+		{"trailing sku", NameRules{}, "Oak Plank OAK-001", "oak plank"},
+		{"bracketed sku", NameRules{}, "Oak Plank (AB-7)", "oak plank"},
+		{"hash number", NameRules{}, "Oak Plank #10045", "oak plank"},
+		{"several skus", NameRules{}, "Oak Plank SKU123 OAK-001", "oak plank"},
+		{"short number kept", NameRules{}, "Oak Plank 12", "oak plank 12"},
+		{"size kept", NameRules{}, "Oak Plank 120mm", "oak plank 120mm"},
+		{"lone sku kept", NameRules{}, "OAK-001", "oak 001"},
+		{"keep case", NameRules{KeepCase: true}, "Oak PLANK", "Oak PLANK"},
+		{"keep punctuation", NameRules{KeepPunctuation: true}, "Oak-Plank!", "oak-plank!"},
+		{"keep sku", NameRules{KeepSkuSuffix: true}, "Oak Plank OAK-001", "oak plank oak 001"},
+		{"custom sku pattern", NameRules{SkuPattern: `^v\d+$`}, "Oak Plank v2", "oak plank"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.normalize(tt.in); got != tt.want {
+				t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameRulesDisplayName(t *testing.T) {
+	tests := []struct {
+		rules NameRules
+		in    string
+		want  string
+	}{
+		{NameRules{}, "Oak_Plank_Floor", "Oak Plank Floor"},
+		{NameRules{}, "Oak  Plank OAK-001", "Oak Plank"},
+		{NameRules{}, "Smoked-Oak Plank", "Smoked-Oak Plank"},
+		{NameRules{KeepSkuSuffix: true}, "Oak_Plank_OAK-001", "Oak Plank OAK-001"},
+		{NameRules{}, "___", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.rules.displayName(tt.in); got != tt.want {
+			t.Errorf("displayName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
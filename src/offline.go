@@ -0,0 +1,68 @@
+package wooh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// ErrOffline is returned for any store request made while offline_products
+// is set.
+var ErrOffline = errors.New("offline mode: no requests are sent to the store")
+
+// offlineTransport fails every request, so nothing in offline mode can reach
+// the network by accident.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("%w (%s %s)", ErrOffline, req.Method, req.URL.Path)
+}
+
+// loadOfflineProducts reads the product fixture at path: a JSON array of
+// products as the products endpoint returns them.
+func loadOfflineProducts(conf *Config) ([]WooProduct, error) {
+	data, err := os.ReadFile(conf.OfflineProducts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offline products: %w", err)
+	}
+	var products []WooProduct
+	if err := json.Unmarshal(data, &products); err != nil {
+		return nil, fmt.Errorf("failed to parse offline products %s: %w", conf.OfflineProducts, err)
+	}
+	if hasPathFields(conf) {
+		var raw []interface{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		for i := range products {
+			decodePathFields(conf, &products[i], raw[i])
+		}
+	}
+	slog.Info("Loaded offline products", "products", len(products), "path", conf.OfflineProducts)
+	return products, nil
+}
+
+func offlineProduct(conf *Config, id int) (WooProduct, error) {
+	products, err := loadOfflineProducts(conf)
+	if err != nil {
+		return WooProduct{}, err
+	}
+	for _, p := range products {
+		if int(p.ID) == id {
+			return p, nil
+		}
+	}
+	return WooProduct{}, fmt.Errorf("product %d is not in %s", id, conf.OfflineProducts)
+}
+
+// logOfflineWrite records the update that would have been sent for productID.
+func logOfflineWrite(productID int, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		data = []byte(fmt.Sprint(payload))
+	}
+	slog.Info("Offline: would update product", "product_id", productID, "payload", string(data))
+}
@@ -0,0 +1,69 @@
+package wooh
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOfflineProducts(t *testing.T) {
+	conf, requests := newTestStore(t, newFakeStore().handle)
+	conf.OfflineProducts = filepath.Join("testdata", "wc8-products.json")
+	conf.OpenAIStub = true
+
+	cache, _ := NewCache(conf)
+	products, err := GetProducts(conf, cache, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 1 || products[0].ID != 202 || products[0].Name != "Walnut Board" {
+		t.Errorf("got %+v, want the fixture's products", products)
+	}
+	if p, err := GetProduct(conf, 202); err != nil || p.Name != "Walnut Board" {
+		t.Errorf("GetProduct = %+v, %v", p, err)
+	}
+	if _, err := GetProduct(conf, 7); err == nil {
+		t.Error("GetProduct found a product missing from the fixture")
+	}
+
+	result, err := UpdateSEO(conf, SEOOptions{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(result.Updated, []int{202}) {
+		t.Errorf("updated %v, want [202]", result.Updated)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("offline mode sent %d requests to the store", n)
+	}
+}
+
+func TestOfflineClientRefusesRequests(t *testing.T) {
+	conf, requests := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {})
+	conf.OfflineProducts = filepath.Join("testdata", "wc7-products.json")
+
+	_, err := newClient(conf).R().Get(wooEndpoint(conf, "products/categories"))
+	if !errors.Is(err, ErrOffline) {
+		t.Errorf("err = %v, want ErrOffline", err)
+	}
+	if requests.Load() != 0 {
+		t.Error("offline client reached the store")
+	}
+}
+
+func TestOfflineProductsErrors(t *testing.T) {
+	dir := t.TempDir()
+	malformed := filepath.Join(dir, "products.json")
+	os.WriteFile(malformed, []byte(`{"id": 1`), 0644)
+
+	for _, path := range []string{filepath.Join(dir, "missing.json"), malformed} {
+		conf := &Config{OfflineProducts: path}
+		if _, err := loadOfflineProducts(conf); err == nil {
+			t.Errorf("loading %s returned no error", path)
+		}
+	}
+}
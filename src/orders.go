@@ -0,0 +1,84 @@
+package wooh
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// defaultSalesWindow is how far back UpdateSEO looks when ordering products
+// by sales.
+const defaultSalesWindow = 90 * 24 * time.Hour
+
+type WooOrder struct {
+	ID          int64         `json:"id"`
+	Status      string        `json:"status"`
+	DateCreated string        `json:"date_created_gmt"`
+	LineItems   []WooLineItem `json:"line_items"`
+}
+
+type WooLineItem struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int   `json:"quantity"`
+}
+
+// GetOrders fetches every order created after since.
+func GetOrders(conf *Config, since time.Time) ([]WooOrder, error) {
+	return paginate[WooOrder](context.Background(), newClient(conf), conf, "orders", map[string]string{
+		"after": since.UTC().Format(time.RFC3339),
+	})
+}
+
+// TopSellingProductIDs returns up to n product IDs ordered by units sold
+// since the given time, best seller first. n <= 0 returns all of them.
+func TopSellingProductIDs(conf *Config, since time.Time, n int) ([]int, error) {
+	orders, err := GetOrders(conf, since)
+	if err != nil {
+		return nil, err
+	}
+	return rankBySales(orders, n), nil
+}
+
+func rankBySales(orders []WooOrder, n int) []int {
+	sold := make(map[int]int)
+	for _, order := range orders {
+		for _, item := range order.LineItems {
+			if item.ProductID != 0 {
+				sold[int(item.ProductID)] += item.Quantity
+			}
+		}
+	}
+
+	ids := make([]int, 0, len(sold))
+	for id := range sold {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if sold[ids[i]] != sold[ids[j]] {
+			return sold[ids[i]] > sold[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	if n > 0 && len(ids) > n {
+		ids = ids[:n]
+	}
+	return ids
+}
+
+// sortBySales moves the products in ranked to the front, in rank order,
+// keeping the rest in their original order.
+func sortBySales(products []WooProduct, ranked []int) {
+	rank := make(map[int]int, len(ranked))
+	for i, id := range ranked {
+		rank[id] = i
+	}
+	sort.SliceStable(products, func(i, j int) bool {
+		ri, iok := rank[int(products[i].ID)]
+		rj, jok := rank[int(products[j].ID)]
+		if iok && jok {
+			return ri < rj
+		}
+		return iok && !jok
+	})
+}
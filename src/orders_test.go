@@ -0,0 +1,78 @@
+package wooh
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTopSellingProductIDs(t *testing.T) {
+	list := &pagedList{total: 150, totalPages: true, item: func(i int) map[string]interface{} {
+		items := []map[string]interface{}{{"product_id": 1, "quantity": 1}}
+		if i%2 == 0 {
+			items = append(items, map[string]interface{}{"product_id": 2, "quantity": 3})
+		}
+		if i%10 == 0 {
+			// Line items of deleted products have product_id 0.
+			items = append(items,
+				map[string]interface{}{"product_id": 3, "quantity": 1},
+				map[string]interface{}{"product_id": 0, "quantity": 50})
+		}
+		return map[string]interface{}{"id": i + 1, "status": "completed", "line_items": items}
+	}}
+	conf, _ := newTestStore(t, list.handle)
+	since := time.Date(2026, 7, 1, 12, 0, 0, 0, time.FixedZone("BST", 3600))
+
+	tests := []struct {
+		n    int
+		want []int
+	}{
+		{0, []int{2, 1, 3}}, // 225, 150 and 15 units
+		{2, []int{2, 1}},
+		{5, []int{2, 1, 3}},
+	}
+	for _, tt := range tests {
+		ids, err := TopSellingProductIDs(conf, since, tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("TopSellingProductIDs(n=%d) = %v, want %v", tt.n, ids, tt.want)
+		}
+	}
+
+	if !list.paths["/wp-json/wc/v3/orders"] || len(list.paths) != 1 {
+		t.Errorf("requested %v, want only orders", list.paths)
+	}
+	q, _ := url.ParseQuery(list.query[0])
+	if got := q.Get("after"); got != "2026-07-01T11:00:00Z" {
+		t.Errorf("after = %q, want the UTC time", got)
+	}
+}
+
+func TestRankBySalesTies(t *testing.T) {
+	orders := []WooOrder{
+		{LineItems: []WooLineItem{{ProductID: 9, Quantity: 2}, {ProductID: 4, Quantity: 2}}},
+		{LineItems: []WooLineItem{{ProductID: 7, Quantity: 1}}},
+	}
+	if got := rankBySales(orders, 0); !slices.Equal(got, []int{4, 9, 7}) {
+		t.Errorf("rankBySales = %v, want ties broken by ID: [4 9 7]", got)
+	}
+}
+
+func TestSortBySales(t *testing.T) {
+	var products []WooProduct
+	for _, id := range []int64{1, 2, 3, 4, 5} {
+		products = append(products, WooProduct{ID: id})
+	}
+	sortBySales(products, []int{4, 2})
+
+	var got []int64
+	for _, p := range products {
+		got = append(got, p.ID)
+	}
+	if want := []int64{4, 2, 1, 3, 5}; !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
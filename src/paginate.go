@@ -0,0 +1,104 @@
+package wooh
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+// paginate fetches every item of a WooCommerce list endpoint, such as
+// "orders" or "products/reviews", sending params with each page. Once the
+// first page reports X-WP-TotalPages the rest are fetched page_concurrency
+// at a time; without the header, or with page_concurrency 1, pages are
+// walked until a short one.
+func paginate[T any](ctx context.Context, client *resty.Client, conf *Config, endpoint string, params map[string]string) ([]T, error) {
+	return paginateURL[T](ctx, client, conf, wooEndpoint(conf, endpoint), params)
+}
+
+// paginateURL is paginate for a list outside the WooCommerce REST
+// namespace, given by its full URL, such as the Store API's products.
+func paginateURL[T any](ctx context.Context, client *resty.Client, conf *Config, listURL string, params map[string]string) ([]T, error) {
+	items, resp, err := fetchPage[T](ctx, client, conf, listURL, params, 1)
+	if err != nil || len(items) < batchLimit {
+		return items, err
+	}
+
+	totalPages, err := strconv.Atoi(resp.Header().Get("X-WP-TotalPages"))
+	if err == nil && conf.PageConcurrency > 1 && totalPages > 1 {
+		pages := make([][]T, totalPages+1)
+		g, ctx := errgroup.WithContext(ctx)
+		g.SetLimit(conf.PageConcurrency)
+		for page := 2; page <= totalPages; page++ {
+			g.Go(func() error {
+				batch, _, err := fetchPage[T](ctx, client, conf, listURL, params, page)
+				pages[page] = batch
+				time.Sleep(pageDelay(conf))
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+		for _, batch := range pages[2:] {
+			items = append(items, batch...)
+		}
+		return items, nil
+	}
+
+	for page := 2; ; page++ {
+		time.Sleep(pageDelay(conf))
+		batch, _, err := fetchPage[T](ctx, client, conf, listURL, params, page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+		if len(batch) < batchLimit {
+			return items, nil
+		}
+	}
+}
+
+// fetchPage fetches one page of the list at listURL. Errors name the last
+// segment of its path, e.g. "failed to fetch reviews on page 2".
+func fetchPage[T any](ctx context.Context, client *resty.Client, conf *Config, listURL string, params map[string]string, page int) ([]T, *resty.Response, error) {
+	what := "items"
+	if u, err := url.Parse(listURL); err == nil {
+		what = path.Base(u.Path)
+	}
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Accept", "application/json").
+		SetQueryParams(map[string]string{
+			"page":     fmt.Sprintf("%d", page),
+			"per_page": fmt.Sprintf("%d", batchLimit),
+		}).
+		SetQueryParams(params).
+		Get(listURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch %s on page %d: %w", what, page, err)
+	}
+	if resp.IsError() {
+		return nil, nil, fmt.Errorf("error fetching %s page %d: %w", what, page, apiError(resp))
+	}
+
+	var items []T
+	if err := decodePage(conf, resp, &items); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s on page %d: %w", what, page, err)
+	}
+	return items, resp, nil
+}
+
+// decodePage decodes a page of items, filling in the custom fields of
+// products.
+func decodePage[T any](conf *Config, resp *resty.Response, items *[]T) error {
+	if products, ok := any(items).(*[]WooProduct); ok {
+		return decodeProducts(conf, resp, products)
+	}
+	return decodeJSON(resp, items)
+}
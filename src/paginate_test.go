@@ -0,0 +1,270 @@
+package wooh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pagedList serves total items made by item, per_page at a time, setting
+// X-WP-TotalPages when totalPages is true. It records the pages requested.
+type pagedList struct {
+	total      int
+	totalPages bool
+	item       func(i int) map[string]interface{}
+
+	mu    sync.Mutex
+	pages []int
+	paths map[string]bool
+	query []string
+}
+
+func (l *pagedList) handle(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	l.mu.Lock()
+	l.pages = append(l.pages, page)
+	if l.paths == nil {
+		l.paths = map[string]bool{}
+	}
+	l.paths[r.URL.Path] = true
+	l.query = append(l.query, r.URL.RawQuery)
+	l.mu.Unlock()
+
+	items := []map[string]interface{}{}
+	for i := (page - 1) * perPage; i < min(page*perPage, l.total); i++ {
+		items = append(items, l.item(i))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if l.totalPages {
+		w.Header().Set("X-WP-TotalPages", strconv.Itoa((l.total+perPage-1)/perPage))
+	}
+	json.NewEncoder(w).Encode(items)
+}
+
+func TestPaginate(t *testing.T) {
+	t.Run("categories with X-WP-TotalPages", func(t *testing.T) {
+		list := &pagedList{total: 250, totalPages: true, item: func(i int) map[string]interface{} {
+			return map[string]interface{}{"id": i + 1, "name": fmt.Sprintf("Category %d", i+1)}
+		}}
+		conf, _ := newTestStore(t, list.handle)
+		conf.PageConcurrency = 3
+
+		categories, err := paginate[WooCategory](context.Background(), newClient(conf), conf, "products/categories", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(categories) != 250 {
+			t.Fatalf("got %d categories, want 250", len(categories))
+		}
+		for i, c := range categories {
+			if c.ID != int64(i+1) {
+				t.Fatalf("category %d has ID %d; pages out of order", i, c.ID)
+			}
+		}
+		if len(list.pages) != 3 || !list.paths["/wp-json/wc/v3/products/categories"] {
+			t.Errorf("requested pages %v of %v", list.pages, list.paths)
+		}
+	})
+
+	t.Run("reviews without the header", func(t *testing.T) {
+		list := &pagedList{total: 200, item: func(i int) map[string]interface{} {
+			return map[string]interface{}{"id": i + 1, "product_id": 7, "rating": 1 + i%5}
+		}}
+		conf, _ := newTestStore(t, list.handle)
+		conf.PageConcurrency = 4
+
+		reviews, err := paginate[Review](context.Background(), newClient(conf), conf, "products/reviews", map[string]string{"product": "7"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reviews) != 200 || reviews[199].ID != 200 || reviews[0].ProductID != 7 {
+			t.Fatalf("got %d reviews, last %+v", len(reviews), reviews[len(reviews)-1])
+		}
+		// Two full pages and the empty one that ends the walk.
+		if fmt.Sprint(list.pages) != "[1 2 3]" {
+			t.Errorf("requested pages %v, want [1 2 3]", list.pages)
+		}
+		for _, q := range list.query {
+			if !strings.Contains(q, "product=7") {
+				t.Errorf("params not sent with every page: %s", q)
+			}
+		}
+	})
+
+	t.Run("error names the list and page", func(t *testing.T) {
+		list := &pagedList{total: 300, item: func(i int) map[string]interface{} {
+			return map[string]interface{}{"id": i + 1}
+		}}
+		conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("page") == "2" {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"code": "internal_error", "message": "boom"}`))
+				return
+			}
+			list.handle(w, r)
+		})
+
+		_, err := paginate[Review](context.Background(), newClient(conf), conf, "products/reviews", nil)
+		if err == nil || !strings.Contains(err.Error(), "reviews page 2") {
+			t.Fatalf("err = %v, want it to name reviews page 2", err)
+		}
+		if strings.Contains(err.Error(), conf.WooConsumerSecret) {
+			t.Errorf("error leaks the consumer secret: %v", err)
+		}
+	})
+}
+
+func TestFetchStoreProducts(t *testing.T) {
+	list := &pagedList{total: 150, totalPages: true, item: func(i int) map[string]interface{} {
+		return map[string]interface{}{
+			"id":   i + 1,
+			"name": fmt.Sprintf("Oak %d", i+1),
+			"prices": map[string]interface{}{
+				"regular_price":       "1299",
+				"currency_minor_unit": 2,
+			},
+			"brand": map[string]interface{}{"name": "Acme"},
+		}
+	}}
+	conf, _ := newTestStore(t, list.handle)
+	conf.ReadMode = ReadModeStore
+	conf.PageConcurrency = 2
+	conf.OrderBy = "price"
+	conf.CustomFields = map[string]string{"brand": "brand.name"}
+
+	products, err := fetchStoreProducts(conf, map[string]string{"category": "12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 150 {
+		t.Fatalf("got %d products, want 150", len(products))
+	}
+	last := products[149]
+	if last.ID != 150 || last.RegularPrice != "12.99" || last.Extra["brand"] != "Acme" {
+		t.Errorf("last product = %+v", last)
+	}
+	if !list.paths["/wp-json/wc/store/v1/products"] || len(list.paths) != 1 {
+		t.Errorf("requested %v, want only the Store API", list.paths)
+	}
+	for _, q := range list.query {
+		if !strings.Contains(q, "orderby=price") || !strings.Contains(q, "category=12") {
+			t.Errorf("query %s is missing orderby or params", q)
+		}
+		if strings.Contains(q, "consumer_key") {
+			t.Errorf("Store API request sent consumer keys: %s", q)
+		}
+	}
+}
+
+func TestPageDelay(t *testing.T) {
+	if d := pageDelay(&Config{}); d != 0 {
+		t.Errorf("default page delay = %s, want 0", d)
+	}
+	conf := &Config{PageDelay: 100 * time.Millisecond, PageDelayJitter: 50 * time.Millisecond}
+	varied := false
+	for i := 0; i < 200; i++ {
+		d := pageDelay(conf)
+		if d < conf.PageDelay || d >= conf.PageDelay+conf.PageDelayJitter {
+			t.Fatalf("pageDelay = %s, want within [100ms, 150ms)", d)
+		}
+		varied = varied || d != conf.PageDelay
+	}
+	if !varied {
+		t.Error("jitter never changed the delay")
+	}
+}
+
+func TestPaginateWaitsBetweenPages(t *testing.T) {
+	const delay = 40 * time.Millisecond
+	var (
+		mu       sync.Mutex
+		arrivals []time.Time
+	)
+	list := &pagedList{total: 250, item: func(i int) map[string]interface{} {
+		return map[string]interface{}{"id": i + 1}
+	}}
+	conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrivals = append(arrivals, time.Now())
+		mu.Unlock()
+		list.handle(w, r)
+	})
+	conf.PageConcurrency = 1
+	conf.PageDelay = delay
+
+	if _, err := paginate[WooCategory](context.Background(), newClient(conf), conf, "products/categories", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(arrivals) != 3 {
+		t.Fatalf("fetched %d pages, want 3", len(arrivals))
+	}
+	for i := 1; i < len(arrivals); i++ {
+		if gap := arrivals[i].Sub(arrivals[i-1]); gap < delay {
+			t.Errorf("page %d requested %s after the previous one, want at least %s", i+1, gap, delay)
+		}
+	}
+}
+
+func TestGetProductsPageConcurrency(t *testing.T) {
+	tests := []struct {
+		name         string
+		totalPages   bool
+		wantParallel bool
+	}{
+		{"with X-WP-TotalPages", true, true},
+		{"without the header", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := &pagedList{total: 520, totalPages: tt.totalPages, item: func(i int) map[string]interface{} {
+				return testProduct(i+1, fmt.Sprintf("Board %d", i+1))
+			}}
+			var (
+				mu             sync.Mutex
+				inFlight, most int
+			)
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				inFlight++
+				most = max(most, inFlight)
+				mu.Unlock()
+				time.Sleep(20 * time.Millisecond)
+				list.handle(w, r)
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			})
+			conf.PageConcurrency = 4
+
+			cache, err := NewCache(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			products, err := GetProducts(conf, cache, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(products) != 520 {
+				t.Fatalf("got %d products, want 520", len(products))
+			}
+			for i, p := range products {
+				if p.ID != int64(i+1) {
+					t.Fatalf("product %d has ID %d; pages out of order", i, p.ID)
+				}
+			}
+			if len(list.pages) != 6 {
+				t.Errorf("requested pages %v, want 6", list.pages)
+			}
+			if parallel := most > 1; parallel != tt.wantParallel {
+				t.Errorf("at most %d pages in flight, want parallel %v", most, tt.wantParallel)
+			}
+		})
+	}
+}
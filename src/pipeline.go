@@ -0,0 +1,565 @@
+package wooh
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ProductUpdate is the output of the generation stage: the meta (and any
+// regenerated descriptions) waiting to be written to one product.
+type ProductUpdate struct {
+	Product          WooProduct
+	MetaData         MetaData
+	Description      string // regenerated description, empty when unchanged
+	ShortDescription string // regenerated short description, empty when unchanged
+}
+
+func (u *ProductUpdate) payload() map[string]interface{} {
+	payload := map[string]interface{}{
+		"meta_data": u.MetaData,
+	}
+	if u.Description != "" {
+		payload["description"] = u.Description
+	}
+	if u.ShortDescription != "" {
+		payload["short_description"] = u.ShortDescription
+	}
+	return payload
+}
+
+// Yoast's display limits for the meta title and description.
+const (
+	maxTitleLength       = 60
+	maxDescriptionLength = 160
+)
+
+// seoRun holds the state shared by the generate and write stages of an
+// UpdateSEO run.
+type seoRun struct {
+	ctx             context.Context // cancelled to stop the run
+	conf            *Config
+	opts            SEOOptions
+	client          *resty.Client
+	tracker         *TrackerUpdate
+	trackerFilepath string
+	result          *SEOResult
+	reader          *bufio.Reader
+	outputMu        sync.Mutex     // keeps diff output and prompts from interleaving
+	export          *seoExport     // set in export-only runs
+	wal             *writeAheadLog // set in runs that write to the store
+	skipList        *SkipList
+	skipListPath    string
+	audit           *auditLog // set when audit_log is configured
+	breaker         *circuitBreaker
+}
+
+// newSEORun sets up a run of opts against conf: its client, circuit breaker
+// and skip list and, when it writes to the store, its write-ahead log and
+// audit log. close releases them.
+func newSEORun(conf *Config, opts SEOOptions, tracker *TrackerUpdate, trackerFilepath string, result *SEOResult) (*seoRun, error) {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client := newClient(conf)
+	if conf.OfflineProducts == "" {
+		client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	}
+	run := &seoRun{
+		ctx:             ctx,
+		conf:            conf,
+		opts:            opts,
+		client:          client,
+		tracker:         tracker,
+		trackerFilepath: trackerFilepath,
+		result:          result,
+		reader:          bufio.NewReader(os.Stdin),
+	}
+	if opts.ExportOnly != "" {
+		run.export = &seoExport{}
+	} else {
+		run.breaker = newCircuitBreaker(conf)
+		if !opts.Diff && conf.OfflineProducts == "" {
+			run.conf = resolveSEOWriteMode(conf)
+		}
+	}
+
+	var err error
+	run.skipListPath, err = CachePath(conf, conf.SkipListFilename)
+	if err != nil {
+		return nil, err
+	}
+	if run.skipList, err = LoadSkipList(run.skipListPath); err != nil {
+		return nil, fmt.Errorf("failed to load skip list: %w", err)
+	}
+
+	if opts.Diff || opts.ExportOnly != "" {
+		return run, nil
+	}
+	walPath, err := CachePath(conf, conf.WALFilename)
+	if err != nil {
+		return nil, err
+	}
+	if run.wal, err = openWAL(walPath); err != nil {
+		return nil, err
+	}
+	if conf.AuditLog != "" {
+		if run.audit, err = openAuditLog(conf.AuditLog); err != nil {
+			run.close()
+			return nil, err
+		}
+	}
+	return run, nil
+}
+
+// close compacts the write-ahead log and closes the audit log.
+func (r *seoRun) close() {
+	if r.wal != nil {
+		if err := r.wal.close(); err != nil {
+			slog.Warn("Could not compact write-ahead log", "err", err)
+		}
+	}
+	if r.audit != nil {
+		r.audit.close()
+	}
+}
+
+// generate produces the update for one product. A nil update means the
+// product was skipped or its outcome already recorded; an error aborts the run.
+func (r *seoRun) generate(product WooProduct) (*ProductUpdate, error) {
+	productID := int(product.ID)
+
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if r.breaker.tripped() {
+		return nil, ErrCircuitOpen
+	}
+
+	slog.Info("Processing product", "product_id", productID)
+
+	input, err := seoInputFromProduct(r.conf, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clean description for product ID %v: %w", productID, err)
+	}
+
+	nameOnly := strings.TrimSpace(input.Description) == "" && strings.TrimSpace(input.ShortDescription) == ""
+	if nameOnly {
+		switch r.conf.EmptyDescriptionPolicy {
+		case EmptyDescriptionSkip:
+			slog.Info("Skipping product: no description", "product_id", productID)
+			r.result.record(&r.result.Skipped, productID)
+			return nil, nil
+		case EmptyDescriptionFlag:
+			slog.Info("Flagging product for review: no description", "product_id", productID)
+			r.result.record(&r.result.Flagged, productID)
+			return nil, nil
+		}
+	}
+
+	ctx, cancel := productContext(r.ctx, r.conf)
+	defer cancel()
+
+	// Generator calls for this product use genConf, which carries the model
+	// chosen for its category.
+	genConf := r.conf
+	if model := ModelFor(r.conf, product.Categories); model != r.conf.GeneratorModel {
+		withModel := *r.conf
+		withModel.GeneratorModel = model
+		genConf = &withModel
+	}
+
+	if r.conf.IncludeReviews {
+		if err := addReviews(ctx, r.conf, &input, productID); err != nil {
+			slog.Warn("Could not fetch reviews", "product_id", productID, "err", err)
+		}
+	}
+
+	var newDescription, newShortDescription string
+	if r.opts.RegenerateDescriptions {
+		if len(input.Description) < r.conf.DescriptionMinLength {
+			newDescription, err = GenerateDescription(ctx, genConf, input)
+			if err != nil {
+				slog.Error("Could not generate description", "product_id", productID, "err", err)
+				r.result.recordGenerationError(productID, err)
+				return nil, nil
+			}
+			input.Description, _ = cleanHTMLToMarkdown(newDescription, r.conf.Markdown)
+		}
+		if len(input.ShortDescription) < r.conf.ShortDescriptionMinLength {
+			newShortDescription, err = GenerateShortDescription(ctx, genConf, input)
+			if err != nil {
+				slog.Error("Could not generate short description", "product_id", productID, "err", err)
+				r.result.recordGenerationError(productID, err)
+				return nil, nil
+			}
+			input.ShortDescription = newShortDescription
+		}
+	}
+
+	if r.opts.Enrich && !nameOnly {
+		if words := wordCount(input.Description); words < r.conf.MinDescriptionWords {
+			enriched, err := EnrichDescription(ctx, genConf, input)
+			if err != nil {
+				slog.Warn("Could not enrich description", "product_id", productID, "err", err)
+			} else {
+				slog.Info("Enriched description", "product_id", productID, "words", words)
+				input.Description = enriched
+				r.result.record(&r.result.Enriched, productID)
+			}
+		}
+	}
+
+	systemPrompt, err := SystemPromptFor(r.conf, input.Categories)
+	if err != nil {
+		return nil, err
+	}
+	if nameOnly && newDescription == "" && newShortDescription == "" {
+		systemPrompt += OpenAINameOnlyPrompt()
+	}
+	titleLimit := maxTitleLength
+	if suffix := r.conf.titleSuffix(); suffix != "" {
+		titleLimit -= len(suffix)
+		systemPrompt += OpenAITitleSuffixPrompt(suffix, titleLimit)
+	}
+
+	var metaTitle, metaDescription, focusKeyphrase, googleCategory string
+	var genErr error
+	var keyphraseMissing bool
+	feedback := ""
+	retries := 1
+	if r.conf.KeyphraseInTitle {
+		retries = 2
+	}
+
+	for i := 0; i < retries; i++ {
+		userPrompt := seoInputPrompt(input) + feedback
+		var generated JSONResponse
+		if r.conf.OpenAIStub {
+			generated = stubMeta(input, titleLimit)
+		} else {
+			generated, genErr = generateMeta(ctx, genConf, systemPrompt, userPrompt)
+		}
+		metaTitle, metaDescription, focusKeyphrase = generated.MetaTitle, generated.MetaDescription, generated.FocusKeyphrase
+		googleCategory = generated.GoogleCategory
+		if errors.Is(genErr, ErrContentPolicy) {
+			slog.Warn("Policy-skip", "product_id", productID, "err", genErr)
+			r.result.record(&r.result.PolicySkipped, productID)
+			return nil, nil
+		}
+		if errors.Is(genErr, context.DeadlineExceeded) {
+			slog.Error("Timed out generating meta fields", "product_id", productID, "timeout", r.conf.PerProductTimeout)
+			break
+		}
+		if genErr != nil {
+			slog.Error("Could not generate meta fields", "product_id", productID, "err", genErr)
+			continue
+		}
+		metaTitle = strings.TrimSpace(strings.TrimSuffix(metaTitle, r.conf.titleSuffix()))
+		if len(metaTitle) > titleLimit || len(metaDescription) > maxDescriptionLength {
+			slog.Warn("Meta fields exceeded char limits", "product_id", productID, "attempt", i+1, "attempts", retries)
+			continue
+		}
+		keyphraseMissing = false
+		if r.conf.KeyphraseInTitle {
+			keyphrase := focusKeyphrase
+			if keyphrase == "" {
+				keyphrase = product.MetaData.YoastFocusKW()
+			}
+			if keyphrase != "" && !strings.Contains(strings.ToLower(metaTitle), strings.ToLower(keyphrase)) {
+				slog.Warn("Meta title lacks focus keyphrase", "product_id", productID, "keyphrase", keyphrase, "attempt", i+1, "attempts", retries)
+				keyphraseMissing = true
+				feedback = fmt.Sprintf("\nA previous meta title, %q, did not contain the focus keyphrase %q. The meta title must include it.\n", metaTitle, keyphrase)
+				continue
+			}
+		}
+		break
+	}
+
+	if genErr != nil || keyphraseMissing || len(metaTitle) > titleLimit || len(metaDescription) > maxDescriptionLength {
+		slog.Error("Failed to generate valid meta fields", "product_id", productID, "attempts", retries)
+		r.result.record(&r.result.Failed, productID)
+		return nil, nil
+	}
+	metaTitle += r.conf.titleSuffix()
+
+	if r.opts.OnlyImprove {
+		keyphrase := product.MetaData.YoastFocusKW()
+		if r.conf.FocusKeyphrase && focusKeyphrase != "" {
+			keyphrase = focusKeyphrase
+		}
+		current := ScoreSEO(product).Total
+		generated := scoreMeta(metaTitle, metaDescription, keyphrase).Total
+		if generated <= current+r.conf.ImproveMargin {
+			slog.Info("Keeping current meta", "product_id", productID, "generated_score", generated, "current_score", current, "margin", r.conf.ImproveMargin)
+			r.result.record(&r.result.Skipped, productID)
+			return nil, nil
+		}
+	}
+
+	var metaData MetaData
+	metaData.Set(yoastTitleKey, metaTitle)
+	metaData.Set(yoastDescKey, metaDescription)
+	if r.conf.FocusKeyphrase && focusKeyphrase != "" {
+		metaData.Set(yoastFocusKWKey, focusKeyphrase)
+	}
+	if r.conf.StructuredData.Enabled {
+		metaData = append(metaData, structuredDataMeta(r.conf.StructuredData, product, googleCategory)...)
+	}
+	if r.opts.Diff {
+		changes := DiffMeta(product, metaData)
+		if newDescription != "" {
+			changes = append(changes, MetaChange{Key: "description", Old: product.Description, New: newDescription})
+		}
+		if newShortDescription != "" {
+			changes = append(changes, MetaChange{Key: "short_description", Old: product.ShortDescription, New: newShortDescription})
+		}
+		r.outputMu.Lock()
+		PrintMetaDiff(os.Stdout, product, changes)
+		r.outputMu.Unlock()
+		return nil, nil
+	}
+
+	skipThisProduct := false
+
+	if r.opts.Prompt {
+		r.outputMu.Lock()
+		if newDescription != "" {
+			fmt.Println("Description: " + newDescription)
+		}
+		if newShortDescription != "" {
+			fmt.Println("Short Description: " + newShortDescription)
+		}
+		fmt.Println("Meta Title: " + metaTitle)
+		fmt.Println("Meta Description: " + metaDescription)
+		if r.conf.FocusKeyphrase {
+			fmt.Println("Focus Keyphrase: " + focusKeyphrase)
+		}
+		if googleCategory != "" {
+			fmt.Println("Google Product Category: " + googleCategory)
+		}
+		for {
+			fmt.Println("Do you approve these values? (y/n, s to skip this product permanently): ")
+			input, _ := r.reader.ReadString('\n')
+			input = strings.TrimSpace(input)
+
+			if input == "y" {
+				break
+			} else if input == "n" {
+				fmt.Println("Skipping this product...")
+				skipThisProduct = true
+				break
+			} else if input == "s" {
+				fmt.Println("Skipping this product permanently...")
+				if err := r.skipList.add(productID, r.skipListPath); err != nil {
+					slog.Warn("Could not save skip list", "err", err)
+				}
+				skipThisProduct = true
+				break
+			} else {
+				fmt.Println("Invalid input. Please enter 'y', 'n' or 's'.")
+			}
+		}
+		r.outputMu.Unlock()
+	}
+
+	if skipThisProduct {
+		r.result.record(&r.result.Skipped, productID)
+		return nil, nil
+	}
+
+	update := &ProductUpdate{
+		Product:          product,
+		MetaData:         metaData,
+		Description:      newDescription,
+		ShortDescription: newShortDescription,
+	}
+	if r.wal != nil {
+		if err := r.wal.add(update); err != nil {
+			slog.Warn("Could not buffer update", "product_id", productID, "err", err)
+		}
+	}
+	return update, nil
+}
+
+// write sends an update to WooCommerce and records the outcome.
+func (r *seoRun) write(u *ProductUpdate) {
+	productID := int(u.Product.ID)
+
+	if r.export != nil {
+		r.export.add(u)
+		return
+	}
+
+	// An interrupted run leaves the rest of its updates in the write-ahead
+	// log for the next one and records nothing about them.
+	if r.ctx.Err() != nil {
+		slog.Info("Not updating product: run interrupted", "product_id", productID)
+		return
+	}
+	if err := r.breaker.allow(r.ctx); err != nil {
+		if r.ctx.Err() != nil {
+			slog.Info("Not updating product: run interrupted", "product_id", productID)
+			return
+		}
+		slog.Error("Not updating product", "product_id", productID, "err", err)
+		r.result.record(&r.result.Failed, productID)
+		r.audit.add(u.auditRecord(err))
+		return
+	}
+
+	ctx, cancel := productContext(r.ctx, r.conf)
+	defer cancel()
+
+	err := writeProductUpdate(ctx, r.client, r.conf, productID, u.payload(), u.MetaData)
+	if err != nil && r.ctx.Err() != nil {
+		slog.Info("Update interrupted", "product_id", productID, "err", err)
+		return
+	}
+	r.breaker.record(err)
+	r.audit.add(u.auditRecord(err))
+	if err != nil {
+		slog.Error("Failed to update SEO", "product_id", productID, "err", err)
+		r.result.record(&r.result.Failed, productID)
+		return
+	}
+
+	slog.Info("Updated SEO", "product_id", productID)
+	r.result.record(&r.result.Updated, productID)
+	if r.wal != nil {
+		if err := r.wal.done(u.Product.ID); err != nil {
+			slog.Warn("Could not update write-ahead log", "err", err)
+		}
+	}
+
+	if err := r.tracker.markUpdated(productID, r.trackerFilepath); err != nil {
+		slog.Warn("Could not save SEO tracker file", "err", err)
+	}
+}
+
+const defaultTitleSeparator = " | "
+
+// countDescriptionRewrites returns how many products would have their
+// description or short description regenerated.
+func countDescriptionRewrites(conf *Config, pages [][]WooProduct) int {
+	n := 0
+	for _, products := range pages {
+		for _, product := range products {
+			input, err := seoInputFromProduct(conf, product)
+			if err != nil {
+				continue
+			}
+			if len(input.Description) < conf.DescriptionMinLength || len(input.ShortDescription) < conf.ShortDescriptionMinLength {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// minTitleBudget is the fewest meta title characters title_suffix may leave
+// for the generated part.
+const minTitleBudget = 20
+
+// titleSuffix is the text appended to every meta title, separator included,
+// or "" when no title_suffix is configured.
+func (c *Config) titleSuffix() string {
+	if c.TitleSuffix == "" {
+		return ""
+	}
+	return c.TitleSeparator + c.TitleSuffix
+}
+
+// productContext bounds the work of one pipeline stage on one product by
+// conf.PerProductTimeout, so a hung request fails that product instead of
+// holding its worker, and ends it when the run's parent context is
+// cancelled. A negative timeout disables the limit.
+func productContext(parent context.Context, conf *Config) (context.Context, context.CancelFunc) {
+	if conf.PerProductTimeout > 0 {
+		return context.WithTimeout(parent, conf.PerProductTimeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// runPipeline feeds products through genWorkers generators and writeWorkers
+// writers connected by a channel. finished is called once per product that
+// leaves the pipeline. The first generation error
+// stops new products from being fed; updates already generated are still
+// written before it is returned.
+func runPipeline(
+	products []WooProduct,
+	genWorkers, writeWorkers int,
+	generate func(WooProduct) (*ProductUpdate, error),
+	write func(*ProductUpdate),
+	finished func(product WooProduct),
+) error {
+	var (
+		firstErr error
+		errOnce  sync.Once
+		stop     = make(chan struct{})
+	)
+
+	in := make(chan WooProduct)
+	go func() {
+		defer close(in)
+		for _, product := range products {
+			select {
+			case in <- product:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	updates := make(chan *ProductUpdate, max(writeWorkers, 1))
+	var genWG sync.WaitGroup
+	for i := 0; i < max(genWorkers, 1); i++ {
+		genWG.Add(1)
+		go func() {
+			defer genWG.Done()
+			for product := range in {
+				update, err := generate(product)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						close(stop)
+					})
+					continue
+				}
+				if update == nil {
+					finished(product)
+					continue
+				}
+				updates <- update
+			}
+		}()
+	}
+	go func() {
+		genWG.Wait()
+		close(updates)
+	}()
+
+	var writeWG sync.WaitGroup
+	for i := 0; i < max(writeWorkers, 1); i++ {
+		writeWG.Add(1)
+		go func() {
+			defer writeWG.Done()
+			for update := range updates {
+				write(update)
+				finished(update.Product)
+			}
+		}()
+	}
+	writeWG.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,355 @@
+package wooh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestUpdateSEOCancelledMidWrite(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Walnut Board"))
+	writing := make(chan struct{}, 2)
+	release := make(chan struct{})
+	conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/products/2") {
+			// A write that hangs until it is cancelled.
+			writing <- struct{}{}
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		store.handle(w, r)
+	})
+	t.Cleanup(func() { close(release) })
+	conf.OpenAIStub = true
+	conf.OpenAIConcurrency = 1
+	conf.WooConcurrency = 1
+	conf.PerProductTimeout = time.Hour
+	conf.AuditLog = t.TempDir() + "/audit.jsonl"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-writing
+		cancel()
+	}()
+
+	done := make(chan error, 1)
+	var result *SEOResult
+	go func() {
+		var err error
+		result, err = UpdateSEO(conf, SEOOptions{Context: ctx, Quiet: true})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("UpdateSEO = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("UpdateSEO kept waiting on the in-flight write after the run was cancelled")
+	}
+	if len(result.Failed) > 0 || len(result.Updated) != 1 {
+		t.Errorf("failed %v, updated %v; want only product 1 updated", result.Failed, result.Updated)
+	}
+	audit, err := os.ReadFile(conf.AuditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(audit)), "\n"); len(lines) != 1 {
+		t.Errorf("audit log has %d records, want 1 for product 1:\n%s", len(lines), audit)
+	}
+
+	// The interrupted write is kept for the next run, which resumes.
+	wal, err := openWAL(mustCachePath(t, conf, conf.WALFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unwritten := wal.unwritten()
+	wal.close()
+	if len(unwritten) != 1 || unwritten[0].Product.ID != 2 {
+		t.Errorf("write-ahead log holds %d updates, want product 2's", len(unwritten))
+	}
+	state, err := LoadRunState(mustCachePath(t, conf, conf.RunStateFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.done(1) || state.done(2) {
+		t.Errorf("run state processed %v, want only product 1", state.ProcessedIDs)
+	}
+}
+
+func mustCachePath(t *testing.T, conf *Config, name string) string {
+	t.Helper()
+	path, err := CachePath(conf, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCountDescriptionRewrites(t *testing.T) {
+	conf := &Config{DescriptionMinLength: 20, ShortDescriptionMinLength: 10}
+	long := "<p>A description long enough to keep.</p>"
+	tests := []struct {
+		name     string
+		products []WooProduct
+		want     int
+	}{
+		{"none", nil, 0},
+		{"both long enough", []WooProduct{{Description: long, ShortDescription: long}}, 0},
+		{"short description too short", []WooProduct{{Description: long, ShortDescription: "Oak"}}, 1},
+		{"description too short", []WooProduct{{Description: "Oak", ShortDescription: long}}, 1},
+		{"both too short counts once", []WooProduct{{Description: "Oak", ShortDescription: "Oak"}}, 1},
+		{"across pages", []WooProduct{{Description: "Oak"}, {Description: long, ShortDescription: long}, {ShortDescription: long}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countDescriptionRewrites(conf, [][]WooProduct{tt.products}); got != tt.want {
+				t.Errorf("countDescriptionRewrites = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunPipelineStageLimits(t *testing.T) {
+	tests := []struct {
+		name                     string
+		genWorkers, writeWorkers int
+	}{
+		{"more generators than writers", 8, 2},
+		{"more writers than generators", 2, 4},
+		{"one each", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var products []WooProduct
+			for id := 1; id <= 40; id++ {
+				products = append(products, WooProduct{ID: int64(id)})
+			}
+
+			// peak records the most calls seen in flight at once.
+			var mu sync.Mutex
+			inFlight := map[string]int{}
+			peak := map[string]int{}
+			enter := func(stage string) func() {
+				mu.Lock()
+				inFlight[stage]++
+				peak[stage] = max(peak[stage], inFlight[stage])
+				mu.Unlock()
+				time.Sleep(2 * time.Millisecond)
+				return func() {
+					mu.Lock()
+					inFlight[stage]--
+					mu.Unlock()
+				}
+			}
+
+			var finished atomic.Int32
+			err := runPipeline(products, tt.genWorkers, tt.writeWorkers,
+				func(p WooProduct) (*ProductUpdate, error) {
+					defer enter("generate")()
+					return &ProductUpdate{Product: p}, nil
+				},
+				func(*ProductUpdate) { defer enter("write")() },
+				func(WooProduct) { finished.Add(1) },
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if finished.Load() != int32(len(products)) {
+				t.Errorf("finished %d products, want %d", finished.Load(), len(products))
+			}
+			if peak["generate"] > tt.genWorkers || peak["write"] > tt.writeWorkers {
+				t.Errorf("peak generate %d (limit %d), write %d (limit %d)", peak["generate"], tt.genWorkers, peak["write"], tt.writeWorkers)
+			}
+			if peak["generate"] < 2 && tt.genWorkers > 1 {
+				t.Errorf("generation never ran in parallel (peak %d)", peak["generate"])
+			}
+		})
+	}
+}
+
+func TestRunPipelineStopsAfterGenerateError(t *testing.T) {
+	var products []WooProduct
+	for id := 1; id <= 100; id++ {
+		products = append(products, WooProduct{ID: int64(id)})
+	}
+	boom := errors.New("boom")
+	var generated, written atomic.Int32
+	err := runPipeline(products, 1, 1,
+		func(p WooProduct) (*ProductUpdate, error) {
+			generated.Add(1)
+			if p.ID == 3 {
+				return nil, boom
+			}
+			return &ProductUpdate{Product: p}, nil
+		},
+		func(*ProductUpdate) { written.Add(1) },
+		func(WooProduct) {},
+	)
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+	// A product already handed over when the error lands may still go
+	// through, but feeding must stop well before the end.
+	if generated.Load() >= int32(len(products)) {
+		t.Errorf("kept generating after the error: %d products", generated.Load())
+	}
+	if written.Load() < 2 {
+		t.Errorf("wrote %d updates, want the 2 generated before the error", written.Load())
+	}
+}
+
+func TestRunPipelineDrains(t *testing.T) {
+	tests := []struct {
+		name                     string
+		products                 int
+		genWorkers, writeWorkers int
+	}{
+		{"no products", 0, 4, 2},
+		{"one product", 1, 4, 2},
+		{"many products", 50, 4, 2},
+		{"zero workers run one each", 10, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var products []WooProduct
+			for id := 1; id <= tt.products; id++ {
+				products = append(products, WooProduct{ID: int64(id)})
+			}
+
+			var mu sync.Mutex
+			var written []int64
+			finished := map[int64]int{}
+			err := runPipeline(products, tt.genWorkers, tt.writeWorkers,
+				func(p WooProduct) (*ProductUpdate, error) {
+					// Even products have nothing to write.
+					if p.ID%2 == 0 {
+						return nil, nil
+					}
+					return &ProductUpdate{Product: p}, nil
+				},
+				func(u *ProductUpdate) {
+					mu.Lock()
+					written = append(written, u.Product.ID)
+					mu.Unlock()
+				},
+				func(p WooProduct) {
+					mu.Lock()
+					finished[p.ID]++
+					mu.Unlock()
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			slices.Sort(written)
+			var wantWritten []int64
+			for id := int64(1); id <= int64(tt.products); id += 2 {
+				wantWritten = append(wantWritten, id)
+			}
+			if !slices.Equal(written, wantWritten) {
+				t.Errorf("wrote %v, want %v", written, wantWritten)
+			}
+			if len(finished) != tt.products {
+				t.Errorf("finished %d products, want %d", len(finished), tt.products)
+			}
+			for id, n := range finished {
+				if n != 1 {
+					t.Errorf("product %d finished %d times", id, n)
+				}
+			}
+		})
+	}
+}
+
+func TestRunPipelineReturnsFirstError(t *testing.T) {
+	var products []WooProduct
+	for id := 1; id <= 20; id++ {
+		products = append(products, WooProduct{ID: int64(id)})
+	}
+	errA, errB := errors.New("a"), errors.New("b")
+	err := runPipeline(products, 4, 2,
+		func(p WooProduct) (*ProductUpdate, error) {
+			switch {
+			case p.ID%3 == 0:
+				return nil, errA
+			case p.ID%5 == 0:
+				return nil, errB
+			}
+			return &ProductUpdate{Product: p}, nil
+		},
+		func(*ProductUpdate) {},
+		func(WooProduct) {},
+	)
+	if !errors.Is(err, errA) && !errors.Is(err, errB) {
+		t.Fatalf("err = %v, want one of the generate errors", err)
+	}
+}
+
+func TestUpdateSEOPerProductTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	tests := []struct {
+		name      string
+		slowGen   bool // generation of product 2 hangs
+		slowWrite bool // the write of product 2 hangs
+	}{
+		{"slow generation", true, false},
+		{"slow write", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Slow Board"))
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.slowWrite && r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/products/2") {
+					// The request context ends with the client only once the
+					// body has been read.
+					io.Copy(io.Discard, r.Body)
+					select {
+					case <-r.Context().Done():
+					case <-time.After(5 * time.Second):
+					}
+					return
+				}
+				store.handle(w, r)
+			})
+			conf.PerProductTimeout = timeout
+			gen := newFakeGenerator(t, func(req chatRequest) openai.ChatCompletionChoice {
+				if tt.slowGen && strings.Contains(req.Messages[len(req.Messages)-1].Content, "Slow Board") {
+					time.Sleep(5 * timeout)
+				}
+				return textChoice(metaJSON(map[string]string{"meta_title": "Board", "meta_description": "Solid board."}))
+			})
+			gen.use(conf)
+
+			start := time.Now()
+			result, err := UpdateSEO(conf, SEOOptions{Quiet: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(result.Failed, []int{2}) || !slices.Equal(result.Updated, []int{1}) {
+				t.Errorf("failed %v, updated %v; want [2], [1]", result.Failed, result.Updated)
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("run took %s; the hung product held its worker", elapsed)
+			}
+			if store.product(2).MetaData.YoastTitle() != "" {
+				t.Error("timed-out product was written")
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package wooh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// LoadProfiles reads every config listed under conf.Profiles. Relative
+// paths are resolved against the directory of configPath. A profile
+// without its own cache_dir gets <user cache dir>/wooh/<name> so stores do
+// not share caches or trackers.
+func LoadProfiles(conf *Config, configPath string) ([]*Config, error) {
+	if len(conf.Profiles) == 0 {
+		return nil, fmt.Errorf("no profiles defined in %s", configPath)
+	}
+	absConfig, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	baseDir := filepath.Dir(absConfig)
+
+	names := make([]string, 0, len(conf.Profiles))
+	for name := range conf.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	configs := make([]*Config, 0, len(names))
+	for _, name := range names {
+		path := conf.Profiles[name]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		profile, err := ReadConfig(path)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", name, err)
+		}
+		if profile.CacheDir == "" {
+			userCacheDir, err := os.UserCacheDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve user cache dir: %w", err)
+			}
+			profile.CacheDir = filepath.Join(userCacheDir, "wooh", name)
+		}
+		configs = append(configs, profile)
+	}
+	return configs, nil
+}
+
+// RunAcross runs op for every config concurrently and returns the failures
+// joined together, each prefixed with its site.
+func RunAcross(configs []*Config, op func(*Config) error) error {
+	errs := make([]error, len(configs))
+	var wg sync.WaitGroup
+	for i, conf := range configs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := op(conf); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", conf.Site, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,98 @@
+package wooh
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	elsewhere := t.TempDir()
+	files := map[string]string{
+		filepath.Join(dir, "stores", "beta.yaml"): "site: beta.example\n",
+		filepath.Join(elsewhere, "alpha.yaml"):    "site: alpha.example\ncache_dir: /srv/cache/alpha\n",
+	}
+	for path, body := range files {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf := &Config{Profiles: map[string]string{
+		"beta":  "stores/beta.yaml",
+		"alpha": filepath.Join(elsewhere, "alpha.yaml"),
+	}}
+
+	configs, err := LoadProfiles(conf, filepath.Join(dir, "wooh.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("loaded %d profiles, want 2", len(configs))
+	}
+	// Profiles load in name order, each with its own cache dir.
+	if configs[0].Site != "alpha.example" || configs[0].CacheDir != "/srv/cache/alpha" {
+		t.Errorf("alpha = site %q, cache %q", configs[0].Site, configs[0].CacheDir)
+	}
+	if want := filepath.Join(userCacheDir, "wooh", "beta"); configs[1].Site != "beta.example" || configs[1].CacheDir != want {
+		t.Errorf("beta = site %q, cache %q; want cache %q", configs[1].Site, configs[1].CacheDir, want)
+	}
+
+	if _, err := LoadProfiles(&Config{}, "wooh.yaml"); err == nil {
+		t.Error("LoadProfiles without profiles = nil error")
+	}
+	conf.Profiles["gamma"] = "missing.yaml"
+	if _, err := LoadProfiles(conf, filepath.Join(dir, "wooh.yaml")); err == nil || !strings.Contains(err.Error(), "profile gamma") {
+		t.Errorf("missing profile err = %v, want it to name the profile", err)
+	}
+}
+
+func TestRunAcross(t *testing.T) {
+	sites := []string{"a.example", "b.example", "c.example", "d.example"}
+	var configs []*Config
+	for _, site := range sites {
+		configs = append(configs, &Config{Site: site})
+	}
+	errB, errD := errors.New("timeout"), errors.New("unauthorized")
+
+	var mu sync.Mutex
+	ran := map[string]int{}
+	err := RunAcross(configs, func(conf *Config) error {
+		mu.Lock()
+		ran[conf.Site]++
+		mu.Unlock()
+		switch conf.Site {
+		case "b.example":
+			return errB
+		case "d.example":
+			return errD
+		}
+		return nil
+	})
+
+	for _, site := range sites {
+		if ran[site] != 1 {
+			t.Errorf("%s ran %d times, want 1", site, ran[site])
+		}
+	}
+	if !errors.Is(err, errB) || !errors.Is(err, errD) {
+		t.Fatalf("err = %v, want both failures", err)
+	}
+	if want := "b.example: timeout\nd.example: unauthorized"; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+
+	if err := RunAcross(configs[:1], func(*Config) error { return nil }); err != nil {
+		t.Errorf("RunAcross with no failures = %v", err)
+	}
+}
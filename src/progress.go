@@ -0,0 +1,72 @@
+package wooh
+
+import (
+	"log/slog"
+	"time"
+)
+
+// ProgressFunc is called after each processed product with the number done,
+// the number pending at the start of the run and an estimate of the time
+// remaining (zero until enough samples exist).
+type ProgressFunc func(done int, total int, eta time.Duration)
+
+const (
+	etaWindow        = 25 // products in the rolling average
+	progressLogEvery = 25 // log an ETA every N processed products
+)
+
+// etaEstimator keeps a rolling average of the wall-clock time between
+// finished products. With several workers running, products finish faster
+// than any one of them takes, so the gaps measure the run's real throughput.
+type etaEstimator struct {
+	samples []time.Duration
+	next    int
+	last    time.Time
+}
+
+// newETAEstimator returns an estimator for a run started at start.
+func newETAEstimator(start time.Time) *etaEstimator {
+	return &etaEstimator{last: start}
+}
+
+// Finished records a product finished at t.
+func (e *etaEstimator) Finished(t time.Time) {
+	e.Add(t.Sub(e.last))
+	e.last = t
+}
+
+func (e *etaEstimator) Add(d time.Duration) {
+	if len(e.samples) < etaWindow {
+		e.samples = append(e.samples, d)
+		return
+	}
+	e.samples[e.next] = d
+	e.next = (e.next + 1) % etaWindow
+}
+
+func (e *etaEstimator) Average() time.Duration {
+	if len(e.samples) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range e.samples {
+		sum += d
+	}
+	return sum / time.Duration(len(e.samples))
+}
+
+func (e *etaEstimator) Estimate(remaining int) time.Duration {
+	if remaining <= 0 {
+		return 0
+	}
+	return e.Average() * time.Duration(remaining)
+}
+
+// logProgress is the default ProgressFunc: it logs an ETA every
+// progressLogEvery products.
+func logProgress(done int, total int, eta time.Duration) {
+	if done%progressLogEvery != 0 || done == total {
+		return
+	}
+	slog.Info("Progress", "done", done, "total", total, "eta", eta.Round(time.Second))
+}
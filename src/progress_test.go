@@ -0,0 +1,71 @@
+package wooh
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestETAEstimator(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		took    time.Duration // per product, on one worker
+		want    time.Duration // for the last 8 of 16 products
+	}{
+		{"one worker", 1, time.Second, 8 * time.Second},
+		{"four workers", 4, time.Second, 2 * time.Second},
+		{"eight workers", 8, 4 * time.Second, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			eta := newETAEstimator(start)
+			// Every worker finishes one product per tt.took, all in step.
+			for i := 0; i < 8; i++ {
+				eta.Finished(start.Add(time.Duration(i/tt.workers+1) * tt.took))
+			}
+			if got := eta.Estimate(8); got != tt.want {
+				t.Errorf("Estimate(8) = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := newETAEstimator(time.Now()).Estimate(10); got != 0 {
+		t.Errorf("estimate without samples = %s, want 0", got)
+	}
+}
+
+func TestUpdateSEOProgressCountsUntracked(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Walnut Board"))
+	conf, _ := newTestStore(t, store.handle)
+	conf.OpenAIStub = true
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Three new products; the first two are tracked now.
+	for id := 3; id <= 5; id++ {
+		store.products[int64(id)] = testProduct(id, "Ash Board")
+	}
+	if err := os.Remove(mustCachePath(t, conf, conf.CacheFilename)); err != nil {
+		t.Fatal(err)
+	}
+	var calls [][2]int
+	var lastETA time.Duration
+	progress := func(done, total int, eta time.Duration) {
+		calls = append(calls, [2]int{done, total})
+		lastETA = eta
+	}
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true, Progress: progress}); err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]int{{1, 3}, {2, 3}, {3, 3}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("progress calls %v, want %v", calls, want)
+	}
+	if lastETA != 0 {
+		t.Errorf("ETA after the last product = %s, want 0", lastETA)
+	}
+}
@@ -0,0 +1,53 @@
+package wooh
+
+import "fmt"
+
+// ResyncProduct refetches one product, regenerates its SEO meta and writes
+// it back, regardless of the cache and of whether the tracker already has
+// it. The write is always verified.
+func ResyncProduct(conf *Config, id int) (ProductUpdate, error) {
+	for _, ignored := range conf.IgnoreIDs {
+		if ignored == id {
+			return ProductUpdate{}, fmt.Errorf("product %d is listed in ignore_ids", id)
+		}
+	}
+	product, err := GetProduct(conf, id)
+	if err != nil {
+		return ProductUpdate{}, err
+	}
+
+	verified := *conf
+	verified.VerifyWrites = true
+
+	trackerFilepath, err := CachePath(conf, conf.TrackerFilename)
+	if err != nil {
+		return ProductUpdate{}, err
+	}
+	tracker, err := TrackerLoad(trackerFilepath)
+	if err != nil {
+		return ProductUpdate{}, fmt.Errorf("failed to load SEO update tracker: %w", err)
+	}
+
+	run, err := newSEORun(&verified, SEOOptions{}, tracker, trackerFilepath, &SEOResult{})
+	if err != nil {
+		return ProductUpdate{}, err
+	}
+	defer run.close()
+
+	update, err := run.generate(product)
+	if err != nil {
+		return ProductUpdate{Product: product}, err
+	}
+	if update == nil {
+		if len(run.result.PolicySkipped) > 0 {
+			return ProductUpdate{Product: product}, fmt.Errorf("product %d: %w", id, ErrContentPolicy)
+		}
+		return ProductUpdate{Product: product}, fmt.Errorf("failed to generate SEO meta for product %d", id)
+	}
+
+	run.write(update)
+	if len(run.result.Failed) > 0 {
+		return *update, fmt.Errorf("failed to write SEO meta for product %d", id)
+	}
+	return *update, nil
+}
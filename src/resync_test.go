@@ -0,0 +1,62 @@
+package wooh
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResyncProduct(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        int
+		ignore    []int
+		wantErr   string
+		wantWrite []int64
+	}{
+		{name: "happy path", id: 7, wantWrite: []int64{7}},
+		{name: "not found", id: 8, wantErr: "error fetching product 8"},
+		{name: "ignored", id: 7, ignore: []int{7}, wantErr: "listed in ignore_ids"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(testProduct(7, "Oak Board"))
+			conf, _ := newTestStore(t, store.handle)
+			conf.OpenAIStub = true
+			conf.IgnoreIDs = tt.ignore
+			conf.AuditLog = t.TempDir() + "/audit.jsonl"
+
+			update, err := ResyncProduct(conf, tt.id)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResyncProduct = %v, want an error containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("ResyncProduct failed: %v", err)
+			}
+			if !slices.Equal(store.written(), tt.wantWrite) {
+				t.Errorf("wrote products %v, want %v", store.written(), tt.wantWrite)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+
+			if DiffMeta(store.product(7), update.MetaData) != nil {
+				t.Errorf("store does not hold the resynced meta")
+			}
+			// The run goes through the same write-ahead log and audit log as seo.
+			wal, err := openWAL(mustCachePath(t, conf, conf.WALFilename))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer wal.close()
+			if n := len(wal.unwritten()); n != 0 {
+				t.Errorf("write-ahead log holds %d unwritten updates", n)
+			}
+			if audit, err := os.ReadFile(conf.AuditLog); err != nil || !strings.Contains(string(audit), `"id":7`) {
+				t.Errorf("audit log = %q, %v; want product 7's write", audit, err)
+			}
+		})
+	}
+}
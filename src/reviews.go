@@ -0,0 +1,78 @@
+package wooh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxReviewSnippet bounds the review text quoted in the SEO prompt.
+const maxReviewSnippet = 200
+
+type Review struct {
+	ID          int64  `json:"id"`
+	ProductID   int64  `json:"product_id"`
+	Reviewer    string `json:"reviewer"`
+	Review      string `json:"review"` // HTML
+	Rating      int    `json:"rating"`
+	Verified    bool   `json:"verified"`
+	DateCreated string `json:"date_created"`
+}
+
+// GetProductReviews fetches the approved reviews of one product, newest first.
+func GetProductReviews(conf *Config, productID int) ([]Review, error) {
+	return getProductReviews(context.Background(), conf, productID)
+}
+
+func getProductReviews(ctx context.Context, conf *Config, productID int) ([]Review, error) {
+	reviews, err := paginate[Review](ctx, newClient(conf), conf, "products/reviews", map[string]string{
+		"product": fmt.Sprintf("%d", productID),
+		"status":  "approved",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("product %d: %w", productID, err)
+	}
+	return reviews, nil
+}
+
+// summarizeReviews returns the average rating of reviews and a Markdown
+// snippet of the best rated one, preferring the newest on ties.
+func summarizeReviews(reviews []Review) (average float64, snippet string) {
+	if len(reviews) == 0 {
+		return 0, ""
+	}
+
+	total := 0
+	best := reviews[0]
+	for _, r := range reviews {
+		total += r.Rating
+		if r.Rating > best.Rating {
+			best = r
+		}
+	}
+
+	text, err := cleanHTMLToMarkdown(best.Review, MarkdownOptions{})
+	if err != nil {
+		text = best.Review
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > maxReviewSnippet {
+		cut := strings.LastIndex(text[:maxReviewSnippet], " ")
+		if cut <= 0 {
+			cut = maxReviewSnippet
+		}
+		text = text[:cut] + "..."
+	}
+	return float64(total) / float64(len(reviews)), text
+}
+
+// addReviews puts the rating and top review of a product into input.
+func addReviews(ctx context.Context, conf *Config, input *SEOInput, productID int) error {
+	reviews, err := getProductReviews(ctx, conf, productID)
+	if err != nil {
+		return err
+	}
+	input.ReviewCount = len(reviews)
+	input.AverageRating, input.TopReview = summarizeReviews(reviews)
+	return nil
+}
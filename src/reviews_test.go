@@ -0,0 +1,102 @@
+package wooh
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// reviewsHandler serves the reviews of product 1 from the reviews endpoint
+// and passes everything else to next.
+func reviewsHandler(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/products/reviews") {
+			next(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-WP-TotalPages", "1")
+		if r.URL.Query().Get("product") != "1" || r.URL.Query().Get("status") != "approved" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[
+			{"id": 3, "product_id": 1, "reviewer": "Sam", "review": "<p>Lovely <strong>colour</strong>, easy to fit.</p>", "rating": 5, "verified": true},
+			{"id": 2, "product_id": 1, "reviewer": "Ali", "review": "<p>Fine.</p>", "rating": 4, "verified": false},
+			{"id": 1, "product_id": 1, "reviewer": "Jo", "review": "<p>Arrived late.</p>", "rating": 3, "verified": true}
+		]`))
+	}
+}
+
+func TestGetProductReviews(t *testing.T) {
+	conf, _ := newTestStore(t, reviewsHandler(http.NotFound))
+	reviews, err := GetProductReviews(conf, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews) != 3 {
+		t.Fatalf("got %d reviews, want 3", len(reviews))
+	}
+	if r := reviews[0]; r.ID != 3 || r.ProductID != 1 || r.Reviewer != "Sam" || r.Rating != 5 || !r.Verified {
+		t.Errorf("first review = %+v", r)
+	}
+	if reviews, err := GetProductReviews(conf, 2); err != nil || len(reviews) != 0 {
+		t.Errorf("product without reviews = %v, %v", reviews, err)
+	}
+}
+
+func TestSummarizeReviews(t *testing.T) {
+	long := strings.Repeat("word ", 60)
+	tests := []struct {
+		name        string
+		reviews     []Review
+		wantAverage float64
+		wantSnippet string
+	}{
+		{"none", nil, 0, ""},
+		{"best rated wins, newest on ties", []Review{
+			{Review: "<p>Newest five.</p>", Rating: 5},
+			{Review: "<p>Older five.</p>", Rating: 5},
+			{Review: "<p>Two.</p>", Rating: 2},
+		}, 4, "Newest five."},
+		{"long review is cut at a word", []Review{{Review: "<p>" + long + "</p>", Rating: 4}}, 4,
+			strings.TrimSpace(long[:maxReviewSnippet-1]) + "..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			average, snippet := summarizeReviews(tt.reviews)
+			if math.Abs(average-tt.wantAverage) > 1e-9 || snippet != tt.wantSnippet {
+				t.Errorf("summarizeReviews = %v, %q; want %v, %q", average, snippet, tt.wantAverage, tt.wantSnippet)
+			}
+		})
+	}
+}
+
+func TestUpdateSEOIncludesReviews(t *testing.T) {
+	for _, include := range []bool{false, true} {
+		store := newFakeStore(testProduct(1, "Oak Board"))
+		conf, _ := newTestStore(t, reviewsHandler(store.handle))
+		conf.IncludeReviews = include
+		gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+			return textChoice(metaJSON(map[string]string{"meta_title": "Oak Board", "meta_description": "Solid oak board."}))
+		})
+		gen.use(conf)
+
+		if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+			t.Fatal(err)
+		}
+		sent := gen.sent()
+		if len(sent) == 0 {
+			t.Fatal("no meta generated")
+		}
+		prompt := sent[0].Messages[len(sent[0].Messages)-1].Content
+		hasRating := strings.Contains(prompt, "- Average Rating: 4.0 out of 5 from 3 reviews\n") &&
+			strings.Contains(prompt, `- Top Review: "Lovely **colour**, easy to fit."`)
+		if hasRating != include {
+			t.Errorf("include_reviews %v: prompt = %s", include, prompt)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package wooh
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// runStatePageSize is the number of products UpdateSEO treats as one page
+// of a resumable run.
+const runStatePageSize = 100
+
+// RunState is the position of an interrupted UpdateSEO run: the ID of the
+// last product of the last finished page, with products taken in ID order,
+// and the products processed since. It is removed once a run completes.
+type RunState struct {
+	LastID       int64     `json:"last_id"`
+	ProcessedIDs []int     `json:"processed_ids"`
+	StartedAt    time.Time `json:"started_at"`
+
+	mu        sync.Mutex
+	path      string
+	processed map[int]bool
+}
+
+// LoadRunState reads the run state at path, or starts a fresh one when
+// there is none.
+func LoadRunState(path string) (*RunState, error) {
+	s := &RunState{StartedAt: time.Now(), path: path, processed: make(map[int]bool)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read run state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse run state: %w", err)
+	}
+	for _, id := range s.ProcessedIDs {
+		s.processed[id] = true
+	}
+	return s, nil
+}
+
+// done reports whether the run already got to product id: it is at or
+// before the cursor, or was processed since.
+func (s *RunState) done(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return id <= s.LastID || s.processed[int(id)]
+}
+
+func (s *RunState) markProcessed(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processed[id] = true
+	s.ProcessedIDs = append(s.ProcessedIDs, id)
+	return s.save()
+}
+
+// advance moves the cursor past lastID, forgetting the IDs processed
+// before it.
+func (s *RunState) advance(lastID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LastID = lastID
+	s.ProcessedIDs = nil
+	s.processed = make(map[int]bool)
+	return s.save()
+}
+
+func (s *RunState) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func clearRunState(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove run state: %w", err)
+	}
+	return nil
+}
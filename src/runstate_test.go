@@ -0,0 +1,67 @@
+package wooh
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestRunStateDone(t *testing.T) {
+	state := &RunState{LastID: 200, processed: map[int]bool{205: true}}
+	tests := []struct {
+		id   int64
+		want bool
+	}{
+		{1, true},
+		{200, true},
+		{201, false},
+		{205, true},
+		{300, false},
+	}
+	for _, tt := range tests {
+		if got := state.done(tt.id); got != tt.want {
+			t.Errorf("done(%d) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateSEOResumesAfterCursor(t *testing.T) {
+	// The first run finished pages 1 and 2 (products 1-200) and product 205
+	// of page 3. Since then product 50 was deleted and 301 added, so the
+	// cached list no longer lines up with the old pages.
+	var products []map[string]interface{}
+	for id := 1; id <= 301; id++ {
+		if id != 50 {
+			products = append(products, testProduct(id, "Board"))
+		}
+	}
+	store := newFakeStore(products...)
+	conf, _ := newTestStore(t, store.handle)
+	conf.OpenAIStub = true
+
+	data, _ := json.Marshal(RunState{LastID: 200, ProcessedIDs: []int{205}})
+	statePath := mustCachePath(t, conf, conf.RunStateFilename)
+	if err := os.WriteFile(statePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []int64
+	for id := int64(201); id <= 301; id++ {
+		if id != 205 {
+			want = append(want, id)
+		}
+	}
+	got := store.written()
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("wrote %d products %v..., want %d starting at 201", len(got), got[:min(3, len(got))], len(want))
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("run state not cleared after the run completed: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package wooh
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	idealTitleMin       = 30
+	idealTitleMax       = 60
+	idealDescriptionMin = 120
+	idealDescriptionMax = 160
+)
+
+type SEOSubScore struct {
+	Name   string
+	Score  int
+	Max    int
+	Reason string
+}
+
+type SEOScore struct {
+	Total int // 0-100
+	Parts []SEOSubScore
+}
+
+// ScoreSEO rates a product's Yoast meta: title and description lengths,
+// whether a focus keyphrase is set and whether it appears in both.
+func ScoreSEO(p WooProduct) SEOScore {
+	return scoreMeta(
+		p.MetaData.YoastTitle(),
+		p.MetaData.YoastDesc(),
+		p.MetaData.YoastFocusKW(),
+	)
+}
+
+func scoreMeta(title, description, focusKW string) SEOScore {
+	var score SEOScore
+	add := func(name string, got, max int, reason string) {
+		score.Parts = append(score.Parts, SEOSubScore{Name: name, Score: got, Max: max, Reason: reason})
+		score.Total += got
+	}
+
+	add(scoreLength("title", len(title), idealTitleMin, idealTitleMax, 30))
+	add(scoreLength("description", len(description), idealDescriptionMin, idealDescriptionMax, 30))
+
+	kw := strings.ToLower(strings.TrimSpace(focusKW))
+	if kw == "" {
+		add("focus keyphrase", 0, 10, "no focus keyphrase set")
+		add("keyphrase in title", 0, 15, "no focus keyphrase to check")
+		add("keyphrase in description", 0, 15, "no focus keyphrase to check")
+		return score
+	}
+	add("focus keyphrase", 10, 10, "focus keyphrase set")
+	if strings.Contains(strings.ToLower(title), kw) {
+		add("keyphrase in title", 15, 15, "title contains the focus keyphrase")
+	} else {
+		add("keyphrase in title", 0, 15, "title is missing the focus keyphrase")
+	}
+	if strings.Contains(strings.ToLower(description), kw) {
+		add("keyphrase in description", 15, 15, "description contains the focus keyphrase")
+	} else {
+		add("keyphrase in description", 0, 15, "description is missing the focus keyphrase")
+	}
+	return score
+}
+
+// scoreLength gives full marks inside [min, max], half when the text exists
+// but is too short or too long, and nothing when it is missing.
+func scoreLength(name string, length, min, max, points int) (string, int, int, string) {
+	switch {
+	case length == 0:
+		return name, 0, points, fmt.Sprintf("%s is missing", name)
+	case length < min:
+		return name, points / 2, points, fmt.Sprintf("%s is short (%d < %d chars)", name, length, min)
+	case length > max:
+		return name, points / 2, points, fmt.Sprintf("%s is long (%d > %d chars)", name, length, max)
+	}
+	return name, points, points, fmt.Sprintf("%s length is ideal (%d chars)", name, length)
+}
+
+func (s SEOScore) String() string {
+	var missed []string
+	for _, p := range s.Parts {
+		if p.Score < p.Max {
+			missed = append(missed, p.Reason)
+		}
+	}
+	if len(missed) == 0 {
+		return fmt.Sprintf("%d/100", s.Total)
+	}
+	return fmt.Sprintf("%d/100 (%s)", s.Total, strings.Join(missed, "; "))
+}
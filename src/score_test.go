@@ -0,0 +1,141 @@
+package wooh
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestScoreSEO(t *testing.T) {
+	idealTitle := "Solid Oak Flooring Board | Oiled Finish Shop" // 44 chars
+	idealDesc := strings.Repeat("Oak flooring, oiled and ready to fit. ", 4)[:140]
+	tests := []struct {
+		name      string
+		title     string
+		desc      string
+		keyphrase string
+		want      map[string]int // part name -> score
+		total     int
+	}{
+		{
+			name: "all criteria met", title: idealTitle, desc: idealDesc, keyphrase: "oak flooring",
+			want:  map[string]int{"title": 30, "description": 30, "focus keyphrase": 10, "keyphrase in title": 15, "keyphrase in description": 15},
+			total: 100,
+		},
+		{
+			name: "no meta",
+			want: map[string]int{"title": 0, "description": 0, "focus keyphrase": 0, "keyphrase in title": 0, "keyphrase in description": 0},
+		},
+		{
+			name: "short title, long description", title: "Oak Board", desc: strings.Repeat("x", 200), keyphrase: "oak board",
+			want:  map[string]int{"title": 15, "description": 15, "focus keyphrase": 10, "keyphrase in title": 15, "keyphrase in description": 0},
+			total: 55,
+		},
+		{
+			name: "keyphrase missing from title", title: idealTitle, desc: idealDesc + " Walnut", keyphrase: "WALNUT",
+			want:  map[string]int{"title": 30, "description": 30, "focus keyphrase": 10, "keyphrase in title": 0, "keyphrase in description": 15},
+			total: 85,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p WooProduct
+			for key, value := range map[string]string{yoastTitleKey: tt.title, yoastDescKey: tt.desc, yoastFocusKWKey: tt.keyphrase} {
+				if value != "" {
+					p.MetaData.Set(key, value)
+				}
+			}
+
+			score := ScoreSEO(p)
+			if score.Total != tt.total {
+				t.Errorf("total = %d, want %d (%s)", score.Total, tt.total, score)
+			}
+			if len(score.Parts) != len(tt.want) {
+				t.Fatalf("got %d parts, want %d", len(score.Parts), len(tt.want))
+			}
+			for _, part := range score.Parts {
+				if want, ok := tt.want[part.Name]; !ok || part.Score != want {
+					t.Errorf("%s scored %d, want %d (%s)", part.Name, part.Score, want, part.Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestSEOScoreString(t *testing.T) {
+	full := SEOScore{Total: 100, Parts: []SEOSubScore{{Name: "title", Score: 30, Max: 30, Reason: "ok"}}}
+	if got := full.String(); got != "100/100" {
+		t.Errorf("String() = %q, want 100/100", got)
+	}
+	partial := SEOScore{Total: 45, Parts: []SEOSubScore{
+		{Name: "title", Score: 30, Max: 30, Reason: "title length is ideal"},
+		{Name: "description", Score: 15, Max: 30, Reason: "description is short"},
+		{Name: "focus keyphrase", Score: 0, Max: 10, Reason: "no focus keyphrase set"},
+	}}
+	if got, want := partial.String(), "45/100 (description is short; no focus keyphrase set)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateSEOOnlyImprove(t *testing.T) {
+	const (
+		goodTitle = "Solid Oak Board, Oiled and Ready"
+		goodDesc  = "A solid oak board, oiled and ready to fit in any room of the house. Cut to size in our workshop and delivered flat-packed to your door."
+	)
+	withMeta := func(id int, title, desc string) map[string]interface{} {
+		p := testProduct(id, "Oak Board")
+		p["meta_data"] = []interface{}{
+			map[string]interface{}{"key": yoastTitleKey, "value": title},
+			map[string]interface{}{"key": yoastDescKey, "value": desc},
+			map[string]interface{}{"key": yoastFocusKWKey, "value": "oak board"},
+		}
+		return p
+	}
+
+	tests := []struct {
+		margin      int
+		wantUpdated []int
+		wantSkipped []int
+	}{
+		{0, []int{2, 3}, []int{1}},
+		{20, []int{2}, []int{1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("margin %d", tt.margin), func(t *testing.T) {
+			store := newFakeStore(
+				withMeta(1, goodTitle+" Oak Board", goodDesc), // already scores 100
+				testProduct(2, "Ash Board"),                   // no meta at all
+				withMeta(3, "Oak Board", goodDesc),            // 85: the title is short
+			)
+			conf, _ := newTestStore(t, store.handle)
+			conf.ImproveMargin = tt.margin
+			newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+				return textChoice(metaJSON(map[string]string{"meta_title": "Solid Oak Board, Oiled and Ready to Fit", "meta_description": goodDesc}))
+			}).use(conf)
+
+			if got := ScoreSEO(store.product(3)).Total; got != 85 {
+				t.Fatalf("product 3 scores %d, want 85", got)
+			}
+			result, err := UpdateSEO(conf, SEOOptions{OnlyImprove: true, Quiet: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(result.Updated)
+			slices.Sort(result.Skipped)
+			if !slices.Equal(result.Updated, tt.wantUpdated) || !slices.Equal(result.Skipped, tt.wantSkipped) {
+				t.Errorf("updated %v, skipped %v; want %v, %v", result.Updated, result.Skipped, tt.wantUpdated, tt.wantSkipped)
+			}
+			written := store.written()
+			slices.Sort(written)
+			if len(written) != len(tt.wantUpdated) {
+				t.Errorf("wrote %v, want %v", written, tt.wantUpdated)
+			}
+			if got := store.product(1).MetaData.YoastTitle(); got != goodTitle+" Oak Board" {
+				t.Errorf("better meta replaced: %q", got)
+			}
+		})
+	}
+}
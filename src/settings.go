@@ -0,0 +1,184 @@
+package wooh
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// StoreSettings are the general WooCommerce settings that affect how prices
+// are written and shown.
+type StoreSettings struct {
+	Currency          string // ISO 4217 code, e.g. "GBP"
+	CurrencyPosition  string // left, right, left_space or right_space
+	ThousandSeparator string
+	DecimalSeparator  string
+	Decimals          int
+	BaseCountry       string // ISO 3166-1 alpha-2
+	BaseState         string // empty when the store has no state set
+	City              string
+	Postcode          string
+}
+
+type wooSetting struct {
+	ID    string      `json:"id"`
+	Value interface{} `json:"value"`
+}
+
+// GetStoreSettings reads the store's general settings.
+func GetStoreSettings(conf *Config) (StoreSettings, error) {
+	resp, err := newClient(conf).R().
+		SetHeader("Accept", "application/json").
+		Get(wooEndpoint(conf, "settings/general"))
+	if err != nil {
+		return StoreSettings{}, fmt.Errorf("failed to fetch store settings: %w", err)
+	}
+	if resp.IsError() {
+		return StoreSettings{}, fmt.Errorf("error fetching store settings: %w", apiError(resp))
+	}
+
+	var raw []wooSetting
+	if err := decodeJSON(resp, &raw); err != nil {
+		return StoreSettings{}, fmt.Errorf("failed to parse store settings: %w", err)
+	}
+	return parseStoreSettings(raw), nil
+}
+
+func parseStoreSettings(raw []wooSetting) StoreSettings {
+	values := make(map[string]string, len(raw))
+	for _, s := range raw {
+		if s.Value != nil {
+			values[s.ID] = fmt.Sprint(s.Value)
+		}
+	}
+
+	settings := StoreSettings{
+		Currency:          values["woocommerce_currency"],
+		CurrencyPosition:  values["woocommerce_currency_pos"],
+		ThousandSeparator: values["woocommerce_price_thousand_sep"],
+		DecimalSeparator:  values["woocommerce_price_decimal_sep"],
+		Decimals:          2,
+		City:              values["woocommerce_store_city"],
+		Postcode:          values["woocommerce_store_postcode"],
+	}
+	if n, err := strconv.Atoi(values["woocommerce_price_num_decimals"]); err == nil {
+		settings.Decimals = n
+	}
+	if settings.DecimalSeparator == "" {
+		settings.DecimalSeparator = "."
+	}
+	// The default country is stored as "GB" or "US:CA".
+	country, state, _ := strings.Cut(values["woocommerce_default_country"], ":")
+	settings.BaseCountry, settings.BaseState = country, state
+	return settings
+}
+
+// FormatPrice formats amount with the store's separators and decimals,
+// without the currency symbol.
+func (s StoreSettings) FormatPrice(amount float64) string {
+	formatted := strconv.FormatFloat(amount, 'f', s.Decimals, 64)
+	whole, frac, _ := strings.Cut(formatted, ".")
+
+	negative := strings.HasPrefix(whole, "-")
+	whole = strings.TrimPrefix(whole, "-")
+	if s.ThousandSeparator != "" {
+		var b strings.Builder
+		for i, digit := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				b.WriteString(s.ThousandSeparator)
+			}
+			b.WriteRune(digit)
+		}
+		whole = b.String()
+	}
+	if negative {
+		whole = "-" + whole
+	}
+
+	if frac == "" {
+		return whole
+	}
+	return whole + s.DecimalSeparator + frac
+}
+
+// currencyDecimals lists the ISO 4217 currencies whose minor unit is not two
+// decimals.
+var currencyDecimals = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+var priceRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// normalizePrice checks that s is a plain non-negative decimal and rounds it
+// half up to the minor unit of currency, e.g. "9.9" is "9.90" in GBP and
+// "10" in JPY. An empty price stays empty.
+func normalizePrice(s string, currency string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(s, "-") {
+		return "", fmt.Errorf("price %q must not be negative", s)
+	}
+	if !priceRegex.MatchString(s) {
+		return "", fmt.Errorf("invalid price %q: expected digits with an optional '.' and decimals", s)
+	}
+	// Rounding the exact decimal keeps halves going up, as WooCommerce
+	// rounds them; through a float64, "10.5" yen would become 10.
+	amount, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return "", fmt.Errorf("invalid price %q", s)
+	}
+	decimals, ok := currencyDecimals[strings.ToUpper(currency)]
+	if !ok {
+		decimals = 2
+	}
+	return amount.FloatString(decimals), nil
+}
+
+// parseProductPrice reads a product price as stores hold it: "1299.00",
+// "1,299.00", "1.299,00", "£12.50" or "12,50". It reports false for an empty
+// or unreadable price.
+func parseProductPrice(s string) (float64, bool) {
+	s = strings.TrimFunc(strings.TrimSpace(s), func(r rune) bool {
+		return !('0' <= r && r <= '9')
+	})
+	if s == "" {
+		return 0, false
+	}
+	comma, dot := strings.LastIndex(s, ","), strings.LastIndex(s, ".")
+	switch {
+	case comma > dot && dot >= 0:
+		// "1.299,00": dots group thousands, the comma is the decimal mark.
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.Replace(s, ",", ".", 1)
+	case comma >= 0 && dot < 0 && strings.Count(s, ",") == 1 && len(s)-comma-1 <= 2:
+		// "12,50": a lone comma before one or two digits is a decimal comma.
+		s = strings.Replace(s, ",", ".", 1)
+	default:
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return amount, true
+}
+
+// filterByPrice keeps the products whose regular price lies within
+// [minPrice, maxPrice]. A bound of zero or less is open.
+func filterByPrice(products []WooProduct, minPrice, maxPrice float64) []WooProduct {
+	var kept []WooProduct
+	for _, p := range products {
+		price, ok := parseProductPrice(p.RegularPrice)
+		if !ok || (minPrice > 0 && price < minPrice) || (maxPrice > 0 && price > maxPrice) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept
+}
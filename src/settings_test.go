@@ -0,0 +1,276 @@
+package wooh
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGetStoreSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want StoreSettings
+	}{
+		{
+			name: "UK store",
+			body: `[
+				{"id": "woocommerce_store_city", "label": "City", "value": "London"},
+				{"id": "woocommerce_store_postcode", "value": "N1 7GU"},
+				{"id": "woocommerce_default_country", "value": "GB"},
+				{"id": "woocommerce_currency", "value": "GBP", "options": {"GBP": "Pound sterling (£)"}},
+				{"id": "woocommerce_currency_pos", "value": "left"},
+				{"id": "woocommerce_price_thousand_sep", "value": ","},
+				{"id": "woocommerce_price_decimal_sep", "value": "."},
+				{"id": "woocommerce_price_num_decimals", "value": "2"}
+			]`,
+			want: StoreSettings{Currency: "GBP", CurrencyPosition: "left", ThousandSeparator: ",", DecimalSeparator: ".",
+				Decimals: 2, BaseCountry: "GB", City: "London", Postcode: "N1 7GU"},
+		},
+		{
+			name: "country with state and no decimals",
+			body: `[
+				{"id": "woocommerce_default_country", "value": "US:CA"},
+				{"id": "woocommerce_currency", "value": "JPY"},
+				{"id": "woocommerce_currency_pos", "value": "right_space"},
+				{"id": "woocommerce_price_thousand_sep", "value": ""},
+				{"id": "woocommerce_price_num_decimals", "value": 0}
+			]`,
+			want: StoreSettings{Currency: "JPY", CurrencyPosition: "right_space", DecimalSeparator: ".",
+				Decimals: 0, BaseCountry: "US", BaseState: "CA"},
+		},
+		{
+			name: "missing values keep defaults",
+			body: `[{"id": "woocommerce_price_num_decimals", "value": null}]`,
+			want: StoreSettings{DecimalSeparator: ".", Decimals: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/settings/general") {
+					t.Errorf("requested %s, want settings/general", r.URL.Path)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.body))
+			})
+			settings, err := GetStoreSettings(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if settings != tt.want {
+				t.Errorf("settings = %+v\nwant %+v", settings, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetStoreSettingsError(t *testing.T) {
+	conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"code":"woocommerce_rest_cannot_view","message":"Sorry, you cannot list resources."}`))
+	})
+	if _, err := GetStoreSettings(conf); err == nil || !strings.Contains(err.Error(), "woocommerce_rest_cannot_view") {
+		t.Errorf("err = %v, want the API error", err)
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	uk := StoreSettings{ThousandSeparator: ",", DecimalSeparator: ".", Decimals: 2}
+	eu := StoreSettings{ThousandSeparator: ".", DecimalSeparator: ",", Decimals: 2}
+	jp := StoreSettings{ThousandSeparator: ",", DecimalSeparator: ".", Decimals: 0}
+	plain := StoreSettings{DecimalSeparator: ".", Decimals: 3}
+	tests := []struct {
+		settings StoreSettings
+		amount   float64
+		want     string
+	}{
+		{uk, 1299.5, "1,299.50"},
+		{uk, 999, "999.00"},
+		{uk, 1234567.891, "1,234,567.89"},
+		{uk, -1500, "-1,500.00"},
+		{eu, 1299.5, "1.299,50"},
+		{jp, 129950, "129,950"},
+		{plain, 12345.6789, "12345.679"},
+	}
+	for _, tt := range tests {
+		if got := tt.settings.FormatPrice(tt.amount); got != tt.want {
+			t.Errorf("FormatPrice(%v) with %+v = %q, want %q", tt.amount, tt.settings, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizePrice(t *testing.T) {
+	tests := []struct {
+		price    string
+		currency string
+		want     string
+		wantErr  string
+	}{
+		{"9.9", "GBP", "9.90", ""},
+		{" 12 ", "usd", "12.00", ""},
+		{"0.00", "", "0.00", ""},
+		{"", "GBP", "", ""},
+		{"1299.5", "JPY", "1300", ""},
+		{"10.5", "JPY", "11", ""},
+		{"1.005", "GBP", "1.01", ""},
+		{"12.3456", "KWD", "12.346", ""},
+		{"-5", "GBP", "", "must not be negative"},
+		{"-0.01", "JPY", "", "must not be negative"},
+		{"12,50", "EUR", "", "invalid price"},
+		{"£12", "GBP", "", "invalid price"},
+		{"1e3", "GBP", "", "invalid price"},
+		{"12.", "GBP", "", "invalid price"},
+	}
+	for _, tt := range tests {
+		got, err := normalizePrice(tt.price, tt.currency)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("normalizePrice(%q, %q) = %q, %v; want error %q", tt.price, tt.currency, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizePrice(%q, %q) = %q, %v; want %q", tt.price, tt.currency, got, err, tt.want)
+		}
+	}
+}
+
+func TestUploadNormalizesPriceToStoreCurrency(t *testing.T) {
+	tests := []struct {
+		currency string
+		dryRun   bool
+		want     string
+	}{
+		{"GBP", false, "10.50"},
+		{"JPY", false, "11"},
+		{"JPY", true, "10.50"}, // a dry run cannot ask the store
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s dry run %v", tt.currency, tt.dryRun), func(t *testing.T) {
+			store := &skuStore{}
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/settings/general") {
+					w.Header().Set("Content-Type", "application/json")
+					fmt.Fprintf(w, `[{"id": "woocommerce_currency", "value": %q}]`, tt.currency)
+					return
+				}
+				store.handle(w, r)
+			})
+			conf.ProductMeta.RegularPrice = "10.5"
+
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, "oak-board.jpg"), []byte("x"), 0644)
+			if _, err := UploadImageToWordPress(conf, dir, UploadOptions{DryRun: tt.dryRun, Quiet: true}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.dryRun {
+				if len(store.bodies) != 0 {
+					t.Errorf("dry run created %d products", len(store.bodies))
+				}
+				return
+			}
+			if len(store.bodies) != 1 || store.bodies[0]["regular_price"] != tt.want {
+				t.Errorf("created %v, want regular_price %q", store.bodies, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadRejectsNegativePrice(t *testing.T) {
+	conf, requests := newTestStore(t, (&skuStore{}).handle)
+	conf.ProductMeta.RegularPrice = "-10"
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "oak-board.jpg"), []byte("x"), 0644)
+	if _, err := UploadImageToWordPress(conf, dir, UploadOptions{DryRun: true, Quiet: true}); err == nil || !strings.Contains(err.Error(), "regular_price") {
+		t.Errorf("err = %v, want a regular_price error", err)
+	}
+	if requests.Load() != 0 {
+		t.Error("store contacted with an invalid price")
+	}
+}
+
+func TestParseProductPrice(t *testing.T) {
+	tests := []struct {
+		price  string
+		want   float64
+		wantOK bool
+	}{
+		{"1299.00", 1299, true},
+		{"1,299.00", 1299, true},
+		{"1.299,00", 1299, true},
+		{"£12.50", 12.5, true},
+		{" 12,50 € ", 12.5, true},
+		{"1,299", 1299, true},
+		{"7", 7, true},
+		{"", 0, false},
+		{"POA", 0, false},
+		{"1.2.3", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseProductPrice(tt.price)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("parseProductPrice(%q) = %v, %v; want %v, %v", tt.price, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFilterByPrice(t *testing.T) {
+	products := []WooProduct{
+		{ID: 1, RegularPrice: "9.99"},
+		{ID: 2, RegularPrice: "10"},
+		{ID: 3, RegularPrice: "1,250.00"},
+		{ID: 4, RegularPrice: "99,50"},
+		{ID: 5, RegularPrice: ""},
+		{ID: 6, RegularPrice: "100"},
+	}
+	tests := []struct {
+		min, max float64
+		want     []int64
+	}{
+		{10, 100, []int64{2, 4, 6}},
+		{100, 0, []int64{3, 6}},
+		{0, 10, []int64{1, 2}},
+		{0, 0, []int64{1, 2, 3, 4, 6}},
+	}
+	for _, tt := range tests {
+		var got []int64
+		for _, p := range filterByPrice(products, tt.min, tt.max) {
+			got = append(got, p.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("filterByPrice(%v, %v) = %v, want %v", tt.min, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateSEOPriceBand(t *testing.T) {
+	var products []map[string]interface{}
+	for i, price := range []string{"5.00", "25.00", "49.99", "120.00", "", "30"} {
+		p := testProduct(i+1, fmt.Sprintf("Board %d", i+1))
+		p["regular_price"] = price
+		products = append(products, p)
+	}
+	store := newFakeStore(products...)
+	conf, _ := newTestStore(t, store.handle)
+	conf.OpenAIStub = true
+
+	// The band combines with the other filters: product 6 is ignored.
+	result, err := UpdateSEO(conf, SEOOptions{MinPrice: 20, MaxPrice: 50, IgnoreIDs: []int{6}, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := store.written()
+	slices.Sort(written)
+	if !slices.Equal(written, []int64{2, 3}) {
+		t.Errorf("wrote %v, want [2 3]", written)
+	}
+	if !slices.Equal(result.Skipped, []int{6}) {
+		t.Errorf("skipped %v, want [6]", result.Skipped)
+	}
+}
@@ -0,0 +1,58 @@
+package wooh
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// shutdown turns SIGINT and SIGTERM into a cancelled context so long runs
+// can stop taking new work, cancel in-flight requests and save their state.
+// A second signal exits at once.
+type shutdown struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	signals  chan os.Signal
+	received atomic.Int32 // number of the first signal, 0 until one arrives
+}
+
+func listenForShutdown() *shutdown {
+	s := &shutdown{signals: make(chan os.Signal, 2)}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	signal.Notify(s.signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range s.signals {
+			num := int32(sig.(syscall.Signal))
+			if !s.received.CompareAndSwap(0, num) {
+				slog.Warn("Received signal again, exiting now", "signal", sig)
+				os.Exit(exitCodeForSignal(num))
+			}
+			slog.Warn("Received signal, stopping and saving progress (send it again to exit now)", "signal", sig)
+			s.cancel()
+		}
+	}()
+	return s
+}
+
+// stop releases the signal handler.
+func (s *shutdown) stop() {
+	signal.Stop(s.signals)
+	close(s.signals)
+	s.cancel()
+}
+
+// exitCode is the conventional 128+n for the signal that stopped the run,
+// or 0 when none arrived.
+func (s *shutdown) exitCode() int {
+	if num := s.received.Load(); num != 0 {
+		return exitCodeForSignal(num)
+	}
+	return 0
+}
+
+func exitCodeForSignal(num int32) int {
+	return 128 + int(num)
+}
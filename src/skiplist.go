@@ -0,0 +1,69 @@
+package wooh
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// SkipList holds products that UpdateSEO must never process. Unlike the
+// tracker it records a decision, not progress, so --force and
+// --reset-tracker leave it alone.
+type SkipList struct {
+	IDs map[int]bool `json:"ids"`
+	mu  sync.Mutex
+}
+
+func LoadSkipList(path string) (*SkipList, error) {
+	s := &SkipList{IDs: make(map[int]bool)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse skip list: %w", err)
+	}
+	if s.IDs == nil {
+		s.IDs = make(map[int]bool)
+	}
+	return s, nil
+}
+
+func (s *SkipList) contains(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.IDs[id]
+}
+
+// add records id and persists the list. It is safe to call from concurrent
+// workers.
+func (s *SkipList) add(id int, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.IDs[id] = true
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// AddToSkipList permanently excludes product id from SEO updates.
+func AddToSkipList(conf *Config, id int) error {
+	path, err := CachePath(conf, conf.SkipListFilename)
+	if err != nil {
+		return err
+	}
+	skipList, err := LoadSkipList(path)
+	if err != nil {
+		return err
+	}
+	if err := skipList.add(id, path); err != nil {
+		return fmt.Errorf("failed to save skip list: %w", err)
+	}
+	return nil
+}
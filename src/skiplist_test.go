@@ -0,0 +1,90 @@
+package wooh
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestAddToSkipList(t *testing.T) {
+	conf := &Config{CacheDir: t.TempDir()}
+	applyDefaults(conf)
+
+	var wg sync.WaitGroup
+	for id := 1; id <= 3; id++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := AddToSkipList(conf, id); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := AddToSkipList(conf, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	skipList, err := LoadSkipList(mustCachePath(t, conf, conf.SkipListFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id <= 3; id++ {
+		if !skipList.contains(id) {
+			t.Errorf("product %d missing from %v", id, skipList.IDs)
+		}
+	}
+}
+
+func TestLoadSkipList(t *testing.T) {
+	dir := t.TempDir()
+	if s, err := LoadSkipList(filepath.Join(dir, "missing.json")); err != nil || len(s.IDs) != 0 {
+		t.Errorf("missing skip list = %v, %v", s, err)
+	}
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte("{"), 0644)
+	if _, err := LoadSkipList(bad); err == nil {
+		t.Error("unreadable skip list returned no error")
+	}
+}
+
+func TestUpdateSEOSkipListAcrossRuns(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Ash Board"))
+	conf, _ := newTestStore(t, store.handle)
+	conf.OpenAIStub = true
+	conf.OpenAIConcurrency = 1
+	conf.WooConcurrency = 1
+
+	// In prompt mode, "s" skips product 1 for good and "y" approves 2.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin })
+	w.WriteString("s\ny\n")
+	w.Close()
+
+	result, err := UpdateSEO(conf, SEOOptions{Prompt: true, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(result.Skipped, []int{1}) || !slices.Equal(result.Updated, []int{2}) {
+		t.Fatalf("prompt run updated %v, skipped %v", result.Updated, result.Skipped)
+	}
+
+	// Later runs leave it out, even when forced.
+	result, err = UpdateSEO(conf, SEOOptions{Force: true, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(result.Skipped, []int{1}) || !slices.Equal(result.Updated, []int{2}) {
+		t.Errorf("forced run updated %v, skipped %v", result.Updated, result.Skipped)
+	}
+	if !slices.Equal(store.written(), []int64{2, 2}) {
+		t.Errorf("wrote %v, want product 2 twice", store.written())
+	}
+}
@@ -0,0 +1,127 @@
+package wooh
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// SKU pattern placeholders.
+const (
+	skuPlaceholderID   = "{id}"
+	skuPlaceholderSlug = "{slug}"
+)
+
+// ValidateSKUPattern checks that pattern yields a different SKU per product,
+// which needs {id} or {slug}.
+func ValidateSKUPattern(pattern string) error {
+	if !strings.Contains(pattern, skuPlaceholderID) && !strings.Contains(pattern, skuPlaceholderSlug) {
+		return fmt.Errorf("invalid SKU pattern %q: it must contain %s or %s", pattern, skuPlaceholderID, skuPlaceholderSlug)
+	}
+	return nil
+}
+
+// skuFromPattern fills in pattern for product, e.g. "OAK-{id}" gives
+// "OAK-42" and "{slug}" gives "oak-plank-42" for "Oak Plank 42".
+func skuFromPattern(pattern string, product WooProduct) string {
+	return strings.NewReplacer(
+		skuPlaceholderID, strconv.FormatInt(product.ID, 10),
+		skuPlaceholderSlug, slugify(product.Name),
+	).Replace(pattern)
+}
+
+// slugify lowercases s and joins its runs of letters and digits with "-".
+func slugify(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, "-")
+}
+
+// planSKUs returns the SKU updates for the products without one. WooCommerce
+// rejects duplicate SKUs, so a generated SKU already taken gets "-{id}"
+// appended, and the product is skipped if that is taken too.
+func planSKUs(products []WooProduct, pattern string) []map[string]interface{} {
+	taken := make(map[string]bool, len(products))
+	for _, p := range products {
+		if sku := strings.TrimSpace(p.Sku); sku != "" {
+			taken[strings.ToLower(sku)] = true
+		}
+	}
+
+	var updates []map[string]interface{}
+	for _, p := range products {
+		if strings.TrimSpace(p.Sku) != "" {
+			continue
+		}
+		sku := skuFromPattern(pattern, p)
+		if taken[strings.ToLower(sku)] {
+			sku = fmt.Sprintf("%s-%d", sku, p.ID)
+		}
+		if taken[strings.ToLower(sku)] || strings.Trim(sku, "-") == "" {
+			slog.Warn("Skipping product: no unique SKU from pattern", "product_id", p.ID, "pattern", pattern)
+			continue
+		}
+		taken[strings.ToLower(sku)] = true
+		updates = append(updates, map[string]interface{}{
+			"id":  p.ID,
+			"sku": sku,
+		})
+	}
+	return updates
+}
+
+// skuExists reports whether any product, whatever its status, has sku.
+func skuExists(client *resty.Client, conf *Config, sku string) (bool, error) {
+	resp, err := client.R().
+		SetHeader("Accept", "application/json").
+		SetQueryParams(map[string]string{
+			"sku":     sku,
+			"status":  "any",
+			"_fields": "id",
+		}).
+		Get(wooEndpoint(conf, "products"))
+	if err != nil {
+		return false, fmt.Errorf("failed to look up SKU %s: %w", sku, err)
+	}
+	if resp.IsError() {
+		return false, fmt.Errorf("error looking up SKU %s: %w", sku, apiError(resp))
+	}
+	var products []WooProduct
+	if err := decodeJSON(resp, &products); err != nil {
+		return false, fmt.Errorf("failed to parse SKU lookup: %w", err)
+	}
+	return len(products) > 0, nil
+}
+
+// BackfillSKUs gives every product without a SKU one generated from pattern
+// (see skuFromPattern) and returns how many were updated. Products that
+// already have a SKU are left untouched.
+func BackfillSKUs(conf *Config, pattern string) (updated int, err error) {
+	if err := ValidateSKUPattern(pattern); err != nil {
+		return 0, err
+	}
+	cache, err := NewCache(conf)
+	if err != nil {
+		return 0, err
+	}
+	// A stale cache could hide SKUs set since, so the catalog is refetched.
+	products, err := GetProducts(conf, cache, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	updates := planSKUs(products, pattern)
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	slog.Info("Backfilling SKUs", "products", len(updates))
+	if err := BatchUpdateProducts(conf, updates); err != nil {
+		return 0, err
+	}
+	return len(updates), nil
+}
@@ -0,0 +1,84 @@
+package wooh
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSkuFromPattern(t *testing.T) {
+	product := WooProduct{ID: 42, Name: "Oak Plank, 42mm (Oiled)"}
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"OAK-{id}", "OAK-42"},
+		{"{slug}", "oak-plank-42mm-oiled"},
+		{"WH-{slug}-{id}", "WH-oak-plank-42mm-oiled-42"},
+	}
+	for _, tt := range tests {
+		if got := skuFromPattern(tt.pattern, product); got != tt.want {
+			t.Errorf("skuFromPattern(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+	if err := ValidateSKUPattern("OAK-001"); err == nil {
+		t.Error("pattern without a placeholder accepted")
+	}
+}
+
+func TestPlanSKUs(t *testing.T) {
+	products := []WooProduct{
+		{ID: 1, Name: "Oak Board", Sku: "oak-board"},
+		{ID: 2, Name: "Oak Board"},
+		{ID: 3, Name: "Walnut Board", Sku: "  "},
+		{ID: 4, Name: "Walnut Board"},
+		{ID: 5, Name: "¿?"},
+	}
+	got := planSKUs(products, "{slug}")
+	want := []map[string]interface{}{
+		{"id": int64(2), "sku": "oak-board-2"},    // "oak-board" is taken by product 1
+		{"id": int64(3), "sku": "walnut-board"},   // a blank SKU counts as missing
+		{"id": int64(4), "sku": "walnut-board-4"}, // taken by product 3's new SKU
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planSKUs = %v\nwant %v", got, want)
+	}
+}
+
+func TestBackfillSKUs(t *testing.T) {
+	withSku := testProduct(1, "Oak Board")
+	withSku["sku"] = "OAK-1"
+	store := newFakeStore(withSku, testProduct(2, "Ash Board"), testProduct(3, "Elm Board"))
+	conf, _ := newTestStore(t, store.handle)
+
+	// A fresh cache from before product 3 got a SKU is not trusted.
+	cache, _ := NewCache(conf)
+	if _, err := GetProducts(conf, cache, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	store.mu.Lock()
+	store.products[3]["sku"] = "ELM"
+	store.mu.Unlock()
+
+	if _, err := BackfillSKUs(conf, "WH"); err == nil {
+		t.Error("BackfillSKUs accepted a pattern without a placeholder")
+	}
+	updated, err := BackfillSKUs(conf, "WH-{id}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != 1 || !slices.Equal(store.written(), []int64{2}) {
+		t.Errorf("updated %d, wrote %v; want only product 2", updated, store.written())
+	}
+	if sku := store.product(2).Sku; sku != "WH-2" {
+		t.Errorf("product 2 SKU = %q, want WH-2", sku)
+	}
+	if a, b := store.product(1).Sku, store.product(3).Sku; a != "OAK-1" || b != "ELM" {
+		t.Errorf("existing SKUs changed to %q, %q", a, b)
+	}
+
+	if updated, err := BackfillSKUs(conf, "WH-{id}"); err != nil || updated != 0 {
+		t.Errorf("second backfill = %d, %v; want nothing left to do", updated, err)
+	}
+}
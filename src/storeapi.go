@@ -0,0 +1,94 @@
+package wooh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+)
+
+const (
+	ReadModeRest  = "rest"
+	ReadModeStore = "store"
+)
+
+// storeOrderBy lists the orderby values the Store API accepts; others
+// (like the REST default "id") are dropped rather than rejected by the store.
+var storeOrderBy = map[string]bool{"date": true, "price": true, "title": true, "menu_order": true, "popularity": true, "rating": true}
+
+func ValidateReadMode(mode string) error {
+	if mode != ReadModeRest && mode != ReadModeStore {
+		return fmt.Errorf("unsupported read_mode %q (allowed: rest, store)", mode)
+	}
+	return nil
+}
+
+type storeProduct struct {
+	ID               int64         `json:"id"`
+	Name             string        `json:"name"`
+	Sku              string        `json:"sku"`
+	Description      string        `json:"description"`
+	ShortDescription string        `json:"short_description"`
+	Categories       []WooCategory `json:"categories"`
+	Prices           struct {
+		RegularPrice      string `json:"regular_price"` // in minor units, e.g. "1299"
+		CurrencyMinorUnit int    `json:"currency_minor_unit"`
+	} `json:"prices"`
+}
+
+func (p storeProduct) toWooProduct() WooProduct {
+	return WooProduct{
+		ID:               p.ID,
+		Name:             p.Name,
+		Sku:              p.Sku,
+		Description:      p.Description,
+		ShortDescription: p.ShortDescription,
+		RegularPrice:     p.regularPrice(),
+		Categories:       p.Categories,
+	}
+}
+
+// regularPrice converts the Store API's minor-unit price to a decimal
+// string like the REST API's.
+func (p storeProduct) regularPrice() string {
+	minor, err := strconv.ParseInt(p.Prices.RegularPrice, 10, 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatFloat(float64(minor)/math.Pow10(p.Prices.CurrencyMinorUnit), 'f', p.Prices.CurrencyMinorUnit, 64)
+}
+
+// fetchStoreProducts lists products through the public Store API
+// (wc/store/v1), which needs no consumer keys but exposes no meta_data.
+func fetchStoreProducts(conf *Config, params map[string]string) ([]WooProduct, error) {
+	query := map[string]string{"order": conf.Order}
+	if storeOrderBy[conf.OrderBy] {
+		query["orderby"] = conf.OrderBy
+	}
+	maps.Copy(query, params)
+
+	// Items stay raw until decoded so fields read by JSON path can use them.
+	items, err := paginateURL[json.RawMessage](context.Background(), newClient(conf), conf, wpEndpoint(conf, "wc/store/v1/products"), query)
+	if err != nil {
+		return nil, err
+	}
+	products := make([]WooProduct, 0, len(items))
+	for _, item := range items {
+		var p storeProduct
+		if err := json.Unmarshal(item, &p); err != nil {
+			return nil, fmt.Errorf("failed to parse store product: %w", err)
+		}
+		product := p.toWooProduct()
+		if hasPathFields(conf) {
+			var raw interface{}
+			if err := json.Unmarshal(item, &raw); err != nil {
+				return nil, fmt.Errorf("failed to parse store product %d: %w", p.ID, err)
+			}
+			decodePathFields(conf, &product, raw)
+		}
+		products = append(products, product)
+	}
+	return products, nil
+}
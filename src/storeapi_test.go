@@ -0,0 +1,90 @@
+package wooh
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreProductRegularPrice(t *testing.T) {
+	tests := []struct {
+		price string
+		minor int
+		want  string
+	}{
+		{"1299", 2, "12.99"},
+		{"1000", 2, "10.00"},
+		{"1500", 0, "1500"},
+		{"12995", 3, "12.995"},
+		{"", 2, ""},
+		{"n/a", 2, ""},
+	}
+	for _, tt := range tests {
+		var p storeProduct
+		p.Prices.RegularPrice, p.Prices.CurrencyMinorUnit = tt.price, tt.minor
+		if got := p.regularPrice(); got != tt.want {
+			t.Errorf("regularPrice(%q, %d) = %q, want %q", tt.price, tt.minor, got, tt.want)
+		}
+	}
+}
+
+func TestValidateReadMode(t *testing.T) {
+	for _, mode := range []string{ReadModeRest, ReadModeStore} {
+		if err := ValidateReadMode(mode); err != nil {
+			t.Errorf("ValidateReadMode(%q) = %v", mode, err)
+		}
+	}
+	if err := ValidateReadMode("graphql"); err == nil {
+		t.Error("ValidateReadMode(graphql) = nil, want an error")
+	}
+}
+
+func TestGetProductsStoreReadMode(t *testing.T) {
+	var paths []string
+	conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Query().Has("consumer_key") {
+			t.Errorf("Store API request sent consumer keys: %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-WP-Total", "1")
+		w.Header().Set("X-WP-TotalPages", "1")
+		w.Write([]byte(`[{
+			"id": 7,
+			"name": "Oak Board",
+			"sku": "OAK-7",
+			"description": "<p>Solid oak.</p>",
+			"short_description": "<p>Oak.</p>",
+			"categories": [{"id": 3, "name": "Flooring", "slug": "flooring"}],
+			"prices": {"price": "1299", "regular_price": "1499", "currency_minor_unit": 2}
+		}]`))
+	})
+	conf.ReadMode = ReadModeStore
+	conf.WooConsumerKey, conf.WooConsumerSecret = "", ""
+
+	cache, err := NewCache(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	products, err := GetProducts(conf, cache, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []WooProduct{{
+		ID:               7,
+		Name:             "Oak Board",
+		Sku:              "OAK-7",
+		Description:      "<p>Solid oak.</p>",
+		ShortDescription: "<p>Oak.</p>",
+		RegularPrice:     "14.99",
+		Categories:       []WooCategory{{ID: 3, Name: "Flooring", Slug: "flooring"}},
+	}}
+	if !reflect.DeepEqual(products, want) {
+		t.Errorf("products = %+v\nwant %+v", products, want)
+	}
+	if len(paths) != 1 || !strings.HasSuffix(paths[0], "/wp-json/wc/store/v1/products") {
+		t.Errorf("requested %v, want only the Store API", paths)
+	}
+}
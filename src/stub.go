@@ -0,0 +1,59 @@
+package wooh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// stubTitleTail ends every stub meta title.
+const stubTitleTail = " | Buy Online"
+
+// stubMeta is the openai_stub stand-in for generateMeta: meta derived from
+// the product name alone, identical on every run and within titleLimit and
+// maxDescriptionLength.
+func stubMeta(input SEOInput, titleLimit int) JSONResponse {
+	name := strings.Join(strings.Fields(input.Name), " ")
+	words := strings.Fields(strings.ToLower(name))
+	if len(words) > 4 {
+		words = words[:4]
+	}
+	return JSONResponse{
+		MetaTitle:       truncateWords(name, titleLimit-len(stubTitleTail)) + stubTitleTail,
+		MetaDescription: truncateWords(fmt.Sprintf("Buy %s online. Fast delivery and expert advice on every order.", name), maxDescriptionLength),
+		FocusKeyphrase:  strings.Join(words, " "),
+	}
+}
+
+// stubText is the openai_stub stand-in for the description generators.
+func stubText(kind string, input SEOInput) string {
+	return fmt.Sprintf("Stub %s for %s.", kind, input.Name)
+}
+
+// truncateWords shortens s to at most limit bytes, cutting at a word
+// boundary where there is one.
+func truncateWords(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	if limit <= 0 {
+		return ""
+	}
+	cut := strings.LastIndex(s[:limit+1], " ")
+	if cut <= 0 {
+		cut = limit
+	}
+	return strings.TrimSpace(s[:cut])
+}
+
+// stubCategories picks the available categories whose name occurs in the
+// product name, as SuggestCategories' model would list them.
+func stubCategories(input SEOInput, available []WooCategory) string {
+	name := strings.ToLower(input.Name)
+	var picked []string
+	for _, c := range available {
+		if c.Name != "" && strings.Contains(name, strings.ToLower(c.Name)) {
+			picked = append(picked, c.Name)
+		}
+	}
+	return strings.Join(picked, "\n")
+}
@@ -0,0 +1,86 @@
+package wooh
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStubMeta(t *testing.T) {
+	tests := []struct {
+		name      string
+		wantTitle string
+		wantKW    string
+	}{
+		{"Oak Board", "Oak Board | Buy Online", "oak board"},
+		{"  Solid   Oak\tBoard ", "Solid Oak Board | Buy Online", "solid oak board"},
+		{"Extra Wide Solid Oak Kitchen Worktop With Square Edge Profile", "Extra Wide Solid Oak Kitchen Worktop With | Buy Online", "extra wide solid oak"},
+		{strings.Repeat("x", 80), strings.Repeat("x", 47) + " | Buy Online", strings.Repeat("x", 80)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := SEOInput{Name: tt.name, Description: "Anything."}
+			got := stubMeta(input, maxTitleLength)
+			if got != stubMeta(input, maxTitleLength) {
+				t.Error("stub meta differs between calls")
+			}
+			if got.MetaTitle != tt.wantTitle || got.FocusKeyphrase != tt.wantKW {
+				t.Errorf("title %q, keyphrase %q; want %q, %q", got.MetaTitle, got.FocusKeyphrase, tt.wantTitle, tt.wantKW)
+			}
+			if len(got.MetaTitle) > maxTitleLength || len(got.MetaDescription) > maxDescriptionLength {
+				t.Errorf("meta over the limits: %d, %d", len(got.MetaTitle), len(got.MetaDescription))
+			}
+			if !strings.HasPrefix(got.MetaDescription, "Buy ") {
+				t.Errorf("description = %q", got.MetaDescription)
+			}
+		})
+	}
+
+	// A title suffix shortens the budget the stub fills.
+	if got := stubMeta(SEOInput{Name: strings.Repeat("oak ", 20)}, 40); len(got.MetaTitle) > 40 {
+		t.Errorf("title %q is over the 40 character limit", got.MetaTitle)
+	}
+}
+
+func TestTruncateWords(t *testing.T) {
+	tests := []struct {
+		s     string
+		limit int
+		want  string
+	}{
+		{"Oak Board", 20, "Oak Board"},
+		{"Solid Oak Board", 12, "Solid Oak"},
+		{"Solid Oak Board", 9, "Solid Oak"},
+		{"Worktop", 4, "Work"},
+		{"Oak", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateWords(tt.s, tt.limit); got != tt.want {
+			t.Errorf("truncateWords(%q, %d) = %q, want %q", tt.s, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateSEOStubMakesNoRequests(t *testing.T) {
+	restore := assumeYes
+	assumeYes = true
+	t.Cleanup(func() { assumeYes = restore })
+
+	thin := testProduct(1, "Oak Board")
+	thin["description"] = "<p>Oak.</p>"
+	store := newFakeStore(thin)
+	conf, _ := newTestStore(t, store.handle)
+	gen := newFakeGenerator(t, copyWriter)
+	gen.use(conf)
+	conf.OpenAIStub = true
+
+	if _, err := UpdateSEO(conf, SEOOptions{RegenerateDescriptions: true, Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := gen.sent(); len(sent) != 0 {
+		t.Errorf("stub mode sent %d generator requests", len(sent))
+	}
+	p := store.product(1)
+	if p.MetaData.YoastTitle() != "Oak Board | Buy Online" || p.Description != "<p>Stub description for Oak Board.</p>" {
+		t.Errorf("title %q, description %q", p.MetaData.YoastTitle(), p.Description)
+	}
+}
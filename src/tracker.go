@@ -0,0 +1,50 @@
+package wooh
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// PruneTracker drops tracker entries for products that no longer exist in
+// the store. The product list is fetched live so deletions are seen.
+func PruneTracker(conf *Config) (removed int, err error) {
+	trackerFilepath, err := CachePath(conf, conf.TrackerFilename)
+	if err != nil {
+		return 0, err
+	}
+	tracker, err := TrackerLoad(trackerFilepath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load SEO update tracker: %w", err)
+	}
+
+	cache, err := NewCache(conf)
+	if err != nil {
+		return 0, err
+	}
+	products, err := GetProducts(conf, cache, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	existing := make(map[int]bool, len(products))
+	for _, p := range products {
+		existing[int(p.ID)] = true
+	}
+
+	tracker.mu.Lock()
+	for id := range tracker.UpdatedIDs {
+		if !existing[id] {
+			delete(tracker.UpdatedIDs, id)
+			removed++
+		}
+	}
+	tracker.mu.Unlock()
+
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := tracker.save(trackerFilepath); err != nil {
+		return removed, fmt.Errorf("failed to save SEO update tracker: %w", err)
+	}
+	slog.Info("Pruned stale IDs from the tracker", "removed", removed)
+	return removed, nil
+}
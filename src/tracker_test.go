@@ -0,0 +1,45 @@
+package wooh
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPruneTracker(t *testing.T) {
+	var products []map[string]interface{}
+	for id := 1; id <= 4; id++ {
+		products = append(products, testProduct(id, "Board"))
+	}
+	store := newFakeStore(products...)
+	conf, _ := newTestStore(t, store.handle)
+	conf.OpenAIStub = true
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	delete(store.products, 2)
+	delete(store.products, 4)
+	removed, err := PruneTracker(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d IDs, want 2", removed)
+	}
+	tracker, err := TrackerLoad(mustCachePath(t, conf, conf.TrackerFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []int
+	for id := range tracker.UpdatedIDs {
+		kept = append(kept, id)
+	}
+	slices.Sort(kept)
+	if !slices.Equal(kept, []int{1, 3}) {
+		t.Errorf("tracker holds %v, want [1 3]", kept)
+	}
+
+	if removed, err := PruneTracker(conf); err != nil || removed != 0 {
+		t.Errorf("second prune removed %d, %v; want 0", removed, err)
+	}
+}
@@ -0,0 +1,68 @@
+package wooh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ErrWriteNotPersisted means the store accepted an update but a re-fetch
+// shows the old values, e.g. because a caching plugin swallowed the write.
+var ErrWriteNotPersisted = errors.New("update was accepted but not persisted")
+
+func putProduct(ctx context.Context, client *resty.Client, conf *Config, productID int, payload map[string]interface{}) error {
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(payload).
+		Put(wooEndpoint(conf, fmt.Sprintf("products/%v", productID)))
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// verifyProductMeta re-fetches the product and checks every entry of metaData
+// now holds the written value.
+func verifyProductMeta(ctx context.Context, conf *Config, productID int, metaData MetaData) error {
+	product, err := getProduct(ctx, conf, productID)
+	if err != nil {
+		return fmt.Errorf("failed to re-fetch product for verification: %w", err)
+	}
+	if changes := DiffMeta(product, metaData); len(changes) > 0 {
+		return fmt.Errorf("%w: %s differs", ErrWriteNotPersisted, changes[0].Key)
+	}
+	return nil
+}
+
+// writeProductUpdate sends payload and, when conf.VerifyWrites is set, checks
+// the meta persisted, re-sending the update once on a mismatch.
+func writeProductUpdate(ctx context.Context, client *resty.Client, conf *Config, productID int, payload map[string]interface{}, metaData MetaData) error {
+	if conf.OfflineProducts != "" {
+		logOfflineWrite(productID, payload)
+		return nil
+	}
+	if err := sendProductUpdate(ctx, client, conf, productID, payload); err != nil {
+		return err
+	}
+	if !conf.VerifyWrites {
+		return nil
+	}
+
+	err := verifyProductMeta(ctx, conf, productID, metaData)
+	if !errors.Is(err, ErrWriteNotPersisted) {
+		return err
+	}
+
+	slog.Warn("Update did not persist, retrying once", "product_id", productID)
+	if err := sendProductUpdate(ctx, client, conf, productID, payload); err != nil {
+		return err
+	}
+	return verifyProductMeta(ctx, conf, productID, metaData)
+}
@@ -0,0 +1,63 @@
+package wooh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteProductUpdateVerifies(t *testing.T) {
+	tests := []struct {
+		name      string
+		verify    bool
+		dropped   int // PUTs answered 200 without persisting
+		wantPuts  int
+		wantErr   error
+		wantTitle string
+	}{
+		{"persisted", true, 0, 1, nil, "Oak Board | Shop"},
+		{"dropped once", true, 1, 2, nil, "Oak Board | Shop"},
+		{"dropped twice", true, 2, 2, ErrWriteNotPersisted, ""},
+		{"unverified", false, 1, 1, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(testProduct(1, "Oak Board"))
+			var mu sync.Mutex
+			puts := 0
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/products/1") {
+					mu.Lock()
+					puts++
+					drop := puts <= tt.dropped
+					mu.Unlock()
+					if drop {
+						w.Header().Set("Content-Type", "application/json")
+						json.NewEncoder(w).Encode(store.product(1))
+						return
+					}
+				}
+				store.handle(w, r)
+			})
+			conf.VerifyWrites = tt.verify
+
+			meta := MetaData{}
+			meta.Set(yoastTitleKey, "Oak Board | Shop")
+			payload := map[string]interface{}{"meta_data": meta}
+			err := writeProductUpdate(context.Background(), newClient(conf), conf, 1, payload, meta)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if puts != tt.wantPuts {
+				t.Errorf("sent %d PUTs, want %d", puts, tt.wantPuts)
+			}
+			if got := store.product(1).MetaData.Get(yoastTitleKey); got != tt.wantTitle {
+				t.Errorf("stored title = %q, want %q", got, tt.wantTitle)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package wooh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Catalog visibility values accepted by WooCommerce.
+var allowedVisibilities = []string{"visible", "catalog", "search", "hidden"}
+
+// ValidateVisibility reports whether visibility is a catalog_visibility
+// value WooCommerce accepts.
+func ValidateVisibility(visibility string) error {
+	for _, allowed := range allowedVisibilities {
+		if visibility == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported visibility %q (allowed: %s)", visibility, strings.Join(allowedVisibilities, ", "))
+}
+
+// SetVisibility sets the catalog_visibility of one product, e.g. "search" to
+// keep it out of the shop pages or "hidden" to keep it out of both.
+func SetVisibility(conf *Config, id int, visibility string) error {
+	if err := ValidateVisibility(visibility); err != nil {
+		return err
+	}
+	return BatchUpdateProducts(conf, []map[string]interface{}{
+		{"id": id, "catalog_visibility": visibility},
+	})
+}
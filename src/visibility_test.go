@@ -0,0 +1,50 @@
+package wooh
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSetVisibility(t *testing.T) {
+	tests := []struct {
+		visibility string
+		wantErr    bool
+	}{
+		{"visible", false},
+		{"catalog", false},
+		{"search", false},
+		{"hidden", false},
+		{"Hidden", true},
+		{"private", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.visibility, func(t *testing.T) {
+			product := testProduct(1, "Oak Board")
+			product["catalog_visibility"] = "visible"
+			store := newFakeStore(product)
+			conf, requests := newTestStore(t, store.handle)
+
+			err := SetVisibility(conf, 1, tt.visibility)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "unsupported visibility") {
+					t.Errorf("err = %v, want unsupported visibility", err)
+				}
+				if requests.Load() != 0 {
+					t.Error("sent an invalid visibility to the store")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(store.written(), []int64{1}) {
+				t.Errorf("wrote %v, want [1]", store.written())
+			}
+			if got := store.products[1]["catalog_visibility"]; got != tt.visibility {
+				t.Errorf("catalog_visibility = %v, want %s", got, tt.visibility)
+			}
+		})
+	}
+}
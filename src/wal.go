@@ -0,0 +1,159 @@
+package wooh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+)
+
+// walEntry is one line of the write-ahead log: a generated update, or a
+// marker that the update for ID has been written.
+type walEntry struct {
+	ID               int64    `json:"id"`
+	Name             string   `json:"name,omitempty"`
+	MetaData         MetaData `json:"meta_data,omitempty"`
+	Description      string   `json:"description,omitempty"`
+	ShortDescription string   `json:"short_description,omitempty"`
+	Done             bool     `json:"done,omitempty"`
+}
+
+// writeAheadLog buffers generated updates until WooCommerce has accepted
+// them, so a run that dies between generation and write can apply them on
+// restart instead of paying for the generation again.
+type writeAheadLog struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	pending map[int64]walEntry
+}
+
+// openWAL replays the log at path and opens it for appending. A torn last
+// line from a crash mid-append is ignored.
+func openWAL(path string) (*writeAheadLog, error) {
+	w := &writeAheadLog{path: path, pending: make(map[int64]walEntry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read write-ahead log: %w", err)
+	}
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var entry walEntry
+			if jsonErr := json.Unmarshal(line, &entry); jsonErr != nil {
+				slog.Warn("Ignoring unreadable write-ahead log entry", "err", jsonErr)
+			} else if entry.Done {
+				delete(w.pending, entry.ID)
+			} else {
+				w.pending[entry.ID] = entry
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	w.f, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	return w, nil
+}
+
+// unwritten returns the buffered updates that were never confirmed, in ID
+// order.
+func (w *writeAheadLog) unwritten() []*ProductUpdate {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ids := make([]int64, 0, len(w.pending))
+	for id := range w.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	updates := make([]*ProductUpdate, 0, len(ids))
+	for _, id := range ids {
+		entry := w.pending[id]
+		updates = append(updates, &ProductUpdate{
+			Product:          WooProduct{ID: entry.ID, Name: entry.Name},
+			MetaData:         entry.MetaData,
+			Description:      entry.Description,
+			ShortDescription: entry.ShortDescription,
+		})
+	}
+	return updates
+}
+
+// add records a generated update before it is handed to the writers.
+func (w *writeAheadLog) add(u *ProductUpdate) error {
+	return w.append(walEntry{
+		ID:               u.Product.ID,
+		Name:             u.Product.Name,
+		MetaData:         u.MetaData,
+		Description:      u.Description,
+		ShortDescription: u.ShortDescription,
+	})
+}
+
+// done records that the update for id has been written.
+func (w *writeAheadLog) done(id int64) error {
+	return w.append(walEntry{ID: id, Done: true})
+}
+
+func (w *writeAheadLog) append(entry walEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.f.Write(line); err != nil {
+		return err
+	}
+	if entry.Done {
+		delete(w.pending, entry.ID)
+	} else {
+		w.pending[entry.ID] = entry
+	}
+	return w.f.Sync()
+}
+
+// close rewrites the log with only the updates still unwritten, removing
+// it when there are none.
+func (w *writeAheadLog) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	if len(w.pending) == 0 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range w.pending {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	tmp := w.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.path)
+}
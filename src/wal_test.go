@@ -0,0 +1,114 @@
+package wooh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestOpenWALReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seo.wal")
+	w, err := openWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := int64(1); id <= 3; id++ {
+		var meta MetaData
+		meta.Set(yoastTitleKey, fmt.Sprintf("Board %d", id))
+		if err := w.add(&ProductUpdate{Product: WooProduct{ID: id, Name: "Board"}, MetaData: meta}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.done(2); err != nil {
+		t.Fatal(err)
+	}
+	// Crash: the file is left as is, with a torn line from an interrupted append.
+	w.f.Close()
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"id":4,"meta_da`)
+	f.Close()
+
+	restarted, err := openWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, u := range restarted.unwritten() {
+		ids = append(ids, u.Product.ID)
+	}
+	if !slices.Equal(ids, []int64{1, 3}) {
+		t.Fatalf("unwritten = %v, want [1 3]", ids)
+	}
+	if got := restarted.unwritten()[1].MetaData.YoastTitle(); got != "Board 3" {
+		t.Errorf("replayed title = %q, want Board 3", got)
+	}
+
+	// Closing keeps only what is still unwritten, and removes the log once
+	// everything is written.
+	restarted.done(1)
+	if err := restarted.close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); strings.Count(string(data), "\n") != 1 || !strings.Contains(string(data), `"id":3`) {
+		t.Errorf("compacted log = %s", data)
+	}
+	again, _ := openWAL(path)
+	again.done(3)
+	if err := again.close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("log still exists after every update was written: %v", err)
+	}
+}
+
+func TestUpdateSEOAppliesBufferedUpdates(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Ash Board"))
+	conf, _ := newTestStore(t, store.handle)
+
+	// A previous run generated meta for product 2 and crashed before writing it.
+	w, err := openWAL(mustCachePath(t, conf, conf.WALFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta MetaData
+	meta.Set(yoastTitleKey, "Buffered Ash Board")
+	meta.Set(yoastDescKey, "Buffered description.")
+	if err := w.add(&ProductUpdate{Product: WooProduct{ID: 2, Name: "Ash Board"}, MetaData: meta}); err != nil {
+		t.Fatal(err)
+	}
+	w.f.Close()
+
+	gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+		return textChoice(metaJSON(map[string]string{"meta_title": "Generated Board", "meta_description": "Solid board."}))
+	})
+	gen.use(conf)
+
+	result, err := UpdateSEO(conf, SEOOptions{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(result.Updated)
+	if !slices.Equal(result.Updated, []int{1, 2}) {
+		t.Errorf("updated %v, want [1 2]", result.Updated)
+	}
+	if got := store.product(2).MetaData.YoastTitle(); got != "Buffered Ash Board" {
+		t.Errorf("product 2 title = %q, want the buffered one", got)
+	}
+	if got := store.product(1).MetaData.YoastTitle(); got != "Generated Board" {
+		t.Errorf("product 1 title = %q, want a generated one", got)
+	}
+	for _, req := range gen.sent() {
+		if strings.Contains(req.Messages[len(req.Messages)-1].Content, "Ash Board") {
+			t.Error("regenerated the product with a buffered update")
+		}
+	}
+	if _, err := os.Stat(mustCachePath(t, conf, conf.WALFilename)); !os.IsNotExist(err) {
+		t.Errorf("write-ahead log kept after a clean run: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package wooh
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// webhookTimeout bounds the completion webhook; it is never retried.
+const webhookTimeout = 5 * time.Second
+
+// RunResult is the JSON body posted to completion_webhook when an SEO run
+// ends.
+type RunResult struct {
+	Site          string    `json:"site"`
+	Status        string    `json:"status"` // "success" or "failed"
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+	Summary       string    `json:"summary,omitempty"`
+	Processed     int64     `json:"processed"`
+	Updated       int       `json:"updated"`
+	Skipped       int       `json:"skipped"`
+	Failed        int       `json:"failed"`
+	PolicySkipped int       `json:"policy_skipped"`
+	FailedIDs     []int     `json:"failed_ids,omitempty"`
+	Error         string    `json:"error,omitempty"`
+}
+
+func newRunResult(conf *Config, start time.Time, result *SEOResult, runErr error) RunResult {
+	rr := RunResult{
+		Site:       siteURL(conf),
+		Status:     "success",
+		StartedAt:  start,
+		FinishedAt: time.Now(),
+	}
+	if result != nil {
+		rr.Summary = result.Summary(rr.FinishedAt.Sub(start))
+		result.mu.Lock()
+		rr.Processed = result.processed.Load()
+		rr.Updated, rr.Skipped, rr.Failed = len(result.Updated), len(result.Skipped), len(result.Failed)
+		rr.PolicySkipped = len(result.PolicySkipped)
+		rr.FailedIDs = append([]int(nil), result.Failed...)
+		result.mu.Unlock()
+	}
+	if runErr != nil {
+		rr.Status = "failed"
+		rr.Error = conf.redact(runErr.Error())
+	}
+	return rr
+}
+
+// notifyCompletion posts rr to completion_webhook. Failures are logged and
+// otherwise ignored.
+func notifyCompletion(conf *Config, rr RunResult) {
+	resp, err := resty.New().
+		SetTimeout(webhookTimeout).
+		R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(rr).
+		Post(conf.CompletionWebhook)
+	if err != nil {
+		slog.Warn("Completion webhook failed", "err", conf.redact(err.Error()))
+		return
+	}
+	if resp.IsError() {
+		slog.Warn("Completion webhook returned an error", "status", resp.Status())
+	}
+}
+
+var credentialParamRegex = regexp.MustCompile(`(consumer_(?:key|secret)=)[^&\s"]+`)
+
+// redact blanks the credentials of conf in s, such as the consumer key in a
+// request URL quoted by an error.
+func (c *Config) redact(s string) string {
+	s = credentialParamRegex.ReplaceAllString(s, "${1}[redacted]")
+	for _, secret := range []string{c.WooConsumerKey, c.WooConsumerSecret, c.WpKey, c.OpenAIKey, c.AnthropicKey} {
+		if len(secret) >= 4 {
+			s = strings.ReplaceAll(s, secret, "[redacted]")
+		}
+	}
+	return s
+}
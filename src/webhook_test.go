@@ -0,0 +1,120 @@
+package wooh
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// webhookReceiver records the RunResults posted to it, answering with status.
+type webhookReceiver struct {
+	status int
+
+	mu      sync.Mutex
+	results []RunResult
+}
+
+func (h *webhookReceiver) start(t *testing.T) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rr RunResult
+		if err := json.NewDecoder(r.Body).Decode(&rr); err != nil {
+			t.Errorf("webhook body: %v", err)
+		}
+		h.mu.Lock()
+		h.results = append(h.results, rr)
+		h.mu.Unlock()
+		if h.status != 0 {
+			w.WriteHeader(h.status)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestCompletionWebhook(t *testing.T) {
+	t.Run("finished run", func(t *testing.T) {
+		store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Ash Board"))
+		conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/products/2") {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"code": "rest_invalid_param", "message": "Invalid parameter"}`))
+				return
+			}
+			store.handle(w, r)
+		})
+		conf.OpenAIStub = true
+		hook := &webhookReceiver{}
+		conf.CompletionWebhook = hook.start(t)
+
+		if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+			t.Fatal(err)
+		}
+		if len(hook.results) != 1 {
+			t.Fatalf("webhook called %d times, want 1", len(hook.results))
+		}
+		rr := hook.results[0]
+		if rr.Status != "success" || rr.Site != conf.Site || rr.Processed != 2 || rr.Updated != 1 || rr.Failed != 1 || !slices.Equal(rr.FailedIDs, []int{2}) {
+			t.Errorf("result = %+v", rr)
+		}
+		if !strings.HasPrefix(rr.Summary, "Processed 2, updated 1, skipped 0, failed 1") || rr.FinishedAt.Before(rr.StartedAt) {
+			t.Errorf("summary %q, started %s, finished %s", rr.Summary, rr.StartedAt, rr.FinishedAt)
+		}
+	})
+
+	t.Run("failed run is redacted", func(t *testing.T) {
+		conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code": "woocommerce_rest_authentication_error", "message": "Invalid consumer secret cs_test"}`))
+		})
+		conf.OpenAIStub = true
+		hook := &webhookReceiver{}
+		conf.CompletionWebhook = hook.start(t)
+
+		if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err == nil {
+			t.Fatal("UpdateSEO succeeded against a failing store")
+		}
+		if len(hook.results) != 1 {
+			t.Fatalf("webhook called %d times, want 1", len(hook.results))
+		}
+		rr := hook.results[0]
+		if rr.Status != "failed" || !strings.Contains(rr.Error, "woocommerce_rest_authentication_error") {
+			t.Errorf("result = %+v", rr)
+		}
+		if strings.Contains(rr.Error, conf.WooConsumerSecret) {
+			t.Errorf("webhook error leaks the consumer secret: %s", rr.Error)
+		}
+	})
+
+	t.Run("failing webhook is not retried", func(t *testing.T) {
+		store := newFakeStore(testProduct(1, "Oak Board"))
+		conf, _ := newTestStore(t, store.handle)
+		conf.OpenAIStub = true
+		hook := &webhookReceiver{status: http.StatusServiceUnavailable}
+		conf.CompletionWebhook = hook.start(t)
+
+		if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+			t.Fatalf("webhook failure failed the run: %v", err)
+		}
+		if len(hook.results) != 1 {
+			t.Errorf("webhook called %d times, want 1", len(hook.results))
+		}
+	})
+}
+
+func TestRedact(t *testing.T) {
+	conf := &Config{WooConsumerKey: "ck_live_1234", WooConsumerSecret: "cs_live_5678", OpenAIKey: "sk-abcdef", WpKey: "abc"}
+	got := conf.redact(`GET https://shop.example/wp-json/wc/v3/products?consumer_key=ck_other&consumer_secret=cs_other: key sk-abcdef, secret cs_live_5678, "abc"`)
+	for _, secret := range []string{"ck_other", "cs_other", "sk-abcdef", "cs_live_5678"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("redacted %q still holds %s", got, secret)
+		}
+	}
+	// Secrets too short to be told from ordinary text are left alone.
+	if !strings.Contains(got, `"abc"`) {
+		t.Errorf("short secret redacted: %s", got)
+	}
+}
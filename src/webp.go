@@ -0,0 +1,61 @@
+package wooh
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chai2010/webp"
+)
+
+const defaultWebPQuality = 80
+
+// canConvertToWebP reports whether path is a JPEG or PNG we can re-encode.
+func canConvertToWebP(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+// convertToWebP re-encodes the image at srcPath as a lossy WebP in a fresh
+// temporary directory and returns its path. The file keeps the source base
+// name so the uploaded media is named accordingly; callers remove its
+// directory when done.
+func convertToWebP(srcPath string, quality float32) (string, error) {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", srcPath, err)
+	}
+
+	dir, err := os.MkdirTemp("", "wooh-webp-")
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Base(srcPath)
+	dstPath := filepath.Join(dir, strings.TrimSuffix(base, filepath.Ext(base))+".webp")
+
+	out, err := os.Create(dstPath)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	defer out.Close()
+
+	if err := webp.Encode(out, img, &webp.Options{Quality: quality}); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to encode %s as WebP: %w", srcPath, err)
+	}
+	return dstPath, nil
+}
@@ -0,0 +1,115 @@
+package wooh
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chai2010/webp"
+)
+
+// writeJPEG writes a small gradient JPEG to path.
+func writeJPEG(t *testing.T, path string) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for x := 0; x < 32; x++ {
+		for y := 0; y < 32; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 8), uint8(y * 8), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertToWebP(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "oak.jpg")
+	writeJPEG(t, src)
+
+	dst, err := convertToWebP(src, defaultWebPQuality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(filepath.Dir(dst))
+	if filepath.Base(dst) != "oak.webp" {
+		t.Errorf("converted to %s, want oak.webp", filepath.Base(dst))
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := webp.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("output is not WebP: %v", err)
+	}
+	if img.Bounds().Dx() != 32 {
+		t.Errorf("converted image is %d pixels wide, want 32", img.Bounds().Dx())
+	}
+
+	bad := filepath.Join(t.TempDir(), "broken.jpg")
+	os.WriteFile(bad, []byte("not a jpeg"), 0644)
+	if _, err := convertToWebP(bad, defaultWebPQuality); err == nil {
+		t.Error("converted a file that is not an image")
+	}
+}
+
+func TestUploadConvertsToWebP(t *testing.T) {
+	tests := []struct {
+		name     string
+		convert  bool
+		valid    bool // a decodable JPEG
+		wantFile string
+		wantWebP bool
+	}{
+		{"enabled", true, true, "oak.webp", true},
+		{"disabled", false, true, "oak.jpg", false},
+		{"falls back on a bad image", true, false, "oak.jpg", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uploaded string
+			var content []byte
+			store := &skuStore{}
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/wp/v2/media") {
+					file, header, err := r.FormFile("file")
+					if err == nil {
+						uploaded = header.Filename
+						content, _ = io.ReadAll(file)
+					}
+				}
+				store.handle(w, r)
+			})
+			conf.ConvertToWebP = tt.convert
+
+			dir := t.TempDir()
+			src := filepath.Join(dir, "oak.jpg")
+			if tt.valid {
+				writeJPEG(t, src)
+			} else {
+				os.WriteFile(src, []byte("not a jpeg"), 0644)
+			}
+			if _, err := UploadImageToWordPress(conf, dir, UploadOptions{Quiet: true}); err != nil {
+				t.Fatal(err)
+			}
+			if uploaded != tt.wantFile {
+				t.Errorf("uploaded %q, want %q", uploaded, tt.wantFile)
+			}
+			_, err := webp.Decode(bytes.NewReader(content))
+			if isWebP := err == nil; isWebP != tt.wantWebP {
+				t.Errorf("uploaded WebP = %v, want %v", isWebP, tt.wantWebP)
+			}
+		})
+	}
+}
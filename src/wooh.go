@@ -1,22 +1,32 @@
 package wooh
 
 import (
-	"bufio"
+	"bytes"
+	"cmp"
 	"context"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/table"
 	"github.com/go-resty/resty/v2"
 	openai "github.com/sashabaranov/go-openai"
 	"github.com/sashabaranov/go-openai/jsonschema"
+	"golang.org/x/sync/singleflight"
 )
 
 type Category struct {
@@ -25,116 +35,270 @@ type Category struct {
 type JSONResponse struct {
 	MetaTitle       string `json:"meta_title"`
 	MetaDescription string `json:"meta_description"`
+	FocusKeyphrase  string `json:"focus_keyphrase"`
+	GoogleCategory  string `json:"google_product_category"`
 }
+
+// StructuredData controls the Google shopping meta written during SEO updates.
+type StructuredData struct {
+	Enabled           bool   `yaml:"enabled"`
+	GoogleCategoryKey string `yaml:"google_category_key"`
+	// GoogleCategory is written as is; when empty OpenAI infers it.
+	GoogleCategory string `yaml:"google_category"`
+	GTINKey        string `yaml:"gtin_key"`
+	// GTINPath is the JSON path of the product's GTIN in the products
+	// response, e.g. "global_unique_id" or "meta_data._ean", read the way
+	// custom_fields are. GTINs are never inferred, since a made-up
+	// identifier is worse than none.
+	GTINPath string `yaml:"gtin_path"`
+	// GTINFromSku copies the product SKU into GTINKey when GTINPath is not
+	// set.
+	GTINFromSku bool `yaml:"gtin_from_sku"`
+}
+
+const (
+	defaultGoogleCategoryKey = "_wc_gla_google_product_category"
+	defaultGTINKey           = "_wc_gla_gtin"
+)
+
+func (sd StructuredData) inferGoogleCategory() bool {
+	return sd.Enabled && sd.GoogleCategory == ""
+}
+
 type ProductMeta struct {
-	Name             *string
+	Name             string        `yaml:"name,omitempty"` // fixed product name; empty uses the image file name
 	Type             string        `yaml:"type"`
+	Status           string        `yaml:"status"`
 	RegularPrice     string        `yaml:"regular_price"`
 	Description      string        `yaml:"description"`
 	ShortDescription string        `yaml:"short_description"`
 	Categories       []interface{} `yaml:"categories"`
+	SkuPrefix        string        `yaml:"sku_prefix"`
+	SkuStart         int           `yaml:"sku_start"`
+	DuplicateSku     string        `yaml:"duplicate_sku"` // "suffix" (default) or "skip"
+	Weight           string        `yaml:"weight"`        // in the store's weight unit
+	Length           string        `yaml:"length"`        // in the store's dimension unit
+	Width            string        `yaml:"width"`
+	Height           string        `yaml:"height"`
 }
 type WooProduct struct {
-	ID               int64         `json:"id"`
-	Name             string        `json:"name"`
-	Description      string        `json:"description"`
-	ShortDescription string        `json:"short_description"`
-	Categories       []WooCategory `json:"categories"`
-	MetaData         []WooMetaData `json:"meta_data"`
+	ID               int64                  `json:"id"`
+	Name             string                 `json:"name"`
+	Sku              string                 `json:"sku"`
+	Description      string                 `json:"description"`
+	ShortDescription string                 `json:"short_description"`
+	RegularPrice     string                 `json:"regular_price,omitempty"`
+	Categories       []WooCategory          `json:"categories"`
+	MetaData         MetaData               `json:"meta_data"`
+	DateModifiedGMT  string                 `json:"date_modified_gmt,omitempty"`
+	Extra            map[string]interface{} `json:"extra,omitempty"` // from Config.CustomFields
+	GTIN             string                 `json:"gtin,omitempty"`  // from StructuredData.GTINPath
 }
 type WooCategory struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
 	Slug string `json:"slug"`
 }
-type WooMetaData struct {
-	ID    int64       `json:"id"`
-	Key   string      `json:"key"`
-	Value interface{} `json:"value"`
-}
+
+var productsFetchGroup singleflight.Group
 
 // -------------------------------------------------------------------
 // Fetch WooCommerce products, with cache
 // -------------------------------------------------------------------
-func GetProducts(conf *Config, maxCacheAge time.Duration) ([]WooProduct, error) {
-	var pc ProductCache
-	dir, err := os.Getwd()
-	ErrChk(err)
-	cacheDir := filepath.Join(dir, ".wooh-output")
-	if PathExist(cacheDir) == false {
-		err := os.Mkdir(cacheDir, 0755)
-		ErrChk(err)
-	}
-	cacheFilePath := filepath.Join(cacheDir, conf.CacheFilename)
-
-	if cachedData, err := pc.FetchFromCache(cacheFilePath, maxCacheAge); err == nil && cachedData != nil {
-		jsonBytes, err := json.Marshal(cachedData)
-		if err == nil {
-			var cachedProducts []WooProduct
-			err = json.Unmarshal(jsonBytes, &cachedProducts)
-			if err == nil {
-				return cachedProducts, nil
-			}
-		}
+func GetProducts(conf *Config, cache Cache, maxCacheAge time.Duration) ([]WooProduct, error) {
+	if conf.OfflineProducts != "" {
+		return loadOfflineProducts(conf)
 	}
 
-	log.Println("Fetching all products from API (paginated)...")
-	client := resty.New()
-	allProducts := make([]WooProduct, 0)
+	// Concurrent callers with a stale cache of the same file share a single
+	// catalog fetch.
+	cacheFilePath, err := CachePath(conf, conf.CacheFilename)
+	if err != nil {
+		return nil, err
+	}
+	v, err, _ := productsFetchGroup.Do(cacheFilePath, func() (interface{}, error) {
+		return getProductsWithCache(conf, cache, maxCacheAge)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]WooProduct), nil
+}
+func getProductsWithCache(conf *Config, cache Cache, maxCacheAge time.Duration) ([]WooProduct, error) {
+	if cachedProducts, ok, err := cache.Fetch(maxCacheAge); err == nil && ok {
+		return cachedProducts, nil
+	}
 
-	page, perPage := 1, 100
-	for {
-		resp, err := client.R().
-			SetHeader("Accept", "application/json").
-			SetQueryParams(map[string]string{
-				"page":     fmt.Sprintf("%d", page),
-				"per_page": fmt.Sprintf("%d", perPage),
-			}).
-			Get(fmt.Sprintf(
-				"https://%s/wp-json/wc/v3/products?consumer_key=%s&consumer_secret=%s",
-				conf.Site, conf.WooConsumerKey, conf.WooConsumerSecret,
-			))
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch products on page %d: %w", page, err)
-		}
-		if resp.IsError() {
-			return nil, fmt.Errorf("error fetching page %d: %s, %s", page, resp.Status(), resp.String())
-		}
+	slog.Info("Fetching all products from API (paginated)")
+	allProducts, err := fetchProductPages(conf, nil)
+	if err != nil {
+		return nil, err
+	}
 
-		var products []WooProduct
-		if err := json.Unmarshal(resp.Body(), &products); err != nil {
-			return nil, fmt.Errorf("failed to parse products on page %d: %w", page, err)
-		}
+	if err := cache.Save(allProducts); err != nil {
+		slog.Warn("Could not save product cache", "err", err)
+	}
+	return allProducts, nil
+}
+
+// loadCachedProducts reads the product cache in format, json or gob. It
+// returns nil when the cache is missing or older than maxCacheAge.
+func loadCachedProducts(format string, pc *ProductCache, cacheFilePath string, maxCacheAge time.Duration) ([]WooProduct, error) {
+	if format == CacheFormatGob {
+		return loadGobCache(cacheFilePath, maxCacheAge)
+	}
+	cachedData, err := pc.FetchFromCache(cacheFilePath, maxCacheAge)
+	if err != nil || cachedData == nil {
+		return nil, err
+	}
+	jsonBytes, err := json.Marshal(cachedData)
+	if err != nil {
+		return nil, err
+	}
+	var cachedProducts []WooProduct
+	if err := json.Unmarshal(jsonBytes, &cachedProducts); err != nil {
+		return nil, err
+	}
+	return cachedProducts, nil
+}
+
+// fetchProductPages pages through the products endpoint with the configured
+// ordering plus any extra query params, using the Store API in store read mode.
+func fetchProductPages(conf *Config, params map[string]string) ([]WooProduct, error) {
+	if conf.ReadMode == ReadModeStore {
+		return fetchStoreProducts(conf, params)
+	}
+
+	query := productQueryParams(conf)
+	query["orderby"] = conf.OrderBy
+	query["order"] = conf.Order
+	for k, v := range params {
+		query[k] = v
+	}
+	products, err := paginate[WooProduct](context.Background(), newClient(conf), conf, "products", query)
+	if err != nil {
+		return nil, err
+	}
+	if products == nil {
+		products = make([]WooProduct, 0)
+	}
+	return products, nil
+}
+
+// defaultProductFields are the product fields WooProduct reads.
+var defaultProductFields = []string{
+	"id", "name", "sku", "description", "short_description", "regular_price",
+	"categories", "meta_data", "date_modified_gmt",
+}
 
-		allProducts = append(allProducts, products...)
-		if len(products) < perPage {
-			break
+// productFields returns the _fields value for product queries: the
+// configured fields plus id and the top-level field of each custom field
+// path and of the GTIN path. It is "" when fields is an empty list, which
+// requests every field.
+func productFields(conf *Config) string {
+	if len(conf.Fields) == 0 {
+		return ""
+	}
+	seen := map[string]bool{"id": true}
+	fields := []string{"id"}
+	add := func(field string) {
+		if field = strings.TrimSpace(field); field != "" && !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
 		}
-		page++
 	}
+	for _, field := range conf.Fields {
+		add(field)
+	}
+	for _, path := range conf.CustomFields {
+		root, _, _ := strings.Cut(path, ".")
+		add(root)
+	}
+	if conf.StructuredData.GTINPath != "" {
+		root, _, _ := strings.Cut(conf.StructuredData.GTINPath, ".")
+		add(root)
+	}
+	return strings.Join(fields, ",")
+}
+
+// productQueryParams are the query parameters sent with every product list
+// request.
+func productQueryParams(conf *Config) map[string]string {
+	params := map[string]string{}
+	if fields := productFields(conf); fields != "" {
+		params["_fields"] = fields
+	}
+	return params
+}
 
-	pc.SaveToCache(cacheFilePath, allProducts)
-	return allProducts, nil
+// GetProduct fetches a single product live from the API.
+func GetProduct(conf *Config, id int) (WooProduct, error) {
+	return getProduct(context.Background(), conf, id)
+}
+
+func getProduct(ctx context.Context, conf *Config, id int) (WooProduct, error) {
+	if conf.OfflineProducts != "" {
+		return offlineProduct(conf, id)
+	}
+	var product WooProduct
+	resp, err := newClient(conf).R().
+		SetContext(ctx).
+		SetHeader("Accept", "application/json").
+		Get(wooEndpoint(conf, fmt.Sprintf("products/%d", id)))
+	if err != nil {
+		return product, fmt.Errorf("failed to fetch product %d: %w", id, err)
+	}
+	if resp.IsError() {
+		return product, fmt.Errorf("error fetching product %d: %w", id, apiError(resp))
+	}
+	if err := decodeProduct(conf, resp, &product); err != nil {
+		return product, fmt.Errorf("failed to parse product %d: %w", id, err)
+	}
+	return product, nil
+}
+
+// SearchProducts returns all products matching query, fetched live.
+func SearchProducts(conf *Config, query string) ([]WooProduct, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("search query must not be empty")
+	}
+	return fetchProductPages(conf, map[string]string{"search": query})
+}
+
+// pageDelay returns the pause between page fetches: PageDelay plus a random
+// amount up to PageDelayJitter.
+func pageDelay(conf *Config) time.Duration {
+	delay := conf.PageDelay
+	if conf.PageDelayJitter > 0 {
+		delay += time.Duration(rand.Int64N(int64(conf.PageDelayJitter)))
+	}
+	return delay
 }
 func ListProductMeta(conf *Config) {
-	products, err := GetProducts(conf, 24*time.Hour)
+	cache, err := NewCache(conf)
 	if err != nil {
-		log.Fatalf("Error fetching products: %v", err)
+		fatal("Error opening product cache", "err", err)
 	}
-	log.Printf("Fetched %d products\n", len(products))
+	products, err := GetProducts(conf, cache, 24*time.Hour)
+	if err != nil {
+		fatal("Error fetching products", "err", err)
+	}
+	slog.Info("Fetched products", "products", len(products))
 
 	for _, product := range products {
 		fmt.Printf("ID: %v\n", product.ID)
 		fmt.Printf("Name: %v\n", product.Name)
 
-		for _, meta := range product.MetaData {
-			switch meta.Key {
-			case "_yoast_wpseo_title":
-				fmt.Printf("Yoast Title: %v\n", meta.Value)
-			case "_yoast_wpseo_metadesc":
-				fmt.Printf("Yoast Meta Description: %v\n", meta.Value)
-			}
+		if title := product.MetaData.YoastTitle(); title != "" {
+			fmt.Printf("Yoast Title: %v\n", title)
 		}
+		if desc := product.MetaData.YoastDesc(); desc != "" {
+			fmt.Printf("Yoast Meta Description: %v\n", desc)
+		}
+		if kw := product.MetaData.YoastFocusKW(); kw != "" {
+			fmt.Printf("Yoast Focus Keyphrase: %v\n", kw)
+		}
+		fmt.Printf("SEO Score: %s\n", ScoreSEO(product))
 
 		fmt.Println()
 	}
@@ -189,327 +353,1103 @@ Here is the product information:
 - Categories: %v
 `, productName, shortDescription, description, categories)
 }
-func OpenAIProcess(conf *Config, userPrompt string) (string, string, error) {
-	client := openai.NewClient(conf.OpenAIKey)
+func OpenAINameOnlyPrompt() string {
+	return `
+This product has no description: only its name and categories are known.
+Do not invent materials, dimensions, specifications or other details that are not implied by the name.
+`
+}
+func OpenAITitleSuffixPrompt(suffix string, limit int) string {
+	return fmt.Sprintf(`
+The text %q is appended to every meta title automatically. Do not include it yourself.
+The meta title you return must be %d characters or fewer so the full title stays within 60 characters.
+`, suffix, limit)
+}
+func OpenAIFocusKeyphrasePrompt() string {
+	return `
+Also generate a **focus keyphrase** (2 to 4 words) that:
+   - Is the search phrase a customer would most likely use to find this product.
+   - Appears naturally in the meta title and meta description.
+`
+}
+
+// SystemPromptFor returns the system prompt for a product, using the
+// category_prompts entry for its primary (first) category when one exists.
+// An entry is either a path to a prompt file or the prompt text itself.
+func SystemPromptFor(conf *Config, categories []WooCategory) (string, error) {
+	if len(categories) == 0 {
+		return OpenAIGenSystemPrompt(), nil
+	}
+	override, ok := conf.CategoryPrompts[int(categories[0].ID)]
+	if !ok || strings.TrimSpace(override) == "" {
+		return OpenAIGenSystemPrompt(), nil
+	}
+	if !strings.Contains(override, "\n") && PathExist(override) {
+		data, err := os.ReadFile(override)
+		if err != nil {
+			return "", fmt.Errorf("failed to read category prompt %s: %w", override, err)
+		}
+		return string(data), nil
+	}
+	return override, nil
+}
+
+// ModelFor returns the generator model for a product: the category_models
+// entry for its primary (first) category, or conf.GeneratorModel.
+func ModelFor(conf *Config, categories []WooCategory) string {
+	if len(categories) > 0 {
+		if model := strings.TrimSpace(conf.CategoryModels[int(categories[0].ID)]); model != "" {
+			return model
+		}
+	}
+	return conf.GeneratorModel
+}
+
+// ErrTruncatedOutput is returned by OpenAIProcess when the model stopped
+// before completing its JSON, usually because max_tokens was too low.
+var ErrTruncatedOutput = errors.New("OpenAI output was truncated")
+
+// ErrContentPolicy is returned when OpenAI refuses to answer or its content
+// filter stops the response. Retrying won't help, so the product is skipped.
+var ErrContentPolicy = errors.New("OpenAI refused the request (content policy)")
+
+// ErrInvalidMeta is returned when the generated JSON parses but does not
+// match the meta schema: a key is missing or unexpected, or a value is not a
+// string.
+var ErrInvalidMeta = errors.New("generated meta does not match the schema")
+
+const defaultOpenAIMaxTokens = 300
+
+const defaultOpenAIModel = openai.GPT4oMini
+
+// maxTokenEscalations bounds how often generateMeta doubles max_tokens after
+// a truncated response. These retries are separate from length-limit retries.
+const maxTokenEscalations = 2
+
+// maxInvalidMetaRetries bounds how often generateMeta asks again after the
+// output failed schema validation.
+const maxInvalidMetaRetries = 2
+
+// isTruncatedJSON reports whether s ends before its objects, arrays or
+// strings are closed.
+func isTruncatedJSON(s string) bool {
+	depth, inString, escaped := 0, false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '{' || r == '[':
+			depth++
+		case r == '}' || r == ']':
+			depth--
+		}
+	}
+	return inString || depth > 0
+}
+
+// generateMeta asks the configured generator for meta, retrying with a
+// larger max_tokens budget when the output was cut off and asking again when
+// it did not match the schema.
+func generateMeta(ctx context.Context, conf *Config, systemPrompt string, userPrompt string) (JSONResponse, error) {
+	maxTokens := conf.OpenAIMaxTokens
+	escalations, invalid := 0, 0
+	for {
+		generated, err := newGenerator(conf).Meta(ctx, systemPrompt, userPrompt, maxTokens)
+		switch {
+		case errors.Is(err, ErrTruncatedOutput) && escalations < maxTokenEscalations:
+			escalations++
+			maxTokens *= 2
+			slog.Warn("Generated output truncated, retrying", "max_tokens", maxTokens)
+		case errors.Is(err, ErrInvalidMeta) && invalid < maxInvalidMetaRetries:
+			invalid++
+			slog.Warn("Retrying", "err", err)
+		default:
+			return generated, err
+		}
+	}
+}
+
+// structuredDataMeta returns the Google category and GTIN meta entries for a
+// product, preferring the configured category over the generated one.
+func structuredDataMeta(sd StructuredData, product WooProduct, generatedCategory string) MetaData {
+	var meta MetaData
+	category := sd.GoogleCategory
+	if category == "" {
+		category = generatedCategory
+	}
+	if category != "" {
+		meta.Set(sd.GoogleCategoryKey, category)
+	}
+	gtin := product.GTIN
+	if sd.GTINPath == "" && sd.GTINFromSku {
+		gtin = product.Sku
+	}
+	if gtin != "" {
+		meta.Set(sd.GTINKey, gtin)
+	}
+	return meta
+}
+func OpenAIGoogleCategoryPrompt() string {
+	return `
+Also choose the **google product category**: the full path from the Google product taxonomy
+that best fits the product (e.g. "Hardware > Building Materials > Flooring & Carpet").
+`
+}
+func contentPolicyError(choice openai.ChatCompletionChoice) error {
+	if choice.Message.Refusal != "" {
+		return fmt.Errorf("%w: %s", ErrContentPolicy, choice.Message.Refusal)
+	}
+	if choice.FinishReason == openai.FinishReasonContentFilter {
+		return ErrContentPolicy
+	}
+	return nil
+}
+
+// metaToolName is the function the model calls with the meta when
+// use_tool_calling is on.
+const metaToolName = "set_seo_meta"
+
+// modelsWithoutTools are prefixes of OpenAI models that cannot call
+// functions.
+var modelsWithoutTools = []string{"o1-mini", "o1-preview", "gpt-3.5-turbo-instruct", "davinci", "babbage"}
+
+var toolFallbackWarned sync.Map
+
+// useToolCalling reports whether meta is requested as a tool call rather
+// than a JSON schema response. use_tool_calling falls back to the schema,
+// with a warning per model, when the model cannot call tools.
+func useToolCalling(conf *Config) bool {
+	if !conf.UseToolCalling {
+		return false
+	}
+	for _, prefix := range modelsWithoutTools {
+		if strings.HasPrefix(conf.GeneratorModel, prefix) {
+			if _, warned := toolFallbackWarned.LoadOrStore(conf.GeneratorModel, true); !warned {
+				slog.Warn("Model does not support tool calling, using a JSON schema response", "model", conf.GeneratorModel)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// toolArguments returns the arguments of the message's call to name, or ""
+// when it made none.
+func toolArguments(msg openai.ChatCompletionMessage, name string) string {
+	for _, call := range msg.ToolCalls {
+		if call.Function.Name == name {
+			return call.Function.Arguments
+		}
+	}
+	return ""
+}
+
+// metaSchema returns the JSON schema of the meta response for conf and
+// systemPrompt extended with the instructions for its optional fields.
+func metaSchema(conf *Config, systemPrompt string) (*jsonschema.Definition, string, error) {
+	schema, err := jsonschema.GenerateSchemaForType(JSONResponse{})
+	if err != nil {
+		return nil, systemPrompt, fmt.Errorf("failed to generate JSON schema: %w", err)
+	}
+	if conf.FocusKeyphrase {
+		systemPrompt += OpenAIFocusKeyphrasePrompt()
+	} else {
+		delete(schema.Properties, "focus_keyphrase")
+		schema.Required = Filter(schema.Required, func(s string) bool { return s != "focus_keyphrase" })
+	}
+	if conf.StructuredData.inferGoogleCategory() {
+		systemPrompt += OpenAIGoogleCategoryPrompt()
+	} else {
+		delete(schema.Properties, "google_product_category")
+		schema.Required = Filter(schema.Required, func(s string) bool { return s != "google_product_category" })
+	}
+	return schema, systemPrompt, nil
+}
+
+func OpenAIProcess(ctx context.Context, conf *Config, systemPrompt string, userPrompt string, maxTokens int) (JSONResponse, error) {
+	client := newOpenAIClient(conf)
 
 	var responseStruct JSONResponse
 
-	schema, err := jsonschema.GenerateSchemaForType(responseStruct)
+	schema, systemPrompt, err := metaSchema(conf, systemPrompt)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to generate JSON schema: %w", err)
-	}
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4oMini,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: OpenAIGenSystemPrompt(),
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: userPrompt,
-				},
+		return responseStruct, err
+	}
+	req := openai.ChatCompletionRequest{
+		Model: conf.GeneratorModel,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: systemPrompt,
 			},
-			ResponseFormat: &openai.ChatCompletionResponseFormat{
-				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
-				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
-					Name:   "metadata_generation",
-					Schema: schema,
-					Strict: true,
-				},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: userPrompt,
 			},
-			Temperature: 0.7,
 		},
-	)
+		MaxTokens:   maxTokens,
+		Temperature: 0.7,
+	}
+	useTools := useToolCalling(conf)
+	if useTools {
+		req.Tools = []openai.Tool{{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        metaToolName,
+				Description: "Set the SEO meta of the product",
+				Strict:      true,
+				Parameters:  schema,
+			},
+		}}
+		req.ToolChoice = openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: metaToolName}}
+	} else {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "metadata_generation",
+				Schema: schema,
+				Strict: true,
+			},
+		}
+	}
+	resp, err := client.CreateChatCompletion(ctx, req)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get chat completion: %w", err)
+		writeDebugDump(conf, DebugDump{Kind: "meta", SystemPrompt: systemPrompt, UserPrompt: userPrompt, Error: err.Error()})
+		return responseStruct, fmt.Errorf("failed to get chat completion: %w", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", "", fmt.Errorf("no choices returned by OpenAI API")
+		return responseStruct, fmt.Errorf("no choices returned by OpenAI API")
 	}
-
 	content := resp.Choices[0].Message.Content
+	if useTools {
+		content = toolArguments(resp.Choices[0].Message, metaToolName)
+	}
+	writeDebugDump(conf, DebugDump{Kind: "meta", SystemPrompt: systemPrompt, UserPrompt: userPrompt, Response: content})
 
-	var parsed map[string]string
+	if refusal := contentPolicyError(resp.Choices[0]); refusal != nil {
+		return responseStruct, refusal
+	}
+
+	if useTools && content == "" && resp.Choices[0].FinishReason != openai.FinishReasonLength {
+		return responseStruct, fmt.Errorf("%w: no %s tool call in the response", ErrInvalidMeta, metaToolName)
+	}
+	if resp.Choices[0].FinishReason == openai.FinishReasonLength || isTruncatedJSON(content) {
+		return responseStruct, fmt.Errorf("%w; raw content: %s", ErrTruncatedOutput, content)
+	}
+	return parseMetaJSON(conf, content)
+}
+
+// parseMetaJSON reads a meta response strictly: it must hold exactly the
+// fields conf asks for, each a string.
+func parseMetaJSON(conf *Config, content string) (JSONResponse, error) {
+	var responseStruct JSONResponse
+	var parsed map[string]json.RawMessage
 	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
-		return "", "", fmt.Errorf("failed to parse JSON: %w; raw content: %s", err, content)
+		return responseStruct, fmt.Errorf("failed to parse JSON: %w; raw content: %s", err, content)
 	}
 
-	metaTitle, ok := parsed["meta_title"]
-	if !ok {
-		return "", "", fmt.Errorf(`JSON response did not include "meta_title"`)
+	fields := map[string]*string{
+		"meta_title":       &responseStruct.MetaTitle,
+		"meta_description": &responseStruct.MetaDescription,
+	}
+	if conf.FocusKeyphrase {
+		fields["focus_keyphrase"] = &responseStruct.FocusKeyphrase
+	}
+	if conf.StructuredData.inferGoogleCategory() {
+		fields["google_product_category"] = &responseStruct.GoogleCategory
 	}
 
-	metaDescription, ok := parsed["meta_description"]
-	if !ok {
-		return "", "", fmt.Errorf(`JSON response did not include "meta_description"`)
+	for key := range parsed {
+		if _, ok := fields[key]; !ok {
+			return responseStruct, fmt.Errorf("%w: unexpected key %q", ErrInvalidMeta, key)
+		}
+	}
+	for key, dest := range fields {
+		raw, ok := parsed[key]
+		if !ok {
+			return responseStruct, fmt.Errorf("%w: JSON response did not include %q", ErrInvalidMeta, key)
+		}
+		if err := json.Unmarshal(raw, dest); err != nil || bytes.TrimSpace(raw)[0] != '"' {
+			return responseStruct, fmt.Errorf("%w: %q must be a string, got %s", ErrInvalidMeta, key, raw)
+		}
 	}
 
-	return metaTitle, metaDescription, nil
+	return responseStruct, nil
 }
 
 // -------------------------------------------------------------------
-// Helper to convert HTML to Markdown (unchanged)
+// Helper to convert HTML to Markdown
 // -------------------------------------------------------------------
-func cleanHTMLToMarkdown(html string) (string, error) {
-	markdown, err := htmltomarkdown.ConvertString(html)
+type MarkdownOptions struct {
+	PreserveImages bool        `yaml:"preserve_images"` // keep images as their alt text
+	KeepTables     bool        `yaml:"keep_tables"`     // render tables as Markdown tables
+	HeadingRemap   map[int]int `yaml:"heading_remap"`   // heading level -> replacement level
+}
+
+func DefaultMarkdownOptions() MarkdownOptions {
+	return MarkdownOptions{HeadingRemap: map[int]int{4: 2}}
+}
+
+var (
+	markdownImageRegex   = regexp.MustCompile(`!\[(.*?)\]\(.*?\)`)
+	markdownHeadingRegex = regexp.MustCompile(`(?m)^(#{1,6}) `)
+	markdownNewlineRegex = regexp.MustCompile(`\n{2,}`)
+)
+
+func cleanHTMLToMarkdown(html string, opts MarkdownOptions) (string, error) {
+	plugins := []converter.Plugin{base.NewBasePlugin(), commonmark.NewCommonmarkPlugin()}
+	if opts.KeepTables {
+		plugins = append(plugins, table.NewTablePlugin())
+	}
+	markdown, err := converter.NewConverter(converter.WithPlugins(plugins...)).ConvertString(html)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
+	}
+
+	markdown = markdownHeadingRegex.ReplaceAllStringFunc(markdown, func(heading string) string {
+		level := len(heading) - 1
+		if to, ok := opts.HeadingRemap[level]; ok && to >= 1 && to <= 6 {
+			return strings.Repeat("#", to) + " "
+		}
+		return heading
+	})
+	if opts.PreserveImages {
+		markdown = markdownImageRegex.ReplaceAllString(markdown, "$1")
+	} else {
+		markdown = markdownImageRegex.ReplaceAllString(markdown, "")
 	}
-	markdown = strings.ReplaceAll(markdown, "####", "##")
-	imageRegex := regexp.MustCompile(`!\[.*?\]\(.*?\)`)
-	markdown = imageRegex.ReplaceAllString(markdown, "")
-	newlineRegex := regexp.MustCompile(`\n{2,}`)
-	markdown = newlineRegex.ReplaceAllString(markdown, "\n")
+	markdown = markdownNewlineRegex.ReplaceAllString(markdown, "\n")
 	markdown = strings.TrimSpace(markdown)
 
 	return markdown, nil
 }
 
 // -------------------------------------------------------------------
-// UpdateSEO now has a restartTracking param and uses the tracker
+// UpdateSEO generates Yoast meta for every untracked product
 // -------------------------------------------------------------------
-func UpdateSEO(conf *Config, restartTracking bool, prompt bool) error {
-	client := resty.New()
-	client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+type SEOOptions struct {
+	RestartTracking bool // ignore the tracker and start fresh
+	RestartRun      bool // ignore the saved position of an interrupted run
+	Prompt          bool // ask for confirmation for each product
+	// RegenerateDescriptions rewrites description/short_description when
+	// they are shorter than the configured minimum lengths.
+	RegenerateDescriptions bool
+	// Diff prints current vs generated meta for each product without writing.
+	Diff bool
+	// ExportOnly writes the generated meta to this file for review instead
+	// of updating the store; see ApplySEOFromFile.
+	ExportOnly string
+	// OrderBy "sales" processes the best sellers of the last 90 days first;
+	// empty keeps the store order.
+	OrderBy string
+	// Progress is called after each processed product; defaults to logging
+	// an ETA periodically.
+	Progress ProgressFunc
+	// Force reprocesses products already in the tracker, keeping the
+	// records of the others.
+	Force bool
+	// Enrich expands descriptions shorter than min_description_words before
+	// generating meta from them.
+	Enrich bool
+	// IgnoreIDs are left untouched in addition to the config's ignore_ids,
+	// whatever the tracker says and even with Force.
+	IgnoreIDs []int
+	// Quiet leaves out the progress lines; the summary is still printed.
+	Quiet bool
+	// Context stops the run when cancelled: no new product is started,
+	// requests in flight are cancelled, generated updates not yet written
+	// stay in the write-ahead log and the tracker is saved. Nil never
+	// cancels.
+	Context context.Context
+	// OnlyImprove skips products whose generated meta does not beat the
+	// current meta's SEO score by more than improve_margin.
+	OnlyImprove bool
+	// MinPrice and MaxPrice, when above zero, limit the run to products whose
+	// regular price lies within them. Products without a price are left out.
+	MinPrice float64
+	MaxPrice float64
+}
+
+const seoOrderBySales = "sales"
+
+const (
+	EmptyDescriptionSkip     = "skip"      // leave the product untouched
+	EmptyDescriptionNameOnly = "name_only" // generate from name and categories with a cautious prompt
+	EmptyDescriptionFlag     = "flag"      // record it in SEOResult.Flagged for manual review
+)
 
-	dir, err := os.Getwd()
-	ErrChk(err)
-	cacheDir := filepath.Join(dir, ".wooh-output")
-	if PathExist(cacheDir) == false {
-		err := os.Mkdir(cacheDir, 0755)
-		ErrChk(err)
+var allowedEmptyDescriptionPolicies = []string{EmptyDescriptionSkip, EmptyDescriptionNameOnly, EmptyDescriptionFlag}
+
+func ValidateEmptyDescriptionPolicy(policy string) error {
+	for _, allowed := range allowedEmptyDescriptionPolicies {
+		if policy == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported empty_description_policy %q (allowed: %s)", policy, strings.Join(allowedEmptyDescriptionPolicies, ", "))
+}
+
+const (
+	defaultOpenAIConcurrency = 1
+	defaultWooConcurrency    = 1
+	defaultPageConcurrency   = 1
+	defaultPerProductTimeout = 60 * time.Second
+)
+
+// SEOResult lists the product IDs by outcome of an UpdateSEO run.
+type SEOResult struct {
+	Updated []int
+	Skipped []int // already tracked or rejected at the prompt
+	Failed  []int
+	// PolicySkipped products were refused by OpenAI's content policy.
+	PolicySkipped []int
+	// Flagged products have no description and were left for manual review
+	// under empty_description_policy "flag".
+	Flagged []int
+	// Enriched products had their description expanded before generating
+	// meta because it was shorter than min_description_words.
+	Enriched []int
+
+	mu sync.Mutex // guards the slices while workers are running
+	// processed counts every product that reached an outcome, including
+	// diff and export runs, which record nothing above.
+	processed atomic.Int64
+}
+
+func (r *SEOResult) record(list *[]int, productID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*list = append(*list, productID)
+}
+
+// Summary is a one-line account of the run, e.g. "Processed 120, updated
+// 100, skipped 15, failed 5 in 3m12s".
+func (r *SEOResult) Summary(elapsed time.Duration) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	summary := fmt.Sprintf("Processed %d, updated %d, skipped %d, failed %d",
+		r.processed.Load(), len(r.Updated), len(r.Skipped), len(r.Failed))
+	if len(r.PolicySkipped) > 0 {
+		summary += fmt.Sprintf(", policy-skipped %d", len(r.PolicySkipped))
+	}
+	if len(r.Flagged) > 0 {
+		summary += fmt.Sprintf(", flagged %d", len(r.Flagged))
+	}
+	if len(r.Enriched) > 0 {
+		summary += fmt.Sprintf(", enriched %d", len(r.Enriched))
+	}
+	return summary + " in " + elapsed.Round(time.Second).String()
+}
+
+func (r *SEOResult) recordGenerationError(productID int, err error) {
+	if errors.Is(err, ErrContentPolicy) {
+		r.record(&r.PolicySkipped, productID)
+		return
+	}
+	r.record(&r.Failed, productID)
+}
+
+// UpdateSEO runs an SEO update and, when completion_webhook is set, reports
+// how it ended there.
+func UpdateSEO(conf *Config, opts SEOOptions) (*SEOResult, error) {
+	start := time.Now()
+	result, err := updateSEO(conf, opts)
+	if conf.CompletionWebhook != "" {
+		notifyCompletion(conf, newRunResult(conf, start, result, err))
+	}
+	return result, err
+}
+
+func updateSEO(conf *Config, opts SEOOptions) (*SEOResult, error) {
+	start := time.Now()
+	result := &SEOResult{}
+	trackerFilepath, err := CachePath(conf, conf.TrackerFilename)
+	if err != nil {
+		return nil, err
 	}
-	trackerFilepath := filepath.Join(cacheDir, conf.TrackerFilename)
 
 	var tracker *TrackerUpdate
-	fmt.Println("Starting SEO update...")
-	if restartTracking {
-		fmt.Println("Starting Fresh Tracker...")
+	if !opts.Quiet {
+		fmt.Println("Starting SEO update...")
+	}
+	if opts.RestartTracking {
+		if !opts.Quiet {
+			fmt.Println("Starting Fresh Tracker...")
+		}
 		tracker = &TrackerUpdate{UpdatedIDs: make(map[int]bool)}
 	} else {
 		var err error
 
 		tracker, err = TrackerLoad(trackerFilepath)
 		if err != nil {
-			return fmt.Errorf("failed to load SEO update tracker: %w", err)
+			return nil, fmt.Errorf("failed to load SEO update tracker: %w", err)
 		}
 	}
 
+	cache, err := NewCache(conf)
+	if err != nil {
+		return nil, err
+	}
 	maxCacheAge := 24 * time.Hour
-	products, err := GetProducts(conf, maxCacheAge)
+	products, err := GetProducts(conf, cache, maxCacheAge)
 	if err != nil {
-		return fmt.Errorf("failed to fetch products: %w", err)
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
 	}
-	fmt.Printf("Products To Be Processed: %d\n", len(products))
-	reader := bufio.NewReader(os.Stdin)
-
-	for _, product := range products {
-		rawID := product.ID
-		productID := int(rawID)
-
-		if tracker.UpdatedIDs[productID] {
-			log.Printf("Skipping product ID %v (already updated)\n", productID)
-			continue
+	if opts.MinPrice > 0 || opts.MaxPrice > 0 {
+		inBand := filterByPrice(products, opts.MinPrice, opts.MaxPrice)
+		if len(inBand) == 0 && len(filterByPrice(products, 0, 0)) == 0 && len(products) > 0 {
+			slog.Warn("No product has a regular price; the product cache may predate price filtering, remove it to refresh it", "cache", conf.CacheFilename)
 		}
-		log.Printf("Processing product ID %v\n", productID)
+		products = inBand
+	}
+	if !opts.Quiet {
+		fmt.Printf("Products To Be Processed: %d\n", len(products))
+	}
 
-		productName := product.Name
-		shortDescription := product.ShortDescription
-		description := product.Description
-		categories := product.Categories
+	run, err := newSEORun(conf, opts, tracker, trackerFilepath, result)
+	if err != nil {
+		return nil, err
+	}
+	defer run.close()
+	ctx := run.ctx
 
-		cleanedDescription, err := cleanHTMLToMarkdown(description)
+	if opts.OrderBy == seoOrderBySales {
+		ranked, err := TopSellingProductIDs(conf, time.Now().Add(-defaultSalesWindow), 0)
 		if err != nil {
-			return fmt.Errorf("failed to clean description for product ID %v: %w", productID, err)
+			return result, fmt.Errorf("failed to rank products by sales: %w", err)
 		}
+		sortBySales(products, ranked)
+	}
 
-		const maxTitleLength = 60
-		const maxDescriptionLength = 160
+	// Diff and export runs write nothing, so they neither resume nor record
+	// a position. Other runs work through products by ID, so the position
+	// still holds after the product cache is refreshed; runs ordered by
+	// sales keep their order and remember every product they processed.
+	runStatePath, err := CachePath(conf, conf.RunStateFilename)
+	if err != nil {
+		return nil, err
+	}
+	var state *RunState
+	byID := opts.OrderBy != seoOrderBySales
+	if !opts.Diff && opts.ExportOnly == "" {
+		if opts.RestartTracking || opts.RestartRun {
+			if err := clearRunState(runStatePath); err != nil {
+				return nil, err
+			}
+		}
+		state, err = LoadRunState(runStatePath)
+		if err != nil {
+			return nil, err
+		}
+		if state.LastID > 0 || len(state.ProcessedIDs) > 0 {
+			slog.Info("Resuming run", "started", state.StartedAt.Format(time.RFC3339), "after_product_id", state.LastID)
+		}
+		if byID {
+			products = slices.Clone(products)
+			slices.SortStableFunc(products, func(a, b WooProduct) int { return cmp.Compare(a.ID, b.ID) })
+		}
+	}
 
-		var metaTitle, metaDescription string
-		retries := 1
+	// Updates generated by an earlier run but never written are applied
+	// before anything new is generated. Failed ones stay buffered for the
+	// next run rather than being generated again.
+	ignored := make(map[int]bool)
+	for _, id := range append(append([]int{}, conf.IgnoreIDs...), opts.IgnoreIDs...) {
+		ignored[id] = true
+	}
+	replayed := make(map[int]bool)
+	if run.wal != nil {
+		for _, update := range run.wal.unwritten() {
+			if ignored[int(update.Product.ID)] || run.skipList.contains(int(update.Product.ID)) {
+				continue
+			}
+			slog.Info("Applying buffered update", "product_id", update.Product.ID)
+			run.write(update)
+			result.processed.Add(1)
+			replayed[int(update.Product.ID)] = true
+		}
+	}
 
-		for i := 0; i < retries; i++ {
-			userPrompt := OpenAIUserPrompt(productName, shortDescription, cleanedDescription, categories)
-			metaTitle, metaDescription, err = OpenAIProcess(conf, userPrompt)
-			if err != nil {
-				log.Printf("Error generating meta fields for product ID %v: %v", productID, err)
+	remaining := products
+	if state != nil {
+		remaining = slices.DeleteFunc(slices.Clone(products), func(p WooProduct) bool { return state.done(p.ID) })
+	}
+	var pages [][]WooProduct
+	var pageEnds []int64 // ID of the last product of each page
+	pending := 0
+	for start := 0; start < len(remaining); start += runStatePageSize {
+		end := min(start+runStatePageSize, len(remaining))
+		var todo []WooProduct
+		for _, product := range remaining[start:end] {
+			productID := int(product.ID)
+			if replayed[productID] {
 				continue
 			}
-			if len(metaTitle) <= maxTitleLength && len(metaDescription) <= maxDescriptionLength {
-				break
-			} else {
-				log.Printf("Meta fields exceeded char limits for product ID %v (attempt %d/%d)", productID, i+1, retries)
+			if ignored[productID] {
+				slog.Info("Skipping product: ignored", "product_id", productID)
+				result.record(&result.Skipped, productID)
+				result.processed.Add(1)
+				continue
 			}
+			if run.skipList.contains(productID) {
+				slog.Info("Skipping product: skip list", "product_id", productID)
+				result.record(&result.Skipped, productID)
+				result.processed.Add(1)
+				continue
+			}
+			if tracker.UpdatedIDs[productID] && !opts.Force {
+				slog.Info("Skipping product: already updated", "product_id", productID)
+				result.record(&result.Skipped, productID)
+				result.processed.Add(1)
+				continue
+			}
+			todo = append(todo, product)
+		}
+		pages = append(pages, todo)
+		pageEnds = append(pageEnds, remaining[end-1].ID)
+		pending += len(todo)
+	}
+
+	if opts.RegenerateDescriptions && !opts.Diff && opts.ExportOnly == "" {
+		if n := countDescriptionRewrites(conf, pages); n > 0 && !confirmDestructive(n, "regenerate descriptions") {
+			return result, ErrNotConfirmed
 		}
+	}
 
-		if len(metaTitle) > maxTitleLength || len(metaDescription) > maxDescriptionLength {
-			log.Printf("Failed to generate valid meta fields for product ID %v after %d retries", productID, retries)
-			continue
+	defer func() { fmt.Println(result.Summary(time.Since(start))) }()
+
+	progress := opts.Progress
+	if progress == nil {
+		progress = logProgress
+	}
+	var (
+		eta        = newETAEstimator(time.Now())
+		done       int
+		progressMu sync.Mutex
+	)
+	finished := func(product WooProduct) {
+		// After an interrupt a product may leave the pipeline unwritten.
+		if ctx.Err() != nil {
+			return
+		}
+		result.processed.Add(1)
+		if state != nil {
+			if err := state.markProcessed(int(product.ID)); err != nil {
+				slog.Warn("Could not save run state", "err", err)
+			}
 		}
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		eta.Finished(time.Now())
+		done++
+		progress(done, pending, eta.Estimate(pending-done))
+	}
 
-		skipThisProduct := false
-
-		if prompt {
-			fmt.Println("Meta Title: " + metaTitle)
-			fmt.Println("Meta Description: " + metaDescription)
-			for {
-				fmt.Println("Do you approve these values? (y/n): ")
-				input, _ := reader.ReadString('\n')
-				input = strings.TrimSpace(input)
-
-				if input == "y" {
-					break
-				} else if input == "n" {
-					fmt.Println("Skipping this product...")
-					skipThisProduct = true
-					break
-				} else {
-					fmt.Println("Invalid input. Please enter 'y' or 'n'.")
-				}
+	for i, todo := range pages {
+		// OpenAI and WooCommerce tolerate very different request rates, so
+		// each stage gets its own worker count.
+		err := runPipeline(todo, conf.OpenAIConcurrency, conf.WooConcurrency, run.generate, run.write, finished)
+		if ctx.Err() != nil {
+			slog.Warn("Run interrupted, progress saved; run seo again to resume")
+			if err := tracker.save(trackerFilepath); err != nil {
+				slog.Warn("Could not save SEO tracker file", "err", err)
+			}
+			return result, ctx.Err()
+		}
+		if err != nil {
+			return result, err
+		}
+		if state != nil && byID {
+			if err := state.advance(pageEnds[i]); err != nil {
+				slog.Warn("Could not save run state", "err", err)
 			}
 		}
+	}
+	if state != nil {
+		if err := clearRunState(runStatePath); err != nil {
+			slog.Warn("Could not clear run state", "err", err)
+		}
+	}
+	if run.export != nil {
+		if err := run.export.write(opts.ExportOnly); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+const defaultProductStatus = "draft"
 
-		if skipThisProduct {
+var allowedProductStatuses = []string{"draft", "pending", "private", "publish"}
+
+// ValidateShippingFields checks that the weight and dimensions set in meta
+// are numbers, which WooCommerce would otherwise store as given.
+func ValidateShippingFields(meta ProductMeta) error {
+	for _, field := range []struct{ name, value string }{
+		{"weight", meta.Weight}, {"length", meta.Length}, {"width", meta.Width}, {"height", meta.Height},
+	} {
+		if field.value == "" {
 			continue
 		}
+		if v, err := strconv.ParseFloat(field.value, 64); err != nil || v < 0 {
+			return fmt.Errorf("product_meta.%s must be a non-negative number, got %q", field.name, field.value)
+		}
+	}
+	return nil
+}
 
-		updatePayload := map[string]interface{}{
-			"meta_data": []map[string]string{
-				{
-					"key":   "_yoast_wpseo_title",
-					"value": metaTitle,
-				},
-				{
-					"key":   "_yoast_wpseo_metadesc",
-					"value": metaDescription,
-				},
-			},
+// addShippingFields sets the weight and dimensions of meta that are given.
+func addShippingFields(body map[string]interface{}, meta ProductMeta) {
+	if meta.Weight != "" {
+		body["weight"] = meta.Weight
+	}
+	dimensions := map[string]string{}
+	for key, value := range map[string]string{"length": meta.Length, "width": meta.Width, "height": meta.Height} {
+		if value != "" {
+			dimensions[key] = value
+		}
+	}
+	if len(dimensions) > 0 {
+		body["dimensions"] = dimensions
+	}
+}
+
+func ValidateProductStatus(status string) error {
+	for _, allowed := range allowedProductStatuses {
+		if status == allowed {
+			return nil
 		}
+	}
+	return fmt.Errorf("unsupported product status %q (allowed: %s)", status, strings.Join(allowedProductStatuses, ", "))
+}
 
-		productEndpoint := fmt.Sprintf(
-			"https://%s/wp-json/wc/v3/products/%v?consumer_key=%s&consumer_secret=%s",
-			conf.Site, productID, conf.WooConsumerKey, conf.WooConsumerSecret,
-		)
+// maxSkuSuffix bounds how many "-N" suffixes createProduct tries for a
+// duplicated SKU before giving up.
+const maxSkuSuffix = 10
 
+// createProduct posts body to the products endpoint, tagged with
+// idempotencyKey as a header and as meta. A duplicated SKU is retried with
+// "-2", "-3", ... appended unless ProductMeta.DuplicateSku is "skip".
+func createProduct(client *resty.Client, conf *Config, body map[string]interface{}, idempotencyKey string) (WooProduct, error) {
+	var product WooProduct
+	baseSku, hasSku := body["sku"].(string)
+	body["meta_data"] = []map[string]interface{}{{"key": idempotencyMetaKey, "value": idempotencyKey}}
+
+	for suffix := 2; ; suffix++ {
 		resp, err := client.R().
 			SetHeader("Content-Type", "application/json").
-			SetBody(updatePayload).
-			Put(productEndpoint)
-
+			SetHeader("Idempotency-Key", idempotencyKey).
+			SetBody(body).
+			Post(wooEndpoint(conf, "products"))
 		if err != nil {
-			log.Printf("Failed to update SEO for product ID %v: %v", productID, err)
-			continue
+			return product, err
 		}
+
 		if resp.IsError() {
-			log.Printf("API error updating SEO for product ID %v: %s", productID, resp.String())
+			err := apiError(resp)
+			var apiErr *WooAPIError
+			duplicateSku := errors.As(err, &apiErr) && apiErr.Code == "product_invalid_sku"
+			if !hasSku || !duplicateSku || conf.ProductMeta.DuplicateSku == "skip" || suffix > maxSkuSuffix {
+				return product, err
+			}
+			body["sku"] = fmt.Sprintf("%s-%d", baseSku, suffix)
+			slog.Warn("SKU already exists, retrying with a suffix", "sku", baseSku, "retry", body["sku"])
 			continue
 		}
 
-		log.Printf("Successfully updated SEO for product ID %v", productID)
+		if err := decodeJSON(resp, &product); err != nil {
+			return product, fmt.Errorf("failed to parse created product: %w", err)
+		}
+		return product, nil
+	}
+}
 
-		tracker.UpdatedIDs[productID] = true
-		if err := tracker.save(trackerFilepath); err != nil {
-			log.Printf("Warning: could not save SEO tracker file: %v", err)
+type UploadOptions struct {
+	// DryRun logs the planned products without uploading or creating anything.
+	DryRun bool
+	// SuggestCategories adds the store categories OpenAI picks for each
+	// product to those set in product_meta.
+	SuggestCategories bool
+	// Quiet leaves out the per-product progress lines.
+	Quiet bool
+}
+
+// withSuggestedCategories returns categories plus those SuggestCategories
+// picks for a new product. Suggestion failures are logged and leave
+// categories as they are.
+func withSuggestedCategories(conf *Config, productName string, categories []map[string]interface{}, available []WooCategory) []map[string]interface{} {
+	input := SEOInput{
+		Name:             productName,
+		ShortDescription: conf.ProductMeta.ShortDescription,
+		Description:      conf.ProductMeta.Description,
+	}
+	ids, err := SuggestCategories(conf, input, available)
+	if err != nil {
+		slog.Warn("Could not suggest categories", "product", productName, "err", err)
+		return categories
+	}
+
+	merged := append([]map[string]interface{}{}, categories...)
+	for _, id := range ids {
+		present := false
+		for _, c := range categories {
+			if fmt.Sprint(c["id"]) == fmt.Sprint(id) {
+				present = true
+				break
+			}
+		}
+		if !present {
+			merged = append(merged, map[string]interface{}{"id": id})
 		}
 	}
+	slog.Info("Suggested categories", "product", productName, "categories", ids)
+	return merged
+}
 
-	return nil
+type CreatedProduct struct {
+	ID         int64
+	Name       string
+	File       string
+	MediaID    int64
+	MediaURL   string
+	Categories []map[string]interface{}
+	Sku        string
 }
-func UploadImageToWordPress(conf *Config, imageDirPath string) error {
-	client := resty.New()
 
-	files, err := os.ReadDir(imageDirPath)
+// ErrNoImages is returned by UploadImageToWordPress when the directory holds
+// no supported image files.
+var ErrNoImages = errors.New("no images found")
+
+var uploadImageExtensions = []string{".jpg", ".jpeg", ".png", ".gif"}
+
+// isUploadImage reports whether name has one of uploadImageExtensions, in
+// any case.
+func isUploadImage(name string) bool {
+	return slices.Contains(uploadImageExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+func UploadImageToWordPress(conf *Config, imageDirPath string, opts UploadOptions) ([]CreatedProduct, error) {
+	client := newClient(conf)
+
+	entries, err := os.ReadDir(imageDirPath)
 	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var files []os.DirEntry
+	for _, entry := range entries {
+		if !entry.IsDir() && isUploadImage(entry.Name()) {
+			files = append(files, entry)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoImages, imageDirPath)
 	}
 
-	for _, file := range files {
-		if !file.IsDir() && Contains([]string{".jpg", ".jpeg", ".png", ".gif"}, filepath.Ext(file.Name())) {
-			imagePath := filepath.Join(imageDirPath, file.Name())
-			fileName := file.Name()
-			productName := fileName[:len(fileName)-len(filepath.Ext(fileName))]
-
-			uploadEndpoint := fmt.Sprintf("https://%s/wp-json/wp/v2/media", conf.Site)
-
-			resp, err := client.R().
-				SetBasicAuth(conf.WpUser, conf.WpKey).
-				SetFile("file", imagePath).
-				SetFormData(map[string]string{
-					"title":   productName,
-					"caption": conf.ProductMeta.Description,
-				}).
-				Post(uploadEndpoint)
+	var formattedCategories []map[string]interface{}
+	for _, category := range conf.ProductMeta.Categories {
+		switch v := category.(type) {
+		case int:
+			formattedCategories = append(formattedCategories, map[string]interface{}{"id": v})
+		case string:
+			formattedCategories = append(formattedCategories, map[string]interface{}{"id": v})
+		}
+	}
+
+	// A dry run makes no requests, so it rounds the price to two decimals
+	// rather than to the store currency's minor unit.
+	regularPrice := conf.ProductMeta.RegularPrice
+	if regularPrice != "" {
+		currency := ""
+		if !opts.DryRun {
+			settings, err := GetStoreSettings(conf)
 			if err != nil {
-				return fmt.Errorf("failed to upload image: %w", err)
+				return nil, err
 			}
+			currency = settings.Currency
+		}
+		if regularPrice, err = normalizePrice(regularPrice, currency); err != nil {
+			return nil, fmt.Errorf("product_meta.regular_price: %w", err)
+		}
+	}
 
-			if resp.IsError() {
-				return fmt.Errorf("failed to upload image: %s, %s", resp.Status(), resp.String())
-			}
+	// Suggestions need the store's categories and a generator call, neither
+	// of which a dry run may make.
+	suggest := opts.SuggestCategories && !opts.DryRun
+	if opts.SuggestCategories && opts.DryRun {
+		fmt.Println("[dry-run] Skipping category suggestions")
+	}
+	var available []WooCategory
+	if suggest {
+		available, err = GetCategories(conf)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-			var result map[string]interface{}
-			if err := json.Unmarshal(resp.Body(), &result); err != nil {
-				return fmt.Errorf("failed to parse response: %w", err)
+	var created []CreatedProduct
+	nextSku := conf.ProductMeta.SkuStart
+	if nextSku == 0 {
+		nextSku = 1
+	}
+	for _, file := range files {
+		imagePath := filepath.Join(imageDirPath, file.Name())
+		fileName := file.Name()
+		productName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+		if name := conf.NameRules.displayName(productName); name != "" {
+			productName = name
+		}
+		if conf.ProductMeta.Name != "" {
+			productName = conf.ProductMeta.Name
+		}
+		planned := CreatedProduct{
+			Name:       productName,
+			File:       imagePath,
+			Categories: formattedCategories,
+		}
+		if conf.ProductMeta.SkuPrefix != "" {
+			planned.Sku = fmt.Sprintf("%s%03d", conf.ProductMeta.SkuPrefix, nextSku)
+			nextSku++
+		}
+		if suggest {
+			planned.Categories = withSuggestedCategories(conf, productName, formattedCategories, available)
+		}
+
+		if opts.DryRun {
+			fmt.Printf("[dry-run] Would upload %s and create product %q (SKU %q) in categories %v\n", imagePath, productName, planned.Sku, planned.Categories)
+			created = append(created, planned)
+			continue
+		}
+
+		// A previous run may have created this product and timed out before
+		// hearing back; finding it avoids both a duplicate and a re-upload.
+		// The key uses the normalized name so spacing or case changes to
+		// product_meta.name between runs still find it.
+		key := idempotencyKey("create-product", conf.NameRules.normalize(productName), fileName)
+		if existing, ok, err := findByIdempotencyKey(client, conf, productName, key); err != nil {
+			return created, err
+		} else if ok {
+			slog.Info("Product already exists, skipping", "product", productName, "file", fileName, "product_id", existing.ID)
+			planned.ID = existing.ID
+			planned.Sku = existing.Sku
+			created = append(created, planned)
+			continue
+		}
+
+		// With duplicate_sku: skip a taken SKU is caught before the image
+		// is uploaded, so no attachment is left without a product.
+		if conf.ProductMeta.DuplicateSku == "skip" && planned.Sku != "" {
+			exists, err := skuExists(client, conf, planned.Sku)
+			if err != nil {
+				return created, err
 			}
-			imageURL := result["source_url"].(string)
-			imageID := result["id"].(float64)
-
-			uploadedImages := []map[string]interface{}{
-				{
-					"id":  imageID,
-					"src": imageURL,
-				},
+			if exists {
+				slog.Warn("Skipping product: SKU already exists", "product", productName, "sku", planned.Sku)
+				continue
 			}
+		}
+
+		mediaFields, err := RenderMediaFields(conf.Media, MediaTemplateData{
+			ProductName: productName,
+			FileName:    fileName,
+			Sku:         planned.Sku,
+			Config:      conf,
+		})
+		if err != nil {
+			return created, err
+		}
+
+		uploadPath := imagePath
+		if conf.ConvertToWebP && canConvertToWebP(imagePath) {
+			webpPath, err := convertToWebP(imagePath, conf.WebPQuality)
+			if err != nil {
+				slog.Warn("WebP conversion failed, uploading original", "err", err)
+			} else {
+				uploadPath = webpPath
+			}
+		}
 
-			if len(uploadedImages) > 0 {
-				productEndpoint := fmt.Sprintf(
-					"https://%s/wp-json/wc/v3/products?consumer_key=%s&consumer_secret=%s",
-					conf.Site, conf.WooConsumerKey, conf.WooConsumerSecret,
-				)
-				fmt.Println("Creating product: " + productName)
-
-				var formattedCategories []map[string]interface{}
-				for _, category := range conf.ProductMeta.Categories {
-					switch v := category.(type) {
-					case int:
-						formattedCategories = append(formattedCategories, map[string]interface{}{"id": v})
-					case string:
-						formattedCategories = append(formattedCategories, map[string]interface{}{"id": v})
-					}
-				}
-
-				body := map[string]interface{}{
-					"name":              &productName,
-					"type":              conf.ProductMeta.Type,
-					"regular_price":     conf.ProductMeta.RegularPrice,
-					"description":       conf.ProductMeta.Description,
-					"short_description": conf.ProductMeta.ShortDescription,
-					"categories":        formattedCategories,
-					"images":            &uploadedImages,
-				}
-
-				productResp, err := client.R().
-					SetHeader("Content-Type", "application/json").
-					SetBody(body).
-					Post(productEndpoint)
-				if err != nil {
-					return fmt.Errorf("failed to create product: %w", err)
-				}
-
-				if productResp.IsError() {
-					return fmt.Errorf("failed to create product: %s, %s", productResp.Status(), productResp.String())
-				}
-
-				fmt.Println("Product created")
+		uploadEndpoint := wpEndpoint(conf, "wp/v2/media")
+
+		resp, err := client.R().
+			SetBasicAuth(conf.WpUser, conf.WpKey).
+			SetFile("file", uploadPath).
+			SetFormData(mediaFields).
+			Post(uploadEndpoint)
+		if uploadPath != imagePath {
+			os.RemoveAll(filepath.Dir(uploadPath))
+		}
+		if err != nil {
+			return created, fmt.Errorf("failed to upload image: %w", err)
+		}
+
+		if resp.IsError() {
+			return created, fmt.Errorf("failed to upload image: %w", apiError(resp))
+		}
+
+		var result map[string]interface{}
+		if err := decodeJSON(resp, &result); err != nil {
+			return created, fmt.Errorf("failed to parse response: %w", err)
+		}
+		imageURL, _ := result["source_url"].(string)
+		imageID, _ := result["id"].(float64)
+		planned.MediaID = int64(imageID)
+		planned.MediaURL = imageURL
+
+		uploadedImages := []map[string]interface{}{
+			{
+				"id":  imageID,
+				"src": imageURL,
+			},
+		}
+
+		if !opts.Quiet {
+			fmt.Println("Creating product: " + productName)
+		}
+
+		body := map[string]interface{}{
+			"name":              productName,
+			"type":              conf.ProductMeta.Type,
+			"status":            conf.ProductMeta.Status,
+			"regular_price":     regularPrice,
+			"description":       conf.ProductMeta.Description,
+			"short_description": conf.ProductMeta.ShortDescription,
+			"categories":        planned.Categories,
+			"images":            uploadedImages,
+		}
+		if planned.Sku != "" {
+			body["sku"] = planned.Sku
+		}
+		addShippingFields(body, conf.ProductMeta)
+
+		product, err := createProduct(client, conf, body, key)
+		var apiErr *WooAPIError
+		if errors.As(err, &apiErr) && apiErr.Code == "product_invalid_sku" && conf.ProductMeta.DuplicateSku == "skip" {
+			// The SKU was taken after the check above.
+			slog.Warn("Skipping product: SKU already exists", "product", productName, "sku", planned.Sku)
+			if err := deleteMedia(client, conf, planned.MediaID); err != nil {
+				slog.Warn("Could not remove the uploaded image", "product", productName, "err", err)
 			}
+			continue
+		}
+		if err != nil {
+			return created, fmt.Errorf("failed to create product: %w", err)
 		}
+		planned.ID = product.ID
+		planned.Sku = product.Sku
+		created = append(created, planned)
 
+		if !opts.Quiet {
+			fmt.Println("Product created")
+		}
 	}
 
-	return nil
+	return created, nil
 }
@@ -0,0 +1,1283 @@
+package wooh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestIsUploadImage(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"oak.jpg", true},
+		{"oak.jpeg", true},
+		{"oak.png", true},
+		{"oak.gif", true},
+		{"OAK.JPG", true},
+		{"Oak.Png", true},
+		{"oak.webp", false},
+		{"oak.txt", false},
+		{"oak", false},
+		{"jpg", false},
+		{".jpgx", false},
+	}
+	for _, tt := range tests {
+		if got := isUploadImage(tt.name); got != tt.want {
+			t.Errorf("isUploadImage(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUploadDryRunMakesNoRequests(t *testing.T) {
+	conf, requests := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	})
+	conf.ProductMeta.RegularPrice = "12.5"
+	conf.ProductMeta.SkuPrefix = "OAK-"
+
+	dir := t.TempDir()
+	for _, name := range []string{"Oak_Plank_OAK-001.png", "walnut.JPG", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	planned, err := UploadImageToWordPress(conf, dir, UploadOptions{DryRun: true, SuggestCategories: true, Quiet: true})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("dry run sent %d requests, want 0", n)
+	}
+	if len(planned) != 2 {
+		t.Fatalf("planned %d products, want 2", len(planned))
+	}
+	if planned[0].Sku != "OAK-001" || planned[1].Sku != "OAK-002" {
+		t.Errorf("planned SKUs %q, %q", planned[0].Sku, planned[1].Sku)
+	}
+	if planned[0].Name != "Oak Plank" || planned[1].Name != "walnut" {
+		t.Errorf("planned names %q, %q", planned[0].Name, planned[1].Name)
+	}
+}
+
+// skuStore fakes the endpoints an upload uses. Products with a SKU in taken
+// already exist; creating one with a SKU in conflicts fails as a duplicate.
+type skuStore struct {
+	mu        sync.Mutex
+	taken     map[string]bool
+	conflicts map[string]bool
+	media     int // uploads
+	deleted   []string
+	created   []string                 // SKUs of created products
+	bodies    []map[string]interface{} // create requests, in order
+}
+
+func (s *skuStore) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/products"):
+		if sku := r.URL.Query().Get("sku"); sku != "" && s.taken[sku] {
+			fmt.Fprint(w, `[{"id": 1}]`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	case r.Method == http.MethodGet:
+		fmt.Fprint(w, `[]`)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/wp/v2/media"):
+		s.media++
+		fmt.Fprintf(w, `{"id": %d, "source_url": "http://img/%d.jpg"}`, 100+s.media, s.media)
+	case r.Method == http.MethodDelete:
+		s.deleted = append(s.deleted, r.URL.Path)
+		fmt.Fprint(w, `{"deleted": true}`)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/products"):
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.bodies = append(s.bodies, body)
+		sku, _ := body["sku"].(string)
+		if s.conflicts[sku] || s.taken[sku] {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code": "product_invalid_sku", "message": "Invalid or duplicated SKU."}`)
+			return
+		}
+		s.created = append(s.created, sku)
+		fmt.Fprintf(w, `{"id": %d, "sku": %q}`, 200+len(s.created), sku)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestUploadDuplicateSku(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		taken       []string
+		conflicts   []string
+		wantCreated []string
+		wantMedia   int
+		wantDeleted int
+	}{
+		{name: "unique", mode: "skip", wantCreated: []string{"OAK-001", "OAK-002"}, wantMedia: 2},
+		{name: "skip taken before upload", mode: "skip", taken: []string{"OAK-001"}, wantCreated: []string{"OAK-002"}, wantMedia: 1},
+		{name: "skip race removes image", mode: "skip", conflicts: []string{"OAK-002"}, wantCreated: []string{"OAK-001"}, wantMedia: 2, wantDeleted: 1},
+		{name: "suffix", mode: "suffix", taken: []string{"OAK-001"}, wantCreated: []string{"OAK-001-2", "OAK-002"}, wantMedia: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &skuStore{taken: map[string]bool{}, conflicts: map[string]bool{}}
+			for _, sku := range tt.taken {
+				store.taken[sku] = true
+			}
+			for _, sku := range tt.conflicts {
+				store.conflicts[sku] = true
+			}
+			conf, _ := newTestStore(t, store.handle)
+			conf.ProductMeta.SkuPrefix = "OAK-"
+			conf.ProductMeta.DuplicateSku = tt.mode
+			conf.ConvertToWebP = false
+
+			dir := t.TempDir()
+			for _, name := range []string{"oak.jpg", "walnut.jpg"} {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := UploadImageToWordPress(conf, dir, UploadOptions{Quiet: true}); err != nil {
+				t.Fatalf("upload failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(store.created, tt.wantCreated) {
+				t.Errorf("created SKUs %v, want %v", store.created, tt.wantCreated)
+			}
+			if store.media != tt.wantMedia {
+				t.Errorf("uploaded %d images, want %d", store.media, tt.wantMedia)
+			}
+			if len(store.deleted) != tt.wantDeleted {
+				t.Errorf("deleted %v, want %d deletions", store.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestStructuredDataWritten(t *testing.T) {
+	tests := []struct {
+		name        string
+		sd          StructuredData
+		product     map[string]interface{}
+		wantGTIN    string // empty for none
+		wantNoWrite bool   // structured data off: no Google meta at all
+	}{
+		{
+			name:     "gtin from a top-level field",
+			sd:       StructuredData{Enabled: true, GoogleCategory: "Hardware", GTINPath: "global_unique_id"},
+			product:  map[string]interface{}{"sku": "OAK-1", "global_unique_id": "4006381333931"},
+			wantGTIN: "4006381333931",
+		},
+		{
+			name:     "gtin from meta as a number",
+			sd:       StructuredData{Enabled: true, GoogleCategory: "Hardware", GTINPath: "meta_data._ean", GTINFromSku: true},
+			product:  map[string]interface{}{"sku": "OAK-1", "meta_data": []interface{}{map[string]interface{}{"key": "_ean", "value": 5012345678900}}},
+			wantGTIN: "5012345678900",
+		},
+		{
+			name:    "path set but missing: no sku fallback",
+			sd:      StructuredData{Enabled: true, GoogleCategory: "Hardware", GTINPath: "global_unique_id", GTINFromSku: true},
+			product: map[string]interface{}{"sku": "OAK-1"},
+		},
+		{
+			name:     "sku without a path",
+			sd:       StructuredData{Enabled: true, GoogleCategory: "Hardware", GTINFromSku: true},
+			product:  map[string]interface{}{"sku": "OAK-1", "global_unique_id": "4006381333931"},
+			wantGTIN: "OAK-1",
+		},
+		{
+			name:        "disabled",
+			sd:          StructuredData{GoogleCategory: "Hardware", GTINPath: "global_unique_id"},
+			product:     map[string]interface{}{"sku": "OAK-1", "global_unique_id": "4006381333931"},
+			wantNoWrite: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := testProduct(7, "Oak Board")
+			for k, v := range tt.product {
+				product[k] = v
+			}
+			store := newFakeStore(product)
+			conf, _ := newTestStore(t, store.handle)
+			conf.OpenAIStub = true
+			conf.StructuredData = tt.sd
+			applyDefaults(conf)
+
+			if _, err := ResyncProduct(conf, 7); err != nil {
+				t.Fatal(err)
+			}
+			meta := store.product(7).MetaData
+			if tt.wantNoWrite {
+				if meta.Get(defaultGoogleCategoryKey) != "" || meta.Get(defaultGTINKey) != "" {
+					t.Errorf("structured data written while disabled: %v", meta)
+				}
+				return
+			}
+			if got := meta.Get(defaultGoogleCategoryKey); got != "Hardware" {
+				t.Errorf("google category = %q, want Hardware", got)
+			}
+			if got := meta.Get(defaultGTINKey); got != tt.wantGTIN {
+				t.Errorf("GTIN = %q, want %q", got, tt.wantGTIN)
+			}
+		})
+	}
+}
+
+func TestProductFieldsIncludesGTINPath(t *testing.T) {
+	conf := &Config{StructuredData: StructuredData{GTINPath: "global_unique_id"}, CustomFields: map[string]string{"brand": "meta_data._brand"}}
+	applyDefaults(conf)
+	fields := strings.Split(productFields(conf), ",")
+	for _, want := range []string{"id", "meta_data", "global_unique_id"} {
+		if !slices.Contains(fields, want) {
+			t.Errorf("_fields %v lacks %s", fields, want)
+		}
+	}
+}
+
+func TestCleanHTMLToMarkdown(t *testing.T) {
+	html := `<h4>Specs</h4>` +
+		`<table><tr><th>Size</th><th>Finish</th></tr><tr><td>120cm</td><td>Oiled</td></tr></table>` +
+		`<ul><li>Solid oak</li><li>FSC certified</li></ul>` +
+		`<p><img src="a.jpg" alt="Oak plank"> Ready to fit.</p>`
+	tests := []struct {
+		name string
+		opts MarkdownOptions
+		want string
+	}{
+		{
+			name: "default",
+			opts: DefaultMarkdownOptions(),
+			want: "## Specs\nSizeFinish120cmOiled\n- Solid oak\n- FSC certified\n Ready to fit.",
+		},
+		{
+			name: "no remap",
+			opts: MarkdownOptions{},
+			want: "#### Specs\nSizeFinish120cmOiled\n- Solid oak\n- FSC certified\n Ready to fit.",
+		},
+		{
+			name: "preserve",
+			opts: MarkdownOptions{KeepTables: true, PreserveImages: true, HeadingRemap: map[int]int{4: 3}},
+			want: "### Specs\n| Size  | Finish |\n|-------|--------|\n| 120cm | Oiled  |\n- Solid oak\n- FSC certified\nOak plank Ready to fit.",
+		},
+		{
+			name: "out of range remap ignored",
+			opts: MarkdownOptions{HeadingRemap: map[int]int{4: 9}},
+			want: "#### Specs\nSizeFinish120cmOiled\n- Solid oak\n- FSC certified\n Ready to fit.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cleanHTMLToMarkdown(html, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFocusKeyphraseWritten(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("focus_keyphrase %v", enabled), func(t *testing.T) {
+			store := newFakeStore(testProduct(1, "Oak Board"))
+			conf, _ := newTestStore(t, store.handle)
+			conf.FocusKeyphrase = enabled
+			gen := newFakeGenerator(t, func(req chatRequest) openai.ChatCompletionChoice {
+				meta := map[string]string{"meta_title": "Oak Board", "meta_description": "Solid oak board."}
+				// Strict parsing rejects a key the schema did not ask for.
+				if strings.Contains(string(req.ResponseFormat), "focus_keyphrase") {
+					meta["focus_keyphrase"] = "oak board"
+				}
+				return textChoice(metaJSON(meta))
+			})
+			gen.use(conf)
+
+			if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+				t.Fatal(err)
+			}
+			meta := store.product(1).MetaData
+			if meta.YoastTitle() != "Oak Board" {
+				t.Fatalf("meta not written: %v", meta)
+			}
+			want := ""
+			if enabled {
+				want = "oak board"
+			}
+			if got := meta.YoastFocusKW(); got != want {
+				t.Errorf("%s = %q, want %q", yoastFocusKWKey, got, want)
+			}
+			if prompt := gen.sent()[0].Messages[0].Content; strings.Contains(prompt, OpenAIFocusKeyphrasePrompt()) != enabled {
+				t.Errorf("focus keyphrase prompt included = %v, want %v", !enabled, enabled)
+			}
+		})
+	}
+}
+
+func TestSystemPromptFor(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tiles.txt")
+	if err := os.WriteFile(file, []byte("You write copy for tiles."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf := &Config{CategoryPrompts: map[int]string{5: "You write copy for oak flooring.", 6: file, 7: "  "}}
+	tests := []struct {
+		name       string
+		categories []WooCategory
+		want       string
+	}{
+		{"no categories", nil, OpenAIGenSystemPrompt()},
+		{"inline prompt", []WooCategory{{ID: 5}}, "You write copy for oak flooring."},
+		{"prompt file", []WooCategory{{ID: 6}}, "You write copy for tiles."},
+		{"blank entry", []WooCategory{{ID: 7}}, OpenAIGenSystemPrompt()},
+		{"no entry", []WooCategory{{ID: 9}}, OpenAIGenSystemPrompt()},
+		{"primary category only", []WooCategory{{ID: 9}, {ID: 5}}, OpenAIGenSystemPrompt()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SystemPromptFor(conf, tt.categories)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("SystemPromptFor = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateSEOUsesCategoryPrompts(t *testing.T) {
+	oak := testProduct(1, "Oak Board")
+	oak["categories"] = []interface{}{map[string]interface{}{"id": 5, "name": "Oak"}}
+	tile := testProduct(2, "Slate Tile")
+	tile["categories"] = []interface{}{map[string]interface{}{"id": 9, "name": "Tiles"}}
+	store := newFakeStore(oak, tile)
+	conf, _ := newTestStore(t, store.handle)
+	conf.CategoryPrompts = map[int]string{5: "You write copy for oak flooring."}
+	gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+		return textChoice(metaJSON(map[string]string{"meta_title": "Board", "meta_description": "A board."}))
+	})
+	gen.use(conf)
+
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	prompts := map[string]string{}
+	for _, req := range gen.sent() {
+		for _, name := range []string{"Oak Board", "Slate Tile"} {
+			if strings.Contains(req.Messages[1].Content, name) {
+				prompts[name] = req.Messages[0].Content
+			}
+		}
+	}
+	if !strings.HasPrefix(prompts["Oak Board"], "You write copy for oak flooring.") {
+		t.Errorf("category 5 product prompt = %q", prompts["Oak Board"])
+	}
+	if !strings.HasPrefix(prompts["Slate Tile"], OpenAIGenSystemPrompt()) {
+		t.Errorf("category 9 product did not use the default prompt: %q", prompts["Slate Tile"])
+	}
+}
+
+func TestIsTruncatedJSON(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{`{"meta_title": "Oak"}`, false},
+		{`{"meta_title": "Oak", "tags": ["a", "b"]}`, false},
+		{`{"meta_title": "Oak \"board\" {"}`, false},
+		{`{"meta_title": "Oak`, true},
+		{`{"meta_title": "Oak"`, true},
+		{`{"tags": ["a", "b"`, true},
+		{`{"meta_title": "Oak \"`, true},
+	}
+	for _, tt := range tests {
+		if got := isTruncatedJSON(tt.s); got != tt.want {
+			t.Errorf("isTruncatedJSON(%s) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateMetaRetriesTruncatedOutput(t *testing.T) {
+	complete := metaJSON(map[string]string{"meta_title": "Oak Board", "meta_description": "Solid oak board."})
+	tests := []struct {
+		name          string
+		truncated     int // replies cut off before a complete one
+		wantErr       bool
+		wantMaxTokens []int
+	}{
+		{"complete", 0, false, []int{500}},
+		{"truncated once", 1, false, []int{500, 1000}},
+		{"truncated twice", 2, false, []int{500, 1000, 2000}},
+		{"always truncated", 3, true, []int{500, 1000, 2000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replies := 0
+			gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+				replies++
+				if replies <= tt.truncated {
+					// Cut off mid-string, but not reported as a length stop.
+					return textChoice(complete[:20])
+				}
+				return textChoice(complete)
+			})
+			conf := &Config{OpenAIMaxTokens: 500}
+			applyDefaults(conf)
+			gen.use(conf)
+
+			meta, err := generateMeta(context.Background(), conf, "system", "user")
+			if tt.wantErr {
+				if !errors.Is(err, ErrTruncatedOutput) {
+					t.Errorf("err = %v, want ErrTruncatedOutput", err)
+				}
+			} else if err != nil || meta.MetaTitle != "Oak Board" {
+				t.Errorf("generateMeta = %+v, %v", meta, err)
+			}
+			var maxTokens []int
+			for _, req := range gen.sent() {
+				maxTokens = append(maxTokens, req.MaxTokens)
+			}
+			if !slices.Equal(maxTokens, tt.wantMaxTokens) {
+				t.Errorf("max_tokens per attempt = %v, want %v", maxTokens, tt.wantMaxTokens)
+			}
+		})
+	}
+}
+
+func TestSearchProducts(t *testing.T) {
+	// 150 oak and 20 walnut products, searched by name.
+	var searches []string
+	conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		term := r.URL.Query().Get("search")
+		searches = append(searches, term)
+		var matches []map[string]interface{}
+		for id := 1; id <= 170; id++ {
+			name := "Oak Board"
+			if id > 150 {
+				name = "Walnut Board"
+			}
+			if strings.Contains(strings.ToLower(name), strings.ToLower(term)) {
+				matches = append(matches, map[string]interface{}{"id": id, "name": name})
+			}
+		}
+		page := 1
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		start, end := min((page-1)*100, len(matches)), min(page*100, len(matches))
+		json.NewEncoder(w).Encode(append([]map[string]interface{}{}, matches[start:end]...))
+	})
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"oak", 150},
+		{"walnut", 20},
+		{"maple", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			searches = nil
+			products, err := SearchProducts(conf, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if products == nil || len(products) != tt.want {
+				t.Errorf("found %d products (nil %v), want %d", len(products), products == nil, tt.want)
+			}
+			for _, term := range searches {
+				if term != tt.query {
+					t.Errorf("searched for %q, want %q", term, tt.query)
+				}
+			}
+		})
+	}
+
+	if _, err := SearchProducts(conf, "  "); err == nil {
+		t.Error("empty search was sent")
+	}
+}
+
+func TestUploadDryRunPlan(t *testing.T) {
+	conf, requests := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	})
+	conf.ProductMeta.Categories = []interface{}{12, "15"}
+	conf.ProductMeta.Name = "Oak Flooring"
+	conf.ConvertToWebP = true
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.jpg", "b.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("not an image"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	planned, err := UploadImageToWordPress(conf, dir, UploadOptions{DryRun: true, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("dry run sent %d requests, want 0", n)
+	}
+	wantCategories := []map[string]interface{}{{"id": 12}, {"id": "15"}}
+	for i, p := range planned {
+		if p.ID != 0 || p.MediaID != 0 || p.MediaURL != "" {
+			t.Errorf("planned product %d has store IDs: %+v", i, p)
+		}
+		if p.Name != "Oak Flooring" || !reflect.DeepEqual(p.Categories, wantCategories) {
+			t.Errorf("planned product %d = %+v", i, p)
+		}
+	}
+	if len(planned) != 2 || filepath.Base(planned[0].File) != "a.jpg" || filepath.Base(planned[1].File) != "b.png" {
+		t.Errorf("planned files %+v", planned)
+	}
+	// WebP conversion happens at upload, so the source files are untouched.
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("dry run left %d files in the directory, want 2", len(entries))
+	}
+}
+
+func TestUploadProductStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"", "draft"},
+		{"pending", "pending"},
+		{"publish", "publish"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			store := &skuStore{}
+			conf, _ := newTestStore(t, store.handle)
+			conf.ProductMeta.Status = tt.status
+			applyDefaults(conf)
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "oak.jpg"), []byte("x"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := UploadImageToWordPress(conf, dir, UploadOptions{Quiet: true}); err != nil {
+				t.Fatal(err)
+			}
+			if len(store.bodies) != 1 || store.bodies[0]["status"] != tt.want {
+				t.Errorf("created products %v, want status %q", store.bodies, tt.want)
+			}
+		})
+	}
+
+	for _, status := range []string{"draft", "pending", "private", "publish"} {
+		if err := ValidateProductStatus(status); err != nil {
+			t.Errorf("ValidateProductStatus(%q) = %v", status, err)
+		}
+	}
+	if err := ValidateProductStatus("live"); err == nil {
+		t.Error("ValidateProductStatus accepted \"live\"")
+	}
+}
+
+func TestUpdateSEOPolicySkip(t *testing.T) {
+	tests := []struct {
+		name   string
+		choice openai.ChatCompletionChoice
+	}{
+		{"content filter", openai.ChatCompletionChoice{FinishReason: openai.FinishReasonContentFilter}},
+		{"refusal", openai.ChatCompletionChoice{
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Refusal: "I can't help with that."},
+			FinishReason: openai.FinishReasonStop,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Forbidden Board"))
+			conf, _ := newTestStore(t, store.handle)
+			gen := newFakeGenerator(t, func(req chatRequest) openai.ChatCompletionChoice {
+				for _, msg := range req.Messages {
+					if strings.Contains(msg.Content, "Forbidden") {
+						return tt.choice
+					}
+				}
+				return textChoice(metaJSON(map[string]string{"meta_title": "Oak Board", "meta_description": "Solid oak board."}))
+			})
+			gen.use(conf)
+
+			result, err := UpdateSEO(conf, SEOOptions{Quiet: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(result.PolicySkipped, []int{2}) || !slices.Equal(result.Updated, []int{1}) {
+				t.Errorf("policy-skipped %v, updated %v; want [2], [1]", result.PolicySkipped, result.Updated)
+			}
+			if len(result.Failed) > 0 {
+				t.Errorf("failed %v, want none", result.Failed)
+			}
+			refused := 0
+			for _, req := range gen.sent() {
+				for _, msg := range req.Messages {
+					if strings.Contains(msg.Content, "Forbidden") {
+						refused++
+						break
+					}
+				}
+			}
+			if refused != 1 {
+				t.Errorf("refused product was sent %d times, want 1 (no retries)", refused)
+			}
+		})
+	}
+}
+
+func TestUpdateSEOKeyphraseInTitle(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		titles    []string // meta titles replied, in order
+		wantTitle string
+		wantCalls int
+		wantFail  bool
+	}{
+		{"first title has keyphrase", true, []string{"Walnut Oak Board"}, "Walnut Oak Board", 1, false},
+		{"retry adds keyphrase", true, []string{"Oak Board", "Walnut Oak Board"}, "Walnut Oak Board", 2, false},
+		{"keyphrase never added", true, []string{"Oak Board", "Oak Board"}, "", 2, true},
+		{"check disabled", false, []string{"Oak Board"}, "Oak Board", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := testProduct(1, "Oak Board")
+			product["meta_data"] = []interface{}{map[string]interface{}{"id": 1, "key": yoastFocusKWKey, "value": "WALNUT"}}
+			store := newFakeStore(product)
+			conf, _ := newTestStore(t, store.handle)
+			conf.KeyphraseInTitle = tt.enabled
+
+			var mu sync.Mutex
+			replies := 0
+			gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+				mu.Lock()
+				defer mu.Unlock()
+				title := tt.titles[min(replies, len(tt.titles)-1)]
+				replies++
+				return textChoice(metaJSON(map[string]string{"meta_title": title, "meta_description": "Solid oak board."}))
+			})
+			gen.use(conf)
+
+			result, err := UpdateSEO(conf, SEOOptions{Quiet: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var prompts []string
+			for _, req := range gen.sent() {
+				if isMetaRequest(req) {
+					prompts = append(prompts, req.Messages[len(req.Messages)-1].Content)
+				}
+			}
+			if len(prompts) != tt.wantCalls {
+				t.Fatalf("sent %d meta requests, want %d", len(prompts), tt.wantCalls)
+			}
+			if tt.wantCalls > 1 && !strings.Contains(prompts[1], `did not contain the focus keyphrase "WALNUT"`) {
+				t.Errorf("retry prompt lacks keyphrase feedback: %s", prompts[1])
+			}
+			if tt.wantFail != slices.Contains(result.Failed, 1) {
+				t.Errorf("failed = %v, want failure %v", result.Failed, tt.wantFail)
+			}
+			got := strings.TrimSuffix(store.product(1).MetaData.YoastTitle(), conf.titleSuffix())
+			if got != tt.wantTitle {
+				t.Errorf("stored title = %q, want %q", got, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestUpdateSEOEmptyDescriptionPolicy(t *testing.T) {
+	tests := []struct {
+		policy      string
+		wantUpdated []int
+		wantSkipped []int
+		wantFlagged []int
+	}{
+		{EmptyDescriptionSkip, []int{1}, []int{2}, nil},
+		{EmptyDescriptionFlag, []int{1}, nil, []int{2}},
+		{EmptyDescriptionNameOnly, []int{1, 2}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			bare := testProduct(2, "Bare Board")
+			bare["description"], bare["short_description"] = "", " "
+			store := newFakeStore(testProduct(1, "Oak Board"), bare)
+			conf, _ := newTestStore(t, store.handle)
+			conf.EmptyDescriptionPolicy = tt.policy
+			gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+				return textChoice(metaJSON(map[string]string{"meta_title": "Board", "meta_description": "Solid board."}))
+			})
+			gen.use(conf)
+
+			result, err := UpdateSEO(conf, SEOOptions{Quiet: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(result.Updated)
+			if !slices.Equal(result.Updated, tt.wantUpdated) || !slices.Equal(result.Skipped, tt.wantSkipped) || !slices.Equal(result.Flagged, tt.wantFlagged) {
+				t.Errorf("updated %v, skipped %v, flagged %v; want %v, %v, %v",
+					result.Updated, result.Skipped, result.Flagged, tt.wantUpdated, tt.wantSkipped, tt.wantFlagged)
+			}
+
+			// Only the product without a description gets the name-only prompt.
+			for _, req := range gen.sent() {
+				bareRequest := strings.Contains(req.Messages[len(req.Messages)-1].Content, "Bare Board")
+				namePrompt := strings.Contains(req.Messages[0].Content, OpenAINameOnlyPrompt())
+				if bareRequest != namePrompt {
+					t.Errorf("request for bare product %v used the name-only prompt %v", bareRequest, namePrompt)
+				}
+				if bareRequest && tt.policy != EmptyDescriptionNameOnly {
+					t.Errorf("policy %s still generated meta for the bare product", tt.policy)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateSEOForceReprocessesTracked(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Ash Board"))
+	conf, _ := newTestStore(t, store.handle)
+	conf.OpenAIStub = true
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	stubTitle := store.product(1).MetaData.YoastTitle()
+
+	gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+		return textChoice(metaJSON(map[string]string{"meta_title": "Regenerated Board", "meta_description": "Solid board."}))
+	})
+	gen.use(conf)
+
+	tests := []struct {
+		name        string
+		force       bool
+		wantUpdated []int
+		wantSkipped []int
+		wantTitle   string
+	}{
+		{"tracked products are skipped", false, nil, []int{1, 2}, stubTitle},
+		{"force reprocesses them", true, []int{1, 2}, nil, "Regenerated Board"},
+	}
+	for _, tt := range tests {
+		result, err := UpdateSEO(conf, SEOOptions{Quiet: true, Force: tt.force})
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(result.Updated)
+		slices.Sort(result.Skipped)
+		if !slices.Equal(result.Updated, tt.wantUpdated) || !slices.Equal(result.Skipped, tt.wantSkipped) {
+			t.Errorf("%s: updated %v, skipped %v; want %v, %v", tt.name, result.Updated, result.Skipped, tt.wantUpdated, tt.wantSkipped)
+		}
+		if got := store.product(1).MetaData.YoastTitle(); got != tt.wantTitle {
+			t.Errorf("%s: title = %q, want %q", tt.name, got, tt.wantTitle)
+		}
+	}
+
+	tracker, err := TrackerLoad(mustCachePath(t, conf, conf.TrackerFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tracker.UpdatedIDs[1] || !tracker.UpdatedIDs[2] || len(tracker.UpdatedIDs) != 2 {
+		t.Errorf("tracker = %v, want both products kept", tracker.UpdatedIDs)
+	}
+}
+
+func TestUpdateSEOCategoryModels(t *testing.T) {
+	inCategory := func(id int, name string, categories ...int) map[string]interface{} {
+		p := testProduct(id, name)
+		var cs []interface{}
+		for _, c := range categories {
+			cs = append(cs, map[string]interface{}{"id": c, "name": fmt.Sprintf("Category %d", c)})
+		}
+		p["categories"] = cs
+		return p
+	}
+	store := newFakeStore(
+		inCategory(1, "Flagship Board", 10),
+		inCategory(2, "Long Tail Board", 20, 10), // only the primary category counts
+		inCategory(3, "Loose Board"),
+		inCategory(4, "Blank Model Board", 30),
+	)
+	conf, _ := newTestStore(t, store.handle)
+	conf.GeneratorModel = "default-model"
+	conf.CategoryModels = map[int]string{10: "flagship-model", 30: " "}
+	gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+		return textChoice(metaJSON(map[string]string{"meta_title": "Board", "meta_description": "Solid board."}))
+	})
+	gen.use(conf)
+
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Flagship Board":    "flagship-model",
+		"Long Tail Board":   "default-model",
+		"Loose Board":       "default-model",
+		"Blank Model Board": "default-model",
+	}
+	got := map[string]string{}
+	for _, req := range gen.sent() {
+		for name := range want {
+			if strings.Contains(req.Messages[len(req.Messages)-1].Content, name) {
+				got[name] = req.Model
+			}
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("models = %v, want %v", got, want)
+	}
+}
+
+func TestUploadProductName(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string // product_meta.name in the config file
+		want   string
+	}{
+		{"unset uses the file name", "", "oak-board"},
+		{"set overrides it", "name: Showroom Sample\n", "Showroom Sample"},
+		{"null is unset", "name: null\n", "oak-board"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &skuStore{}
+			srvConf, _ := newTestStore(t, store.handle)
+			path := filepath.Join(t.TempDir(), "wooh.yaml")
+			config := fmt.Sprintf("site: %s\nconsumer_key: ck\nconsumer_secret: cs\ncache_dir: %s\nproduct_meta:\n  regular_price: \"10.00\"\n", srvConf.Site, srvConf.CacheDir)
+			if tt.config != "" {
+				config += "  " + tt.config
+			}
+			os.WriteFile(path, []byte(config), 0644)
+			conf, err := ReadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, "oak-board.jpg"), []byte("x"), 0644)
+			if _, err := UploadImageToWordPress(conf, dir, UploadOptions{Quiet: true}); err != nil {
+				t.Fatal(err)
+			}
+			if len(store.bodies) != 1 {
+				t.Fatalf("created %d products, want 1", len(store.bodies))
+			}
+			// The name is always sent as a string, never null.
+			if got, ok := store.bodies[0]["name"].(string); !ok || got != tt.want {
+				t.Errorf("name = %#v, want %q", store.bodies[0]["name"], tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateSEOSummaryCounts(t *testing.T) {
+	var products []map[string]interface{}
+	for id := 1; id <= 30; id++ {
+		products = append(products, testProduct(id, fmt.Sprintf("Board %d", id)))
+	}
+	store := newFakeStore(products...)
+	conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		// Writes of products 10 and 20 are rejected.
+		if r.Method == http.MethodPut && (strings.HasSuffix(r.URL.Path, "/products/10") || strings.HasSuffix(r.URL.Path, "/products/20")) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":"rest_invalid_param","message":"Invalid parameter(s): meta_data"}`))
+			return
+		}
+		store.handle(w, r)
+	})
+	conf.OpenAIStub = true
+	conf.OpenAIConcurrency = 4
+	conf.WooConcurrency = 3
+
+	// Products 1-5 were updated by an earlier run.
+	trackerPath := mustCachePath(t, conf, conf.TrackerFilename)
+	tracker, err := TrackerLoad(trackerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id <= 5; id++ {
+		tracker.markUpdated(id, trackerPath)
+	}
+
+	result, err := UpdateSEO(conf, SEOOptions{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := result.Summary(3*time.Minute+12*time.Second), "Processed 30, updated 23, skipped 5, failed 2 in 3m12s"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+	slices.Sort(result.Failed)
+	if !slices.Equal(result.Failed, []int{10, 20}) {
+		t.Errorf("failed = %v, want [10 20]", result.Failed)
+	}
+	if n := len(store.written()); n != 23 {
+		t.Errorf("store has %d writes, want 23", n)
+	}
+}
+
+func TestUpdateSEOTitleSuffix(t *testing.T) {
+	tests := []struct {
+		name      string
+		generated string
+		wantTitle string
+	}{
+		{"appended", "Oak Board", "Oak Board | MyBrand"},
+		{"not doubled when the model adds it", "Oak Board | MyBrand", "Oak Board | MyBrand"},
+		{"fills the budget", strings.Repeat("o", 50), strings.Repeat("o", 50) + " | MyBrand"},
+		{"too long with the suffix", strings.Repeat("o", 51), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(testProduct(1, "Oak Board"))
+			conf, _ := newTestStore(t, store.handle)
+			conf.TitleSuffix = "MyBrand"
+			gen := newFakeGenerator(t, func(req chatRequest) openai.ChatCompletionChoice {
+				return textChoice(metaJSON(map[string]string{"meta_title": tt.generated, "meta_description": "Solid oak board."}))
+			})
+			gen.use(conf)
+
+			result, err := UpdateSEO(conf, SEOOptions{Quiet: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			title := store.product(1).MetaData.YoastTitle()
+			if title != tt.wantTitle {
+				t.Errorf("stored title = %q, want %q", title, tt.wantTitle)
+			}
+			if len(title) > maxTitleLength {
+				t.Errorf("stored title is %d characters, over %d", len(title), maxTitleLength)
+			}
+			if (tt.wantTitle == "") != slices.Contains(result.Failed, 1) {
+				t.Errorf("failed = %v", result.Failed)
+			}
+			if system := gen.sent()[0].Messages[0].Content; !strings.Contains(system, `" | MyBrand"`) || !strings.Contains(system, "50 characters or fewer") {
+				t.Errorf("system prompt does not leave room for the suffix: %s", system)
+			}
+		})
+	}
+}
+
+func TestReadConfigRejectsLongTitleSuffix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wooh.yaml")
+	os.WriteFile(path, []byte("site: shop.example\ntitle_suffix: "+strings.Repeat("b", 40)+"\n"), 0644)
+	if _, err := ReadConfig(path); err == nil || !strings.Contains(err.Error(), "title_suffix") {
+		t.Errorf("ReadConfig = %v, want a title_suffix error", err)
+	}
+}
+
+func TestUpdateSEOIgnoredIDs(t *testing.T) {
+	for _, force := range []bool{false, true} {
+		t.Run(fmt.Sprintf("force %v", force), func(t *testing.T) {
+			store := newFakeStore(testProduct(1, "Oak Board"), testProduct(2, "Ash Board"), testProduct(3, "Elm Board"))
+			conf, _ := newTestStore(t, store.handle)
+			conf.OpenAIStub = true
+			conf.IgnoreIDs = []int{2}
+
+			// The ignored products were updated before being listed, so
+			// --force alone would reprocess them.
+			trackerPath := mustCachePath(t, conf, conf.TrackerFilename)
+			tracker, err := TrackerLoad(trackerPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tracker.markUpdated(2, trackerPath)
+			tracker.markUpdated(3, trackerPath)
+
+			result, err := UpdateSEO(conf, SEOOptions{Quiet: true, Force: force, IgnoreIDs: []int{3}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(result.Skipped)
+			if !slices.Equal(result.Skipped, []int{2, 3}) || !slices.Equal(result.Updated, []int{1}) {
+				t.Errorf("updated %v, skipped %v; want [1], [2 3]", result.Updated, result.Skipped)
+			}
+			if !slices.Equal(store.written(), []int64{1}) {
+				t.Errorf("wrote %v, want only product 1", store.written())
+			}
+			if store.product(2).MetaData.YoastTitle() != "" || store.product(3).MetaData.YoastTitle() != "" {
+				t.Error("ignored product modified")
+			}
+		})
+	}
+}
+
+func TestValidateShippingFields(t *testing.T) {
+	tests := []struct {
+		meta    ProductMeta
+		wantErr string
+	}{
+		{ProductMeta{}, ""},
+		{ProductMeta{Weight: "1.5", Length: "120", Width: "30", Height: "2.5"}, ""},
+		{ProductMeta{Weight: "0"}, ""},
+		{ProductMeta{Weight: "1.5kg"}, "product_meta.weight"},
+		{ProductMeta{Width: "-3"}, "product_meta.width"},
+		{ProductMeta{Length: "12", Height: "tall"}, "product_meta.height"},
+	}
+	for _, tt := range tests {
+		err := ValidateShippingFields(tt.meta)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("ValidateShippingFields(%+v) = %v, want %q", tt.meta, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUploadShippingFields(t *testing.T) {
+	tests := []struct {
+		name           string
+		meta           ProductMeta
+		wantWeight     interface{}
+		wantDimensions interface{}
+	}{
+		{"all set", ProductMeta{Weight: "1.5", Length: "120", Width: "30", Height: "2.5"},
+			"1.5", map[string]interface{}{"length": "120", "width": "30", "height": "2.5"}},
+		{"weight only", ProductMeta{Weight: "1.5"}, "1.5", nil},
+		{"some dimensions", ProductMeta{Length: "120"}, nil, map[string]interface{}{"length": "120"}},
+		{"none", ProductMeta{}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &skuStore{}
+			conf, _ := newTestStore(t, store.handle)
+			conf.ProductMeta = tt.meta
+			conf.ProductMeta.RegularPrice = "10.00"
+
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, "oak-board.jpg"), []byte("x"), 0644)
+			if _, err := UploadImageToWordPress(conf, dir, UploadOptions{Quiet: true}); err != nil {
+				t.Fatal(err)
+			}
+			if len(store.bodies) != 1 {
+				t.Fatalf("created %d products, want 1", len(store.bodies))
+			}
+			body := store.bodies[0]
+			if !reflect.DeepEqual(body["weight"], tt.wantWeight) || !reflect.DeepEqual(body["dimensions"], tt.wantDimensions) {
+				t.Errorf("weight %#v, dimensions %#v; want %#v, %#v", body["weight"], body["dimensions"], tt.wantWeight, tt.wantDimensions)
+			}
+		})
+	}
+}
+
+func TestParseMetaJSON(t *testing.T) {
+	plain := &Config{}
+	withKeyphrase := &Config{FocusKeyphrase: true}
+	tests := []struct {
+		name    string
+		conf    *Config
+		content string
+		wantErr string
+	}{
+		{"valid", plain, `{"meta_title": "Oak Board", "meta_description": "Solid oak."}`, ""},
+		{"numeric title", plain, `{"meta_title": 42, "meta_description": "Solid oak."}`, `"meta_title" must be a string`},
+		{"nested description", plain, `{"meta_title": "Oak", "meta_description": {"text": "Solid oak."}}`, `"meta_description" must be a string`},
+		{"array title", plain, `{"meta_title": ["Oak"], "meta_description": "Solid oak."}`, `"meta_title" must be a string`},
+		{"null title", plain, `{"meta_title": null, "meta_description": "Solid oak."}`, `"meta_title" must be a string`},
+		{"extra key", plain, `{"meta_title": "Oak", "meta_description": "Solid oak.", "notes": "x"}`, `unexpected key "notes"`},
+		{"keyphrase not asked for", plain, `{"meta_title": "Oak", "meta_description": "Solid oak.", "focus_keyphrase": "oak"}`, `unexpected key "focus_keyphrase"`},
+		{"missing keyphrase", withKeyphrase, `{"meta_title": "Oak", "meta_description": "Solid oak."}`, `did not include "focus_keyphrase"`},
+		{"with keyphrase", withKeyphrase, `{"meta_title": "Oak", "meta_description": "Solid oak.", "focus_keyphrase": "oak"}`, ""},
+		{"not JSON", plain, `meta_title: Oak`, "failed to parse JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMetaJSON(tt.conf, tt.content)
+			if tt.wantErr == "" {
+				if err != nil || got.MetaTitle == "" || got.MetaDescription != "Solid oak." {
+					t.Errorf("parseMetaJSON = %+v, %v", got, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+			if tt.wantErr != "failed to parse JSON" && !errors.Is(err, ErrInvalidMeta) {
+				t.Errorf("err = %v, want ErrInvalidMeta", err)
+			}
+		})
+	}
+}
+
+func TestGenerateMetaRetriesSchemaViolations(t *testing.T) {
+	tests := []struct {
+		name      string
+		invalid   int // replies violating the schema before a valid one
+		wantCalls int
+		wantErr   bool
+	}{
+		{"retried until valid", 1, 2, false},
+		{"gives up", 100, maxInvalidMetaRetries + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice {
+				calls++
+				if calls <= tt.invalid {
+					return textChoice(`{"meta_title": 42, "meta_description": "Solid oak."}`)
+				}
+				return textChoice(metaJSON(map[string]string{"meta_title": "Oak Board", "meta_description": "Solid oak."}))
+			})
+			conf := &Config{}
+			applyDefaults(conf)
+			gen.use(conf)
+
+			got, err := generateMeta(context.Background(), conf, "system", "user")
+			if tt.wantErr != errors.Is(err, ErrInvalidMeta) {
+				t.Errorf("err = %v, want ErrInvalidMeta %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.MetaTitle != "Oak Board" {
+				t.Errorf("meta = %+v", got)
+			}
+			if n := len(gen.sent()); n != tt.wantCalls {
+				t.Errorf("sent %d requests, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// toolCallChoice is a reply calling name with arguments.
+func toolCallChoice(name, arguments string) openai.ChatCompletionChoice {
+	return openai.ChatCompletionChoice{
+		Message: openai.ChatCompletionMessage{
+			Role: openai.ChatMessageRoleAssistant,
+			ToolCalls: []openai.ToolCall{{
+				ID:       "call_1",
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: name, Arguments: arguments},
+			}},
+		},
+		FinishReason: openai.FinishReasonToolCalls,
+	}
+}
+
+func TestGenerateMetaToolCalling(t *testing.T) {
+	meta := metaJSON(map[string]string{"meta_title": "Oak Board", "meta_description": "Solid oak."})
+	tests := []struct {
+		name      string
+		model     string
+		reply     openai.ChatCompletionChoice
+		wantTools bool
+		wantCalls int
+		wantErr   error
+	}{
+		{"tool call", "", toolCallChoice(metaToolName, meta), true, 1, nil},
+		{"content ignored", "", textChoice(meta), true, maxInvalidMetaRetries + 1, ErrInvalidMeta},
+		{"other tool", "", toolCallChoice("lookup_product", meta), true, maxInvalidMetaRetries + 1, ErrInvalidMeta},
+		{"invalid arguments", "", toolCallChoice(metaToolName, `{"meta_title": "Oak Board"}`), true, maxInvalidMetaRetries + 1, ErrInvalidMeta},
+		{"model without tools", "gpt-3.5-turbo-instruct", textChoice(meta), false, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := newFakeGenerator(t, func(chatRequest) openai.ChatCompletionChoice { return tt.reply })
+			conf := &Config{UseToolCalling: true}
+			applyDefaults(conf)
+			gen.use(conf)
+			if tt.model != "" {
+				conf.GeneratorModel = tt.model
+			}
+
+			got, err := generateMeta(context.Background(), conf, "system", "user")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil || got.MetaTitle != "Oak Board" || got.MetaDescription != "Solid oak." {
+				t.Errorf("meta = %+v, %v", got, err)
+			}
+
+			sent := gen.sent()
+			if len(sent) != tt.wantCalls {
+				t.Fatalf("sent %d requests, want %d", len(sent), tt.wantCalls)
+			}
+			req := sent[0]
+			if tt.wantTools {
+				if len(req.Tools) != 1 || req.Tools[0].Function == nil || req.Tools[0].Function.Name != metaToolName {
+					t.Errorf("tools = %+v, want %s", req.Tools, metaToolName)
+				}
+				if len(req.ResponseFormat) > 0 && string(req.ResponseFormat) != "null" {
+					t.Errorf("sent a response format with tools: %s", req.ResponseFormat)
+				}
+			} else if len(req.Tools) > 0 || len(req.ResponseFormat) == 0 {
+				t.Errorf("model without tools sent tools %+v, response format %s", req.Tools, req.ResponseFormat)
+			}
+		})
+	}
+}
+
+func TestUpdateSEOToolCalling(t *testing.T) {
+	store := newFakeStore(testProduct(1, "Oak Board"))
+	conf, _ := newTestStore(t, store.handle)
+	conf.UseToolCalling = true
+	newFakeGenerator(t, func(req chatRequest) openai.ChatCompletionChoice {
+		if len(req.Tools) == 0 {
+			return textChoice(metaJSON(map[string]string{"meta_title": "From content", "meta_description": "From content."}))
+		}
+		return toolCallChoice(metaToolName, metaJSON(map[string]string{"meta_title": "Oak Board", "meta_description": "Solid oak."}))
+	}).use(conf)
+
+	if _, err := UpdateSEO(conf, SEOOptions{Quiet: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.product(1).MetaData.YoastTitle(); !strings.HasPrefix(got, "Oak Board") {
+		t.Errorf("meta title = %q, want the tool call's arguments", got)
+	}
+}
+
+func TestGetProductsSendsFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []string
+		want   string
+	}{
+		{"default", nil, strings.Join(defaultProductFields, ",")},
+		{"configured", []string{"name", "meta_data", "name"}, "id,name,meta_data"},
+		{"empty list requests everything", []string{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+			conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+				sent = append(sent, r.URL.Query().Get("_fields"))
+				w.Header().Set("Content-Type", "application/json")
+				// What WooCommerce returns for _fields=id,name,meta_data.
+				w.Write([]byte(`[{"id": 1, "name": "Oak Board", "meta_data": [{"id": 3, "key": "_yoast_wpseo_title", "value": "Oak"}]}]`))
+			})
+			if tt.fields != nil {
+				conf.Fields = tt.fields
+			}
+
+			cache, _ := NewCache(conf)
+			products, err := GetProducts(conf, cache, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sent) != 1 || sent[0] != tt.want {
+				t.Errorf("_fields = %q, want %q", sent, tt.want)
+			}
+			if len(products) != 1 || products[0].Name != "Oak Board" || products[0].MetaData.YoastTitle() != "Oak" || products[0].Description != "" {
+				t.Errorf("trimmed response decoded as %+v", products)
+			}
+		})
+	}
+}
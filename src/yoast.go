@@ -0,0 +1,143 @@
+package wooh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	SEOWriteModeWooMeta   = "woo_meta"
+	SEOWriteModeYoastRest = "yoast_rest"
+)
+
+// yoastMetaPrefix marks the post meta Yoast owns.
+const yoastMetaPrefix = "_yoast_wpseo_"
+
+func ValidateSEOWriteMode(mode string) error {
+	if mode != SEOWriteModeWooMeta && mode != SEOWriteModeYoastRest {
+		return fmt.Errorf("unsupported seo_write_mode %q (allowed: %s, %s)", mode, SEOWriteModeWooMeta, SEOWriteModeYoastRest)
+	}
+	return nil
+}
+
+// probeYoastREST reports whether the site can take Yoast meta over the
+// WordPress REST API: Yoast's namespace must be registered and products
+// must be exposed under wp/v2.
+func probeYoastREST(conf *Config) (bool, error) {
+	resp, err := newClient(conf).R().
+		SetHeader("Accept", "application/json").
+		Get(wpEndpoint(conf, "/"))
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch REST index: %w", err)
+	}
+	if resp.IsError() {
+		return false, fmt.Errorf("error fetching REST index: %w", apiError(resp))
+	}
+
+	var index struct {
+		Namespaces []string               `json:"namespaces"`
+		Routes     map[string]interface{} `json:"routes"`
+	}
+	if err := decodeJSON(resp, &index); err != nil {
+		return false, fmt.Errorf("failed to parse REST index: %w", err)
+	}
+	hasYoast := false
+	for _, ns := range index.Namespaces {
+		if ns == "yoast/v1" {
+			hasYoast = true
+			break
+		}
+	}
+	_, hasProducts := index.Routes["/wp/v2/product/(?P<id>[\\d]+)"]
+	return hasYoast && hasProducts, nil
+}
+
+// resolveSEOWriteMode returns conf, or a copy of it set to woo_meta when
+// yoast_rest is configured but the site does not support it.
+func resolveSEOWriteMode(conf *Config) *Config {
+	if conf.SEOWriteMode != SEOWriteModeYoastRest {
+		return conf
+	}
+	ok, err := probeYoastREST(conf)
+	if ok {
+		return conf
+	}
+	if err != nil {
+		fmt.Printf("Warning: could not probe the Yoast REST API (%v), writing meta through WooCommerce\n", err)
+	} else {
+		fmt.Println("Warning: the Yoast REST API is not available on this site, writing meta through WooCommerce")
+	}
+	fallback := *conf
+	fallback.SEOWriteMode = SEOWriteModeWooMeta
+	return &fallback
+}
+
+// splitYoastMeta separates the Yoast entries of metaData from the rest.
+func splitYoastMeta(metaData MetaData) (yoast, rest MetaData) {
+	for _, m := range metaData {
+		if strings.HasPrefix(m.Key, yoastMetaPrefix) {
+			yoast = append(yoast, m)
+		} else {
+			rest = append(rest, m)
+		}
+	}
+	return yoast, rest
+}
+
+// yoastPayload is the wp/v2 body that sets the Yoast meta in metaData.
+func yoastPayload(metaData MetaData) map[string]interface{} {
+	meta := make(map[string]interface{}, len(metaData))
+	for _, m := range metaData {
+		meta[m.Key] = m.Value
+	}
+	return map[string]interface{}{"meta": meta}
+}
+
+func postYoastMeta(ctx context.Context, client *resty.Client, conf *Config, productID int, metaData MetaData) error {
+	resp, err := client.R().
+		SetContext(ctx).
+		SetBasicAuth(conf.WpUser, conf.WpKey).
+		SetHeader("Content-Type", "application/json").
+		SetBody(yoastPayload(metaData)).
+		Post(wpEndpoint(conf, fmt.Sprintf("wp/v2/product/%d", productID)))
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return apiError(resp)
+	}
+	return nil
+}
+
+// sendProductUpdate writes payload through WooCommerce, or in yoast_rest
+// mode sends the Yoast entries of its meta_data through WordPress and only
+// the remainder through WooCommerce.
+func sendProductUpdate(ctx context.Context, client *resty.Client, conf *Config, productID int, payload map[string]interface{}) error {
+	if conf.SEOWriteMode != SEOWriteModeYoastRest {
+		return putProduct(ctx, client, conf, productID, payload)
+	}
+
+	metaData, _ := payload["meta_data"].(MetaData)
+	yoast, rest := splitYoastMeta(metaData)
+
+	wooPayload := make(map[string]interface{}, len(payload))
+	for k, v := range payload {
+		wooPayload[k] = v
+	}
+	delete(wooPayload, "meta_data")
+	if len(rest) > 0 {
+		wooPayload["meta_data"] = rest
+	}
+	if len(wooPayload) > 0 {
+		if err := putProduct(ctx, client, conf, productID, wooPayload); err != nil {
+			return err
+		}
+	}
+	if len(yoast) == 0 {
+		return nil
+	}
+	return postYoastMeta(ctx, client, conf, productID, yoast)
+}
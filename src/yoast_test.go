@@ -0,0 +1,150 @@
+package wooh
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// yoastSite serves the WordPress REST index with namespaces and records the
+// body of every product write by method and path.
+type yoastSite struct {
+	namespaces []string
+
+	mu     sync.Mutex
+	writes map[string]map[string]interface{}
+	auth   map[string]bool
+}
+
+func (s *yoastSite) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/wp-json/" {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"namespaces": s.namespaces,
+			"routes":     map[string]interface{}{"/wp/v2/product/(?P<id>[\\d]+)": map[string]interface{}{}},
+		})
+		return
+	}
+	var body map[string]interface{}
+	b, _ := io.ReadAll(r.Body)
+	json.Unmarshal(b, &body)
+	_, _, basic := r.BasicAuth()
+	s.mu.Lock()
+	if s.writes == nil {
+		s.writes, s.auth = map[string]map[string]interface{}{}, map[string]bool{}
+	}
+	s.writes[r.Method+" "+r.URL.Path] = body
+	s.auth[r.Method+" "+r.URL.Path] = basic
+	s.mu.Unlock()
+	w.Write([]byte(`{"id": 1}`))
+}
+
+func TestResolveSEOWriteMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespaces []string
+		want       string
+	}{
+		{"yoast installed", []string{"wp/v2", "wc/v3", "yoast/v1"}, SEOWriteModeYoastRest},
+		{"no yoast namespace", []string{"wp/v2", "wc/v3"}, SEOWriteModeWooMeta},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &yoastSite{namespaces: tt.namespaces}
+			conf, _ := newTestStore(t, site.handle)
+			conf.SEOWriteMode = SEOWriteModeYoastRest
+			if got := resolveSEOWriteMode(conf).SEOWriteMode; got != tt.want {
+				t.Errorf("write mode = %s, want %s", got, tt.want)
+			}
+			if conf.SEOWriteMode != SEOWriteModeYoastRest {
+				t.Error("falling back changed the caller's config")
+			}
+		})
+	}
+
+	t.Run("index unavailable", func(t *testing.T) {
+		conf, _ := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code": "rest_no_route", "message": "No route"}`))
+		})
+		if ok, err := probeYoastREST(conf); ok || err == nil {
+			t.Errorf("probe = %v, %v; want an error", ok, err)
+		}
+		conf.SEOWriteMode = SEOWriteModeYoastRest
+		if got := resolveSEOWriteMode(conf).SEOWriteMode; got != SEOWriteModeWooMeta {
+			t.Errorf("write mode = %s after a failed probe, want %s", got, SEOWriteModeWooMeta)
+		}
+	})
+}
+
+func TestSendProductUpdateWriteModes(t *testing.T) {
+	payload := map[string]interface{}{
+		"description": "<p>Oak.</p>",
+		"meta_data": MetaData{
+			{Key: yoastTitleKey, Value: "Oak Board"},
+			{Key: yoastDescKey, Value: "Solid oak board."},
+			{Key: "_gtin", Value: "5012345678900"},
+		},
+	}
+	const (
+		wooPath   = "PUT /wp-json/wc/v3/products/1"
+		yoastPath = "POST /wp-json/wp/v2/product/1"
+	)
+
+	t.Run(SEOWriteModeWooMeta, func(t *testing.T) {
+		site := &yoastSite{}
+		conf, _ := newTestStore(t, site.handle)
+		if err := sendProductUpdate(context.Background(), newClient(conf), conf, 1, payload); err != nil {
+			t.Fatal(err)
+		}
+		if len(site.writes) != 1 || len(site.writes[wooPath]["meta_data"].([]interface{})) != 3 {
+			t.Errorf("writes = %v, want every meta entry in one WooCommerce update", site.writes)
+		}
+	})
+
+	t.Run(SEOWriteModeYoastRest, func(t *testing.T) {
+		site := &yoastSite{}
+		conf, _ := newTestStore(t, site.handle)
+		conf.SEOWriteMode = SEOWriteModeYoastRest
+		conf.WpUser, conf.WpKey = "editor", "app-password"
+		if err := sendProductUpdate(context.Background(), newClient(conf), conf, 1, payload); err != nil {
+			t.Fatal(err)
+		}
+
+		woo := site.writes[wooPath]
+		wantWoo := map[string]interface{}{
+			"description": "<p>Oak.</p>",
+			"meta_data":   []interface{}{map[string]interface{}{"key": "_gtin", "value": "5012345678900"}},
+		}
+		if !reflect.DeepEqual(woo, wantWoo) {
+			t.Errorf("WooCommerce payload = %v, want %v", woo, wantWoo)
+		}
+		wantYoast := map[string]interface{}{"meta": map[string]interface{}{
+			yoastTitleKey: "Oak Board",
+			yoastDescKey:  "Solid oak board.",
+		}}
+		if !reflect.DeepEqual(site.writes[yoastPath], wantYoast) {
+			t.Errorf("Yoast payload = %v, want %v", site.writes[yoastPath], wantYoast)
+		}
+		if !site.auth[yoastPath] {
+			t.Error("Yoast write sent without WordPress credentials")
+		}
+	})
+
+	t.Run("yoast meta only", func(t *testing.T) {
+		site := &yoastSite{}
+		conf, _ := newTestStore(t, site.handle)
+		conf.SEOWriteMode = SEOWriteModeYoastRest
+		onlyYoast := map[string]interface{}{"meta_data": MetaData{{Key: yoastTitleKey, Value: "Oak Board"}}}
+		if err := sendProductUpdate(context.Background(), newClient(conf), conf, 1, onlyYoast); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := site.writes[wooPath]; ok || len(site.writes) != 1 {
+			t.Errorf("writes = %v, want only the Yoast update", site.writes)
+		}
+	})
+}